@@ -1,17 +1,130 @@
 package handlers
 
 import (
+	"cashpal/audit"
 	"cashpal/database"
 	db "cashpal/database/generated"
+	"cashpal/ledger"
 	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type accountResponse struct {
+	db.Account
+	Balance money.Amount `json:"balance"`
+}
+
+type accountBalanceResponse struct {
+	AccountID         int32        `json:"account_id"`
+	AsOf              pgtype.Date  `json:"as_of"`
+	Currency          string       `json:"currency"`
+	Balance           money.Amount `json:"balance"`
+	ReportingCurrency string       `json:"reporting_currency"`
+	ReportingBalance  money.Amount `json:"reporting_balance"`
+}
+
+var errAccountArchived = errors.New("this account is archived and read-only")
+
+// verifyWritable rejects changes to archived accounts.
+func verifyWritable(context context.Context, query *db.Queries, accountID int32) (int, error) {
+	account, err := query.GetAccount(context, accountID)
+
+	if err != nil {
+		return http.StatusNotFound, errors.New("this account does not exist")
+	}
+
+	if account.ArchivedAt.Valid {
+		return http.StatusConflict, errAccountArchived
+	}
+
+	return http.StatusOK, nil
+}
+
+func today() pgtype.Date {
+	return pgtype.Date{Time: time.Now().UTC().Truncate(24 * time.Hour), Valid: true}
+}
+
+// parseAsOfDate reads the optional as_of query parameter, defaulting to today.
+func parseAsOfDate(r *http.Request) (pgtype.Date, error) {
+	asOf := r.URL.Query().Get("as_of")
+
+	if asOf == "" {
+		return today(), nil
+	}
+
+	date, err := time.Parse(time.DateOnly, asOf)
+
+	if err != nil {
+		return pgtype.Date{}, err
+	}
+
+	return pgtype.Date{Time: date, Valid: true}, nil
+}
+
+// accountBalance sums the account's postings up to asOf in the requested
+// currency. Postings in any other currency are converted with the rate valid
+// on the date of their journal entry.
+func accountBalance(context context.Context, query *db.Queries, accountID int32, asOf pgtype.Date, currency string) (money.Amount, error) {
+	balanceParams := db.ListAccountBalanceByCurrencyParams{
+		AccountID: accountID,
+		AsOf:      asOf,
+	}
+
+	totals, err := query.ListAccountBalanceByCurrency(context, balanceParams)
+
+	if err != nil {
+		return 0, err
+	}
+
+	currencies := []string{currency}
+
+	for _, total := range totals {
+		if total.Currency != currency {
+			currencies = append(currencies, total.Currency)
+		}
+	}
+
+	var rateTable *money.RateTable
+
+	if len(currencies) > 1 {
+		rateTable, err = rates.LoadTable(context, query, currencies, asOf)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var balance money.Amount
+
+	for _, total := range totals {
+		if total.Currency == currency {
+			balance += total.Amount
+			continue
+		}
+
+		converted, err := rateTable.Convert(total.Amount, total.Currency, currency, total.EntryDate.Time)
+
+		if err != nil {
+			return 0, err
+		}
+
+		balance += converted
+	}
+
+	return balance.RoundTo(currency), nil
+}
+
 func ListAccounts(w http.ResponseWriter, r *http.Request) {
 	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
 
@@ -30,7 +143,12 @@ func ListAccounts(w http.ResponseWriter, r *http.Request) {
 
 	defer connClose()
 
-	accounts, err := query.ListAccountByUser(r.Context(), contextUserID)
+	listAccountParams := db.ListAccountByUserParams{
+		UserID:          contextUserID,
+		IncludeArchived: r.URL.Query().Get("include_archived") == "true",
+	}
+
+	accounts, err := query.ListAccountByUser(r.Context(), listAccountParams)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -38,7 +156,28 @@ func ListAccounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serializedAccounts, err := json.Marshal(accounts)
+	asOf := today()
+
+	response := make([]accountResponse, 0, len(accounts))
+
+	for _, account := range accounts {
+		balance, err := accountBalance(r.Context(), query, account.ID, asOf, account.Currency)
+
+		if errors.Is(err, money.ErrRateNotFound) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "balance calculation failed", http.StatusInternalServerError)
+			return
+		}
+
+		response = append(response, accountResponse{Account: account, Balance: balance})
+	}
+
+	serializedAccounts, err := json.Marshal(response)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -94,7 +233,35 @@ func GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serializedAccount, err := json.Marshal(account)
+	balance, err := accountBalance(r.Context(), query, account.ID, today(), account.Currency)
+
+	if errors.Is(err, money.ErrRateNotFound) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "balance calculation failed", http.StatusInternalServerError)
+		return
+	}
+
+	response := accountResponse{
+		Account: db.Account{
+			ID:                     account.ID,
+			AccountName:            account.AccountName,
+			AccountType:            account.AccountType,
+			CreatedAt:              account.CreatedAt,
+			UpdatedAt:              account.UpdatedAt,
+			Currency:               account.Currency,
+			ArchivedAt:             account.ArchivedAt,
+			BudgetingMode:          account.BudgetingMode,
+			AllowNegativeEnvelopes: account.AllowNegativeEnvelopes,
+		},
+		Balance: balance,
+	}
+
+	serializedAccount, err := json.Marshal(response)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -105,6 +272,108 @@ func GetAccount(w http.ResponseWriter, r *http.Request) {
 	w.Write(serializedAccount)
 }
 
+func GetAccountBalance(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	asOf, err := parseAsOfDate(r)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "as_of date is invalid or malformed, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	userCheckParams := db.GetAccountWithUserCheckParams{
+		ID:     int32(accountID),
+		UserID: contextUserID,
+	}
+
+	account, err := query.GetAccountWithUserCheck(r.Context(), userCheckParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	user, err := query.GetUser(r.Context(), contextUserID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	currency := account.Currency
+
+	if r.URL.Query().Has("currency") {
+		currency, err = money.NormalizeCurrency(r.URL.Query().Get("currency"))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := accountBalanceResponse{
+		AccountID:         account.ID,
+		AsOf:              asOf,
+		Currency:          currency,
+		ReportingCurrency: user.ReportingCurrency,
+	}
+
+	response.Balance, err = accountBalance(r.Context(), query, account.ID, asOf, currency)
+
+	if err == nil {
+		response.ReportingBalance, err = accountBalance(r.Context(), query, account.ID, asOf, user.ReportingCurrency)
+	}
+
+	if errors.Is(err, money.ErrRateNotFound) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "balance calculation failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedBalance, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedBalance)
+}
+
 func saveAccount(context context.Context, newAccount db.CreateAccountParams) (*db.Account, int, error) {
 	userID, ok := context.Value(middleware.UserIDContextKey).(int32)
 
@@ -142,6 +411,24 @@ func saveAccount(context context.Context, newAccount db.CreateAccountParams) (*d
 		return nil, http.StatusInternalServerError, errors.New("account creation failed")
 	}
 
+	if err := seedCategories(context, qtx, account.ID); err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("account creation failed")
+	}
+
+	event := audit.Event{
+		AccountID:   account.ID,
+		UserID:      userID,
+		Type:        audit.AccountCreated,
+		Description: fmt.Sprintf("account %q created", account.AccountName),
+		After:       account,
+	}
+
+	if _, err := audit.Record(context, qtx, event); err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("account creation failed")
+	}
+
 	tx.Commit(context)
 
 	return &account, http.StatusOK, nil
@@ -158,6 +445,19 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if newAccount.Currency == "" {
+		newAccount.Currency = money.DefaultCurrency
+	}
+
+	currency, err := money.NormalizeCurrency(newAccount.Currency)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	newAccount.Currency = currency
+
 	account, statuscode, err := saveAccount(r.Context(), newAccount)
 
 	if err != nil {
@@ -214,7 +514,7 @@ func UpdateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query, connClose, err := database.GetNewConnection(r.Context())
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
 
 	if err != nil {
 		log.Println(err.Error())
@@ -223,13 +523,16 @@ func UpdateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
 
 	userCheckParams := db.GetAccountWithUserCheckParams{
 		ID:     int32(accountID),
 		UserID: contextUserID,
 	}
 
-	account, err := query.GetAccountWithUserCheck(r.Context(), userCheckParams)
+	account, err := qtx.GetAccountWithUserCheck(r.Context(), userCheckParams)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -242,9 +545,22 @@ func UpdateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if account.ArchivedAt.Valid {
+		http.Error(w, errAccountArchived.Error(), http.StatusConflict)
+		return
+	}
+
+	currentAccount, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
 	compareAccountData(&accountUpdateData, &account)
 
-	updatedAccount, err := query.UpdateAccount(r.Context(), accountUpdateData)
+	updatedAccount, err := qtx.UpdateAccount(r.Context(), accountUpdateData)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -252,6 +568,27 @@ func UpdateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	event := audit.Event{
+		AccountID:   updatedAccount.ID,
+		UserID:      contextUserID,
+		Type:        audit.AccountUpdated,
+		Description: fmt.Sprintf("account %q updated", updatedAccount.AccountName),
+		Before:      currentAccount,
+		After:       updatedAccount,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
 	serializedUpdatedAccount, err := json.Marshal(updatedAccount)
 
 	if err != nil {
@@ -263,7 +600,228 @@ func UpdateAccount(w http.ResponseWriter, r *http.Request) {
 	w.Write(serializedUpdatedAccount)
 }
 
+// setAccountArchived archives or unarchives an account on behalf of one of
+// its administrators.
+func setAccountArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	currentAccount, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	var account db.Account
+
+	event := audit.Event{
+		AccountID: currentAccount.ID,
+		UserID:    contextUserID,
+		Before:    currentAccount,
+	}
+
+	if archived {
+		event.Type = audit.AccountArchived
+		event.Description = fmt.Sprintf("account %q archived", currentAccount.AccountName)
+		account, err = qtx.ArchiveAccount(r.Context(), int32(accountID))
+	} else {
+		event.Type = audit.AccountUnarchived
+		event.Description = fmt.Sprintf("account %q unarchived", currentAccount.AccountName)
+		account, err = qtx.UnarchiveAccount(r.Context(), int32(accountID))
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	event.After = account
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedAccount, err := json.Marshal(account)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedAccount)
+}
+
+func ArchiveAccount(w http.ResponseWriter, r *http.Request) {
+	setAccountArchived(w, r, true)
+}
+
+func UnarchiveAccount(w http.ResponseWriter, r *http.Request) {
+	setAccountArchived(w, r, false)
+}
+
+// DeleteAccount permanently removes an account with its transactions, members
+// and events. Transfers to other accounts keep their other leg, which is then
+// balanced by the equity of its own account. Only administrators can do this,
+// and the deletion is recorded in Account_Deletions.
 func DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	account, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	removal, err := ledger.RemoveAccount(r.Context(), qtx, account.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	// The events of the deleted account go with it, but the accounts on the
+	// other side of its transfers keep theirs.
+	for _, rewrite := range removal.Rewritten {
+		event := audit.Event{
+			AccountID:   rewrite.After.AccountID,
+			UserID:      contextUserID,
+			Type:        audit.TransactionUpdated,
+			Description: fmt.Sprintf("transaction %d detached from deleted account %q", rewrite.After.ID, account.AccountName),
+			Before:      rewrite.Before,
+			After:       rewrite.After,
+		}
+
+		if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "account deletion failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := qtx.DeleteAccountEventsByAccount(r.Context(), account.ID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := qtx.DeleteMembersByAccount(r.Context(), account.ID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := qtx.DeleteAccount(r.Context(), account.ID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	deletionParams := db.CreateAccountDeletionParams{
+		AccountID:        account.ID,
+		AccountName:      account.AccountName,
+		DeletedBy:        contextUserID,
+		TransactionCount: int32(removal.Removed),
+	}
+
+	deletion, err := qtx.CreateAccountDeletion(r.Context(), deletionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedDeletion, err := json.Marshal(deletion)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("the accounts cannot be deleted yet"))
+	w.Write(serializedDeletion)
 }
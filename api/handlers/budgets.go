@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"cashpal/budget"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type budgetRequest struct {
+	Amount   money.Amount `json:"amount"`
+	Rollover bool         `json:"rollover"`
+}
+
+type budgetResponse struct {
+	Month    string `json:"month"`
+	Currency string `json:"currency"`
+	budget.Report
+}
+
+// parseBudgetMonth reads a month formatted as YYYY-MM.
+func parseBudgetMonth(value string) (time.Time, error) {
+	month, err := time.Parse("2006-01", value)
+
+	if err != nil {
+		return time.Time{}, errors.New("month must be formatted as YYYY-MM")
+	}
+
+	return month, nil
+}
+
+// categorySpending returns what was spent per category and day between from
+// and to, converted to the account currency with the rate of each day.
+func categorySpending(context context.Context, query *db.Queries, account db.Account, from time.Time, to time.Time) ([]budget.Spending, error) {
+	spendingParams := db.ListCategorySpendingParams{
+		AccountID: account.ID,
+		FromDate:  pgtype.Date{Time: from, Valid: true},
+		ToDate:    pgtype.Date{Time: to, Valid: true},
+	}
+
+	totals, err := query.ListCategorySpending(context, spendingParams)
+
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := []string{account.Currency}
+
+	for _, total := range totals {
+		if total.Currency != account.Currency {
+			currencies = append(currencies, total.Currency)
+		}
+	}
+
+	var rateTable *money.RateTable
+
+	if len(currencies) > 1 {
+		rateTable, err = rates.LoadTable(context, query, currencies, spendingParams.ToDate)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	spending := make([]budget.Spending, 0, len(totals))
+
+	for _, total := range totals {
+		amount := total.Amount
+
+		if total.Currency != account.Currency {
+			amount, err = rateTable.Convert(total.Amount, total.Currency, account.Currency, total.EntryDate.Time)
+
+			if err != nil {
+				return nil, err
+			}
+
+			amount = amount.RoundTo(account.Currency)
+		}
+
+		// Account postings are negative for money going out.
+		spending = append(spending, budget.Spending{
+			CategoryID: total.CategoryID,
+			Date:       total.EntryDate.Time,
+			Amount:     -amount,
+		})
+	}
+
+	return spending, nil
+}
+
+// GetBudget reports planned, spent and remaining amounts per budgeted
+// category for a month, in the account currency.
+func GetBudget(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	month, err := parseBudgetMonth(r.PathValue("month"))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	listBudgetsParams := db.ListBudgetsByAccountParams{
+		AccountID: account.ID,
+		Through:   pgtype.Date{Time: month, Valid: true},
+	}
+
+	budgets, err := query.ListBudgetsByAccount(r.Context(), listBudgetsParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	categories, err := query.ListCategoryByAccount(r.Context(), account.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	// Budgets are ordered by month, so the first one tells how far back
+	// spending matters for rollover.
+	from := month
+
+	if len(budgets) > 0 {
+		from = budget.MonthOf(budgets[0].Month.Time)
+	}
+
+	spending, err := categorySpending(r.Context(), query, account, from, month.AddDate(0, 1, -1))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "spending cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := budgetResponse{
+		Month:    month.Format("2006-01"),
+		Currency: account.Currency,
+		Report:   budget.Summarize(month, budgets, categories, spending),
+	}
+
+	serializedBudget, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedBudget)
+}
+
+// SetBudget creates or replaces the budget of a category for a month.
+func SetBudget(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	month, err := parseBudgetMonth(r.PathValue("month"))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(r.PathValue("categoryID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request budgetRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Amount < 0 {
+		http.Error(w, "amount cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	if err := request.Amount.Validate(account.Currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err = verifyCategory(r.Context(), query, account.ID, pgtype.Int4{Int32: int32(categoryID), Valid: true})
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	upsertBudgetParams := db.UpsertBudgetParams{
+		AccountID:  account.ID,
+		CategoryID: int32(categoryID),
+		Month:      pgtype.Date{Time: month, Valid: true},
+		Amount:     request.Amount,
+		Rollover:   request.Rollover,
+	}
+
+	savedBudget, err := query.UpsertBudget(r.Context(), upsertBudgetParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "budget update failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedBudget, err := json.Marshal(savedBudget)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedBudget)
+}
+
+func DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	month, err := parseBudgetMonth(r.PathValue("month"))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(r.PathValue("categoryID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	deleteBudgetParams := db.DeleteBudgetParams{
+		AccountID:  int32(accountID),
+		CategoryID: int32(categoryID),
+		Month:      pgtype.Date{Time: month, Valid: true},
+	}
+
+	deleted, err := query.DeleteBudget(r.Context(), deleteBudgetParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "budget deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 {
+		http.Error(w, "this budget does not exist", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("budget deleted"))
+}
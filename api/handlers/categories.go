@@ -0,0 +1,638 @@
+package handlers
+
+import (
+	"cashpal/budget"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// defaultCategories are created for every new account so that transactions
+// can be categorised right away.
+var defaultCategories = []struct {
+	name     string
+	children []string
+}{
+	{"Income", []string{"Salary", "Interest", "Gifts"}},
+	{"Housing", []string{"Rent", "Utilities", "Maintenance"}},
+	{"Food", []string{"Groceries", "Restaurants"}},
+	{"Transportation", []string{"Fuel", "Public Transport"}},
+	{"Health", []string{"Insurance", "Medical"}},
+	{"Entertainment", nil},
+	{"Shopping", nil},
+}
+
+type categoryResponse struct {
+	db.Category
+	Children []*categoryResponse `json:"children"`
+}
+
+func seedCategories(context context.Context, query *db.Queries, accountID int32) error {
+	for _, category := range defaultCategories {
+		parentParams := db.CreateCategoryParams{
+			AccountID: accountID,
+			Name:      category.name,
+		}
+
+		parent, err := query.CreateCategory(context, parentParams)
+
+		if err != nil {
+			return err
+		}
+
+		for _, name := range category.children {
+			childParams := db.CreateCategoryParams{
+				AccountID: accountID,
+				ParentID:  pgtype.Int4{Int32: parent.ID, Valid: true},
+				Name:      name,
+			}
+
+			if _, err := query.CreateCategory(context, childParams); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// categoryTree nests the categories of an account under their parents.
+func categoryTree(categories []db.Category) []*categoryResponse {
+	nodes := make(map[int32]*categoryResponse, len(categories))
+
+	for _, category := range categories {
+		nodes[category.ID] = &categoryResponse{Category: category, Children: []*categoryResponse{}}
+	}
+
+	roots := []*categoryResponse{}
+
+	for _, category := range categories {
+		node := nodes[category.ID]
+
+		if parent, ok := nodes[category.ParentID.Int32]; category.ParentID.Valid && ok {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+// categoryPaths names every category after its ancestors and itself,
+// separated by colons, such as "Food:Groceries".
+func categoryPaths(categories []db.Category) map[int32]string {
+	byID := make(map[int32]db.Category, len(categories))
+
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	paths := make(map[int32]string, len(categories))
+
+	var path func(categoryID int32) string
+
+	path = func(categoryID int32) string {
+		if known, ok := paths[categoryID]; ok {
+			return known
+		}
+
+		category := byID[categoryID]
+		name := category.Name
+
+		if _, ok := byID[category.ParentID.Int32]; category.ParentID.Valid && ok {
+			name = path(category.ParentID.Int32) + ":" + name
+		}
+
+		paths[categoryID] = name
+
+		return name
+	}
+
+	for _, category := range categories {
+		path(category.ID)
+	}
+
+	return paths
+}
+
+// verifyCategory checks that a category belongs to the account.
+func verifyCategory(context context.Context, query *db.Queries, accountID int32, categoryID pgtype.Int4) (int, error) {
+	if !categoryID.Valid {
+		return http.StatusOK, nil
+	}
+
+	getCategoryParams := db.GetCategoryParams{
+		ID:        categoryID.Int32,
+		AccountID: accountID,
+	}
+
+	if _, err := query.GetCategory(context, getCategoryParams); err != nil {
+		return http.StatusBadRequest, errors.New("the category does not belong to this account")
+	}
+
+	return http.StatusOK, nil
+}
+
+// verifyCategoryParent checks that parentID belongs to the account and is not
+// the category itself or one of its descendants.
+func verifyCategoryParent(context context.Context, query *db.Queries, accountID int32, categoryID int32, parentID pgtype.Int4) (int, error) {
+	for parentID.Valid {
+		if parentID.Int32 == categoryID {
+			return http.StatusBadRequest, errors.New("a category cannot be nested under itself")
+		}
+
+		getCategoryParams := db.GetCategoryParams{
+			ID:        parentID.Int32,
+			AccountID: accountID,
+		}
+
+		parent, err := query.GetCategory(context, getCategoryParams)
+
+		if err != nil {
+			return http.StatusBadRequest, errors.New("the parent category does not belong to this account")
+		}
+
+		parentID = parent.ParentID
+	}
+
+	return http.StatusOK, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func ListCategories(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	categories, err := query.ListCategoryByAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	serializedCategories, err := json.Marshal(categoryTree(categories))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedCategories)
+}
+
+func GetCategory(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(r.PathValue("categoryID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	categories, err := query.ListCategoryByAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	var category *categoryResponse
+
+	// Search the tree so the category comes back with its subcategories.
+	for pending := categoryTree(categories); len(pending) > 0 && category == nil; {
+		node := pending[0]
+		pending = append(pending[1:], node.Children...)
+
+		if node.ID == int32(categoryID) {
+			category = node
+		}
+	}
+
+	if category == nil {
+		http.Error(w, "this category does not exist", http.StatusNotFound)
+		return
+	}
+
+	serializedCategory, err := json.Marshal(category)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedCategory)
+}
+
+func CreateCategory(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var newCategory db.CreateCategoryParams
+
+	if err := json.NewDecoder(r.Body).Decode(&newCategory); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	newCategory.AccountID = int32(accountID)
+	newCategory.Name = strings.TrimSpace(newCategory.Name)
+
+	if newCategory.Name == "" {
+		http.Error(w, "category name is required", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyCategory(r.Context(), query, int32(accountID), newCategory.ParentID)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	category, err := query.CreateCategory(r.Context(), newCategory)
+
+	if err != nil {
+		log.Println(err.Error())
+
+		if isUniqueViolation(err) {
+			http.Error(w, "a category with this name already exists here", http.StatusConflict)
+			return
+		}
+
+		http.Error(w, "category creation failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedCategory, err := json.Marshal(category)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedCategory)
+}
+
+func UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(r.PathValue("categoryID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	getCategoryParams := db.GetCategoryParams{
+		ID:        int32(categoryID),
+		AccountID: int32(accountID),
+	}
+
+	category, err := query.GetCategory(r.Context(), getCategoryParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this category does not exist", http.StatusNotFound)
+		return
+	}
+
+	// Fields missing from the body keep their current value, while an explicit
+	// "parent_id": null moves the category to the top level.
+	updatedData := db.UpdateCategoryParams{
+		ParentID: category.ParentID,
+		Name:     category.Name,
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	updatedData.ID = category.ID
+	updatedData.AccountID = category.AccountID
+	updatedData.Name = strings.TrimSpace(updatedData.Name)
+
+	if updatedData.Name == "" {
+		http.Error(w, "category name is required", http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err = verifyCategoryParent(r.Context(), query, category.AccountID, category.ID, updatedData.ParentID)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	updatedCategory, err := query.UpdateCategory(r.Context(), updatedData)
+
+	if err != nil {
+		log.Println(err.Error())
+
+		if isUniqueViolation(err) {
+			http.Error(w, "a category with this name already exists here", http.StatusConflict)
+			return
+		}
+
+		http.Error(w, "category update failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedCategory, err := json.Marshal(updatedCategory)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedCategory)
+}
+
+func DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(r.PathValue("categoryID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	statusCode, err := verifyMembership(r.Context(), qtx, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	_, err = verifyCategory(r.Context(), qtx, int32(accountID), pgtype.Int4{Int32: int32(categoryID), Valid: true})
+
+	if err != nil {
+		http.Error(w, "this category does not exist", http.StatusNotFound)
+		return
+	}
+
+	children, err := qtx.CountCategoryChildren(r.Context(), pgtype.Int4{Int32: int32(categoryID), Valid: true})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if children > 0 {
+		http.Error(w, "the category still has subcategories", http.StatusConflict)
+		return
+	}
+
+	moves, err := qtx.CountCategoryEnvelopeMoves(r.Context(), pgtype.Int4{Int32: int32(categoryID), Valid: true})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	// Moves are the history of the envelope budget, so they are never erased
+	// with the category.
+	if moves > 0 {
+		http.Error(w, "money has been moved into or out of the envelope of this category", http.StatusConflict)
+		return
+	}
+
+	deleteCategoryParams := db.DeleteCategoryParams{
+		ID:        int32(categoryID),
+		AccountID: int32(accountID),
+	}
+
+	// Spending left uncategorised is taken from the money waiting to be
+	// assigned instead of the envelope of the category.
+	envelopes, err := budget.Lock(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Transactions in the category are left uncategorised by the foreign key.
+	if err := qtx.DeleteCategory(r.Context(), deleteCategoryParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	statusCode, err = verifyEnvelopes(r.Context(), qtx, envelopes)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "category deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("category deleted"))
+}
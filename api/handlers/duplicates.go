@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/budget"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/duplicates"
+	"cashpal/ledger"
+	"cashpal/middleware"
+	"cashpal/money"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type mergeRequest struct {
+	DuplicateID int32 `json:"duplicate_id"`
+}
+
+// findDuplicates returns the transactions of the account that look like the
+// one about to be created: same direction, amount and currency, a
+// near-identical description and a date at most window days from it.
+// Transactions in excluded are never returned.
+func findDuplicates(context context.Context, query *db.Queries, account db.Account, transaction db.CreateTransactionParams, amount money.Amount, window int, excluded []int32) ([]db.Transaction, error) {
+	found := []db.Transaction{}
+
+	if !transaction.TransactionDate.Valid {
+		return found, nil
+	}
+
+	if excluded == nil {
+		excluded = []int32{}
+	}
+
+	date := transaction.TransactionDate.Time
+	currency := account.Currency
+
+	if transaction.Currency.Valid {
+		currency = transaction.Currency.String
+	}
+
+	candidatesParams := db.ListTransactionDuplicateCandidatesParams{
+		AccountID:         account.ID,
+		TransactionTypeID: transaction.TransactionTypeID,
+		Amount:            amount,
+		AccountCurrency:   account.Currency,
+		Currency:          currency,
+		FromDate:          pgtype.Date{Time: date.AddDate(0, 0, -window), Valid: true},
+		ToDate:            pgtype.Date{Time: date.AddDate(0, 0, window), Valid: true},
+		ExcludedIds:       excluded,
+	}
+
+	candidates, err := query.ListTransactionDuplicateCandidates(context, candidatesParams)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		if duplicates.Similar(candidate.Description, transaction.Description) {
+			found = append(found, candidate)
+		}
+	}
+
+	return found, nil
+}
+
+// duplicateMessage names the transactions something looks like a duplicate
+// of.
+func duplicateMessage(found []db.Transaction) string {
+	ids := make([]string, len(found))
+
+	for i, transaction := range found {
+		ids[i] = strconv.Itoa(int(transaction.ID))
+	}
+
+	if len(ids) == 1 {
+		return fmt.Sprintf("this looks like a duplicate of transaction %s", ids[0])
+	}
+
+	return fmt.Sprintf("this looks like a duplicate of transactions %s", strings.Join(ids, ", "))
+}
+
+// MergeTransaction folds a duplicate into the transaction in the path. The
+// transaction keeps its own fields and takes over what only the duplicate
+// has: its category, its external id, so the statement line it came from is
+// not imported again, its metadata and its tags. The duplicate then goes to
+// the trash, from where it can still be restored. Both must move the same
+// amount in the same direction and currency, and neither can be part of a
+// transfer.
+func MergeTransaction(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request mergeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.DuplicateID == int32(transactionID) {
+		http.Error(w, "a transaction cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	getTransactionParams.ID = request.DuplicateID
+
+	duplicate, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "the duplicate transaction does not exist in this account", http.StatusNotFound)
+		return
+	}
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if transaction.TransferID.Valid || duplicate.TransferID.Valid {
+		http.Error(w, "transfers cannot be merged", http.StatusUnprocessableEntity)
+		return
+	}
+
+	currency, err := transactionCurrency(r.Context(), qtx, transaction)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	duplicateCurrency, err := transactionCurrency(r.Context(), qtx, duplicate)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if transaction.TransactionTypeID != duplicate.TransactionTypeID || transaction.Amount != duplicate.Amount || currency != duplicateCurrency {
+		http.Error(w, "only transactions with the same type, amount and currency can be merged", http.StatusUnprocessableEntity)
+		return
+	}
+
+	currentSplits, err := qtx.ListTransactionSplits(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	envelopes, err := budget.Lock(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	mergeParams := db.MergeTransactionParams{
+		Metadata: duplicate.Metadata,
+		ID:       transaction.ID,
+	}
+
+	if mergeParams.Metadata == nil {
+		mergeParams.Metadata = json.RawMessage("{}")
+	}
+
+	// External ids are unique per account, trashed transactions included, so
+	// the duplicate gives its id up before the transaction takes it. When the
+	// transaction has one of its own, the duplicate keeps its id and the
+	// statement line still counts as imported.
+	if !transaction.ExternalID.Valid && duplicate.ExternalID.Valid {
+		if err := qtx.ClearTransactionExternalID(r.Context(), duplicate.ID); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+			return
+		}
+
+		mergeParams.ExternalID = duplicate.ExternalID
+	}
+
+	if err := qtx.MergeTransaction(r.Context(), mergeParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := qtx.ListTransactionTags(r.Context(), duplicate.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	for _, tag := range tags {
+		addTagParams := db.AddTransactionTagParams{
+			TransactionID: transaction.ID,
+			TagID:         tag.ID,
+		}
+
+		if err := qtx.AddTransactionTag(r.Context(), addTagParams); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := ledger.Trash(r.Context(), qtx, duplicate.JournalEntryID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+		return
+	}
+
+	// The category lives in the postings, so a category taken over from the
+	// duplicate means booking the transaction again. A split transaction
+	// keeps the categories of its splits.
+	if !transaction.CategoryID.Valid && len(currentSplits) == 0 && duplicate.CategoryID.Valid {
+		transactionType, err := qtx.GetTransactionType(r.Context(), transaction.TransactionTypeID)
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+			return
+		}
+
+		lines := []ledger.Line{{CategoryID: duplicate.CategoryID, Amount: transaction.Amount}}
+		postings := ledger.Book(transaction.ID, transaction.AccountID, currency, transactionType.Sign, counterAccount(transactionType), lines)
+
+		if err := ledger.Rebook(r.Context(), qtx, transaction.JournalEntryID, postings); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	mergedTransaction, err := qtx.GetTransaction(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+		return
+	}
+
+	statusCode, err = verifyEnvelopes(r.Context(), qtx, envelopes)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	response := newTransactionResponse(mergedTransaction, currentSplits)
+
+	event := audit.Event{
+		AccountID:   int32(accountID),
+		UserID:      contextUserID,
+		Type:        audit.TransactionMerged,
+		Description: fmt.Sprintf("transaction %d merged into transaction %d and moved to trash", duplicate.ID, mergedTransaction.ID),
+		Before:      newTransactionResponse(transaction, currentSplits),
+		After:       response,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction merge failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedTransaction, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTransaction)
+}
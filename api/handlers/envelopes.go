@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/budget"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"cashpal/money"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type budgetingRequest struct {
+	Mode                   string `json:"mode"`
+	AllowNegativeEnvelopes bool   `json:"allow_negative_envelopes"`
+}
+
+type envelopesResponse struct {
+	Currency     string            `json:"currency"`
+	ToBeAssigned money.Amount      `json:"to_be_assigned"`
+	Envelopes    []budget.Envelope `json:"envelopes"`
+}
+
+var errNotEnvelopeMode = errors.New("this account does not use envelope budgeting")
+
+// verifyEnvelopes rejects a change that left an envelope of an account
+// guarded by budget.Lock short.
+func verifyEnvelopes(context context.Context, query *db.Queries, guard budget.Guard) (int, error) {
+	err := guard.Verify(context, query)
+
+	var overdrawn *budget.OverdrawnError
+
+	if errors.As(err, &overdrawn) {
+		return http.StatusConflict, err
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		return http.StatusInternalServerError, errors.New("envelope balances cannot be computed")
+	}
+
+	return http.StatusOK, nil
+}
+
+// SetBudgetingMode switches an account between monthly and envelope
+// budgeting. Only administrators can change it.
+func SetBudgetingMode(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request budgetingRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Mode != budget.ModeMonthly && request.Mode != budget.ModeEnvelope {
+		http.Error(w, "mode must be monthly or envelope", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	currentAccount, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	budgetingParams := db.SetAccountBudgetingParams{
+		ID:                     currentAccount.ID,
+		BudgetingMode:          request.Mode,
+		AllowNegativeEnvelopes: request.AllowNegativeEnvelopes,
+	}
+
+	account, err := qtx.SetAccountBudgeting(r.Context(), budgetingParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	event := audit.Event{
+		AccountID:   account.ID,
+		UserID:      contextUserID,
+		Type:        audit.AccountUpdated,
+		Description: fmt.Sprintf("account %q switched to %s budgeting", account.AccountName, account.BudgetingMode),
+		Before:      currentAccount,
+		After:       account,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account update failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedAccount, err := json.Marshal(account)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedAccount)
+}
+
+// GetEnvelopes returns the money waiting to be assigned and the balance of
+// every envelope of an account in envelope mode.
+func GetEnvelopes(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	if account.BudgetingMode != budget.ModeEnvelope {
+		http.Error(w, errNotEnvelopeMode.Error(), http.StatusConflict)
+		return
+	}
+
+	envelopes, err := budget.Balances(r.Context(), query, account)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := envelopesResponse{
+		Currency:     account.Currency,
+		ToBeAssigned: envelopes[budget.ToBeAssigned],
+		Envelopes:    envelopes.List(),
+	}
+
+	serializedEnvelopes, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedEnvelopes)
+}
+
+func ListEnvelopeMoves(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	moves, err := query.ListEnvelopeMoves(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if moves == nil {
+		moves = []db.EnvelopeMove{}
+	}
+
+	serializedMoves, err := json.Marshal(moves)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedMoves)
+}
+
+// MoveEnvelopeMoney moves money between two envelopes of an account. A
+// missing from_category_id or to_category_id stands for the money waiting
+// to be assigned.
+func MoveEnvelopeMoney(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var newMove db.CreateEnvelopeMoveParams
+
+	if err := json.NewDecoder(r.Body).Decode(&newMove); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	newMove.AccountID = int32(accountID)
+	newMove.UserID = contextUserID
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	statusCode, err := verifyMembership(r.Context(), qtx, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := qtx.GetAccountForUpdate(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	if account.BudgetingMode != budget.ModeEnvelope {
+		http.Error(w, errNotEnvelopeMode.Error(), http.StatusConflict)
+		return
+	}
+
+	if err := newMove.Amount.Validate(account.Currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, categoryID := range []pgtype.Int4{newMove.FromCategoryID, newMove.ToCategoryID} {
+		statusCode, err = verifyCategory(r.Context(), qtx, account.ID, categoryID)
+
+		if err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+	}
+
+	envelopes, err := budget.Balances(r.Context(), qtx, account)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = envelopes.Move(newMove.FromCategoryID.Int32, newMove.ToCategoryID.Int32, newMove.Amount, account.AllowNegativeEnvelopes)
+
+	if errors.Is(err, budget.ErrInsufficientFunds) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	move, err := qtx.CreateEnvelopeMove(r.Context(), newMove)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope move failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope move failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedMove, err := json.Marshal(move)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedMove)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// ListAccountEvents returns the audit trail of an account, oldest first, to
+// any of its members.
+func ListAccountEvents(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	events, err := query.ListAccountEventByAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if events == nil {
+		events = []db.ListAccountEventByAccountRow{}
+	}
+
+	serializedEvents, err := json.Marshal(events)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedEvents)
+}
+
+// VerifyAccountEvents checks that the audit trail of an account was not
+// rewritten and reports the first broken link of its hash chain.
+func VerifyAccountEvents(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	verification, err := audit.Verify(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	serializedVerification, err := json.Marshal(verification)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedVerification)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxRateFileSize comfortably fits the ECB historical rate file.
+const maxRateFileSize = 64 << 20
+
+func ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	var listParams db.ListExchangeRatesParams
+
+	if base := r.URL.Query().Get("base"); base != "" {
+		currency, err := money.NormalizeCurrency(base)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		listParams.BaseCurrency = pgtype.Text{String: currency, Valid: true}
+	}
+
+	if quote := r.URL.Query().Get("quote"); quote != "" {
+		currency, err := money.NormalizeCurrency(quote)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		listParams.QuoteCurrency = pgtype.Text{String: currency, Valid: true}
+	}
+
+	if date := r.URL.Query().Get("date"); date != "" {
+		rateDate, err := time.Parse(time.DateOnly, date)
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "date is invalid or malformed, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		listParams.RateDate = pgtype.Date{Time: rateDate, Valid: true}
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	exchangeRates, err := query.ListExchangeRates(r.Context(), listParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	serializedRates, err := json.Marshal(exchangeRates)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedRates)
+}
+
+// verifyOperator rejects users who are not operators. Exchange rates are
+// shared by every account, so only operators can change them.
+func verifyOperator(context context.Context) (int, error) {
+	userID, ok := context.Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		return http.StatusInternalServerError, errors.New("user id cannot be loaded from the session")
+	}
+
+	query, connClose, err := database.GetNewConnection(context)
+
+	if err != nil {
+		log.Println(err.Error())
+		return http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	defer connClose()
+
+	operator, err := query.IsOperator(context, userID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	if !operator {
+		return http.StatusForbidden, errors.New("only operators can change exchange rates")
+	}
+
+	return http.StatusOK, nil
+}
+
+// importExchangeRates upserts the rates in a single database transaction.
+func importExchangeRates(context context.Context, exchangeRates []money.ExchangeRate) (*rates.Summary, int, error) {
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(context)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	defer connClose()
+	defer tx.Rollback(context)
+
+	summary, err := rates.Import(context, query.WithTx(tx), exchangeRates)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("exchange rate import failed")
+	}
+
+	if err := tx.Commit(context); err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("exchange rate import failed")
+	}
+
+	return &summary, http.StatusOK, nil
+}
+
+func CreateExchangeRates(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := verifyOperator(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	var newRates []db.UpsertExchangeRateParams
+
+	if err := json.NewDecoder(r.Body).Decode(&newRates); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	exchangeRates := make([]money.ExchangeRate, 0, len(newRates))
+
+	for _, newRate := range newRates {
+		base, err := money.NormalizeCurrency(newRate.BaseCurrency)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		quote, err := money.NormalizeCurrency(newRate.QuoteCurrency)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if base == quote {
+			http.Error(w, "base and quote currencies must differ", http.StatusBadRequest)
+			return
+		}
+
+		if !newRate.RateDate.Valid {
+			http.Error(w, "rate_date was not provided", http.StatusBadRequest)
+			return
+		}
+
+		exchangeRates = append(exchangeRates, money.ExchangeRate{
+			Date:  newRate.RateDate.Time,
+			Base:  base,
+			Quote: quote,
+			Rate:  newRate.Rate,
+		})
+	}
+
+	summary, statusCode, err := importExchangeRates(r.Context(), exchangeRates)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	serializedSummary, err := json.Marshal(summary)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedSummary)
+}
+
+// ImportExchangeRates loads a rate file sent as the request body. The format
+// is taken from the format query parameter or guessed from the content type.
+// Only operators can import rates.
+func ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := verifyOperator(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		format = rates.DetectFormat(r.Header.Get("Content-Type"))
+	}
+
+	exchangeRates, err := rates.Parse(format, http.MaxBytesReader(w, r.Body, maxRateFileSize))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	summary, statusCode, err := importExchangeRates(r.Context(), exchangeRates)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	serializedSummary, err := json.Marshal(summary)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedSummary)
+}
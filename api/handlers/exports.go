@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
+	"cashpal/statements"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// exportRows turns the transactions of an account into statement rows in the
+// account currency, with their categories and splits. Settle-up payments
+// move no money in the account and are left out.
+func exportRows(context context.Context, query *db.Queries, account db.Account) ([]statements.Row, error) {
+	transactions, err := query.ListTransactionForExport(context, account.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	splits, err := query.ListTransactionSplitsByAccount(context, account.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := query.ListCategoryByAccount(context, account.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	paths := categoryPaths(categories)
+	splitsByTransaction := make(map[int32][]db.TransactionSplit)
+
+	for _, split := range splits {
+		splitsByTransaction[split.TransactionID] = append(splitsByTransaction[split.TransactionID], split)
+	}
+
+	currencies := []string{account.Currency}
+
+	for _, transaction := range transactions {
+		if transaction.Transaction.Currency.Valid && transaction.Transaction.Currency.String != account.Currency {
+			currencies = append(currencies, transaction.Transaction.Currency.String)
+		}
+	}
+
+	var rateTable *money.RateTable
+
+	if len(currencies) > 1 {
+		rateTable, err = rates.LoadTable(context, query, currencies, today())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows := make([]statements.Row, 0, len(transactions))
+
+	for _, item := range transactions {
+		transaction := item.Transaction
+
+		if item.Sign == 0 {
+			continue
+		}
+
+		convert := func(amount money.Amount) (money.Amount, error) {
+			if !transaction.Currency.Valid || transaction.Currency.String == account.Currency {
+				return amount, nil
+			}
+
+			converted, err := rateTable.Convert(amount, transaction.Currency.String, account.Currency, transaction.TransactionDate.Time)
+
+			if err != nil {
+				return 0, err
+			}
+
+			return converted.RoundTo(account.Currency), nil
+		}
+
+		amount, err := convert(transaction.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sign := money.Amount(item.Sign)
+
+		row := statements.Row{
+			Date:        transaction.TransactionDate,
+			Amount:      sign * amount,
+			Description: transaction.Description,
+		}
+
+		if transaction.CategoryID.Valid {
+			row.Category = paths[transaction.CategoryID.Int32]
+		}
+
+		remaining := amount
+
+		for i, split := range splitsByTransaction[transaction.ID] {
+			splitAmount, err := convert(split.Amount)
+
+			if err != nil {
+				return nil, err
+			}
+
+			// Rounding each converted split could leave them a unit
+			// away from the converted total, so the last one takes the
+			// difference.
+			if i == len(splitsByTransaction[transaction.ID])-1 {
+				splitAmount = remaining
+			}
+
+			remaining -= splitAmount
+
+			exported := statements.Split{
+				Memo:   split.Memo,
+				Amount: sign * splitAmount,
+			}
+
+			if split.CategoryID.Valid {
+				exported.Category = paths[split.CategoryID.Int32]
+			}
+
+			row.Splits = append(row.Splits, exported)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ExportTransactions writes the transactions of an account as a QIF file.
+// The type query parameter picks the QIF account type, Bank by default, and
+// day_first=true writes dates day first. Amounts in other currencies are
+// converted to the account currency since QIF has no notion of currency.
+func ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != statements.FormatQIF {
+		http.Error(w, "only qif exports are supported", http.StatusBadRequest)
+		return
+	}
+
+	accountType := r.URL.Query().Get("type")
+
+	if accountType == "" {
+		accountType = statements.QIFBank
+	}
+
+	if accountType != statements.QIFBank && accountType != statements.QIFCCard && accountType != statements.QIFCash {
+		http.Error(w, "type must be Bank, CCard or Cash", http.StatusBadRequest)
+		return
+	}
+
+	dayFirst, err := boolParameter(r, "day_first")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	rows, err := exportRows(r.Context(), query, account)
+
+	if errors.Is(err, money.ErrRateNotFound) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "export failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/qif")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"account-%d.qif\"", account.ID))
+	w.WriteHeader(http.StatusOK)
+
+	if err := statements.WriteQIF(w, accountType, rows, dayFirst); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,606 @@
+package handlers
+
+import (
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/goal"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type goalResponse struct {
+	db.Goal
+	Currency string        `json:"currency"`
+	Progress goal.Progress `json:"progress"`
+}
+
+// verifyGoal checks the fields of a goal before it is saved.
+func verifyGoal(context context.Context, query *db.Queries, account db.Account, savingsGoal db.Goal) (int, error) {
+	if savingsGoal.Name == "" {
+		return http.StatusBadRequest, errors.New("name was not provided")
+	}
+
+	if savingsGoal.TargetAmount <= 0 {
+		return http.StatusBadRequest, errors.New("target_amount must be positive")
+	}
+
+	if err := savingsGoal.TargetAmount.Validate(account.Currency); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if !savingsGoal.TargetDate.Valid {
+		return http.StatusBadRequest, errors.New("target_date was not provided")
+	}
+
+	if savingsGoal.TargetDate.Time.Before(savingsGoal.StartDate.Time) {
+		return http.StatusBadRequest, errors.New("target_date cannot be before start_date")
+	}
+
+	return verifyCategory(context, query, account.ID, savingsGoal.CategoryID)
+}
+
+// goalContributions sums what was put towards a goal from its start date up
+// to asOf, in the account currency.
+//
+// A goal without a category saves up in the account itself, so it counts
+// every posting of the account: income adds to the goal and expenses take
+// from it. A goal with a category is saved for by paying money into that
+// category, such as an expense booked to Savings, so it counts the net
+// outflow of the account into the category and its subcategories: money
+// paid into them adds to the goal and money coming back out of them, such as
+// income booked to the category, takes from it.
+func goalContributions(context context.Context, query *db.Queries, savingsGoal db.Goal, currency string, asOf pgtype.Date) (money.Amount, error) {
+	contributionParams := db.ListGoalContributionsParams{
+		AccountID:  savingsGoal.AccountID,
+		CategoryID: savingsGoal.CategoryID,
+		FromDate:   savingsGoal.StartDate,
+		ToDate:     asOf,
+	}
+
+	totals, err := query.ListGoalContributions(context, contributionParams)
+
+	if err != nil {
+		return 0, err
+	}
+
+	currencies := []string{currency}
+
+	for _, total := range totals {
+		if total.Currency != currency {
+			currencies = append(currencies, total.Currency)
+		}
+	}
+
+	var rateTable *money.RateTable
+
+	if len(currencies) > 1 {
+		rateTable, err = rates.LoadTable(context, query, currencies, asOf)
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var contributed money.Amount
+
+	for _, total := range totals {
+		if total.Currency == currency {
+			contributed += total.Amount
+			continue
+		}
+
+		converted, err := rateTable.Convert(total.Amount, total.Currency, currency, total.EntryDate.Time)
+
+		if err != nil {
+			return 0, err
+		}
+
+		contributed += converted
+	}
+
+	// Postings are signed from the account's side, where money paid into
+	// the goal's category is negative.
+	if savingsGoal.CategoryID.Valid {
+		contributed = -contributed
+	}
+
+	return contributed.RoundTo(currency), nil
+}
+
+func newGoalResponse(context context.Context, query *db.Queries, account db.Account, savingsGoal db.Goal) (goalResponse, error) {
+	asOf := today()
+
+	current, err := goalContributions(context, query, savingsGoal, account.Currency, asOf)
+
+	if err != nil {
+		return goalResponse{}, err
+	}
+
+	response := goalResponse{
+		Goal:     savingsGoal,
+		Currency: account.Currency,
+		Progress: goal.Compute(savingsGoal.TargetAmount, current, savingsGoal.TargetDate.Time, asOf.Time, account.Currency),
+	}
+
+	return response, nil
+}
+
+func ListGoals(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	goals, err := query.ListGoalsByAccount(r.Context(), account.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]goalResponse, 0, len(goals))
+
+	for _, savingsGoal := range goals {
+		item, err := newGoalResponse(r.Context(), query, account, savingsGoal)
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "goal progress cannot be computed in the account currency", http.StatusUnprocessableEntity)
+			return
+		}
+
+		response = append(response, item)
+	}
+
+	serializedGoals, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedGoals)
+}
+
+func GetGoal(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	goalID, err := strconv.ParseInt(r.PathValue("goalID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	getGoalParams := db.GetGoalParams{
+		ID:        int32(goalID),
+		AccountID: account.ID,
+	}
+
+	savingsGoal, err := query.GetGoal(r.Context(), getGoalParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this goal does not exist", http.StatusNotFound)
+		return
+	}
+
+	response, err := newGoalResponse(r.Context(), query, account, savingsGoal)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal progress cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	serializedGoal, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedGoal)
+}
+
+// CreateGoal adds a savings goal to an account. Contributions are counted
+// from start_date, which defaults to today.
+func CreateGoal(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var newGoal db.CreateGoalParams
+
+	if err := json.NewDecoder(r.Body).Decode(&newGoal); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	newGoal.AccountID = int32(accountID)
+
+	if !newGoal.StartDate.Valid {
+		newGoal.StartDate = today()
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	candidate := db.Goal{
+		AccountID:    newGoal.AccountID,
+		Name:         newGoal.Name,
+		TargetAmount: newGoal.TargetAmount,
+		TargetDate:   newGoal.TargetDate,
+		CategoryID:   newGoal.CategoryID,
+		StartDate:    newGoal.StartDate,
+	}
+
+	statusCode, err = verifyGoal(r.Context(), query, account, candidate)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	savingsGoal, err := query.CreateGoal(r.Context(), newGoal)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal creation failed", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := newGoalResponse(r.Context(), query, account, savingsGoal)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal progress cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	serializedGoal, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedGoal)
+}
+
+// UpdateGoal changes the fields of a goal that are present in the request.
+func UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	goalID, err := strconv.ParseInt(r.PathValue("goalID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var updatedData db.UpdateGoalParams
+
+	if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	updatedData.ID = int32(goalID)
+	updatedData.AccountID = int32(accountID)
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	getGoalParams := db.GetGoalParams{
+		ID:        updatedData.ID,
+		AccountID: account.ID,
+	}
+
+	candidate, err := query.GetGoal(r.Context(), getGoalParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this goal does not exist", http.StatusNotFound)
+		return
+	}
+
+	if updatedData.Name.Valid {
+		candidate.Name = updatedData.Name.String
+	}
+
+	if updatedData.TargetAmount.Valid {
+		candidate.TargetAmount = updatedData.TargetAmount.Amount
+	}
+
+	if updatedData.TargetDate.Valid {
+		candidate.TargetDate = updatedData.TargetDate
+	}
+
+	if updatedData.CategoryID.Valid {
+		candidate.CategoryID = updatedData.CategoryID
+	}
+
+	if updatedData.StartDate.Valid {
+		candidate.StartDate = updatedData.StartDate
+	}
+
+	statusCode, err = verifyGoal(r.Context(), query, account, candidate)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	savingsGoal, err := query.UpdateGoal(r.Context(), updatedData)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal update failed", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := newGoalResponse(r.Context(), query, account, savingsGoal)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal progress cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	serializedGoal, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedGoal)
+}
+
+func DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	goalID, err := strconv.ParseInt(r.PathValue("goalID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	deleteGoalParams := db.DeleteGoalParams{
+		ID:        int32(goalID),
+		AccountID: int32(accountID),
+	}
+
+	deleted, err := query.DeleteGoal(r.Context(), deleteGoalParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "goal deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 {
+		http.Error(w, "this goal does not exist", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("goal deleted"))
+}
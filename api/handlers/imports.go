@@ -0,0 +1,930 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/budget"
+	"cashpal/config"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/statements"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxStatementFileSize is far above the size of a year of statements from
+// any bank.
+const maxStatementFileSize = 16 << 20
+
+const (
+	defaultImportDelimiter  = ","
+	defaultImportDateFormat = "YYYY-MM-DD"
+	defaultImportSkipRows   = 1
+)
+
+type importProfileRequest struct {
+	db.CreateImportProfileParams
+	SkipRows pgtype.Int4 `json:"skip_rows"`
+}
+
+// balanceCheck compares a balance reported by a statement with the balance
+// Cashpal computes for the account on the same day, in the same currency.
+type balanceCheck struct {
+	statements.Balance
+	CashpalBalance money.NullAmount `json:"cashpal_balance"`
+	Difference     money.NullAmount `json:"difference"`
+}
+
+// importDuplicate lists the transactions a statement line looks like a
+// duplicate of.
+type importDuplicate struct {
+	Line         int              `json:"line"`
+	Transactions []db.Transaction `json:"transactions"`
+}
+
+type importResponse struct {
+	statements.Statement
+	AlreadyImported    []statements.Row  `json:"already_imported"`
+	PossibleDuplicates []importDuplicate `json:"possible_duplicates"`
+	BalanceChecks      []balanceCheck    `json:"balance_checks"`
+	Committed          bool              `json:"committed"`
+	Transactions       []db.Transaction  `json:"transactions"`
+}
+
+// verifyStatementRows moves rows in an unsupported currency, or whose amount
+// their currency cannot hold, from the rows of the statement to its errors.
+// Rows in the account currency end up without a currency of their own.
+func verifyStatementRows(statement *statements.Statement, accountCurrency string) {
+	rows := statement.Rows[:0]
+
+	for _, row := range statement.Rows {
+		currency := accountCurrency
+
+		if row.Currency != "" {
+			normalized, err := money.NormalizeCurrency(row.Currency)
+
+			if err != nil {
+				statement.Fail(row.Line, "%s", err)
+				continue
+			}
+
+			currency = normalized
+		}
+
+		if err := row.Amount.Validate(currency); err != nil {
+			statement.Fail(row.Line, "%s", err)
+			continue
+		}
+
+		if err := validateSplitAmounts(row.Splits, currency); err != nil {
+			statement.Fail(row.Line, "%s", err)
+			continue
+		}
+
+		row.Currency = ""
+
+		if currency != accountCurrency {
+			row.Currency = currency
+		}
+
+		rows = append(rows, row)
+	}
+
+	statement.Rows = rows
+
+	sort.SliceStable(statement.Errors, func(i, j int) bool {
+		return statement.Errors[i].Line < statement.Errors[j].Line
+	})
+}
+
+func validateSplitAmounts(splits []statements.Split, currency string) error {
+	for _, split := range splits {
+		if err := split.Amount.Validate(currency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// categoryResolver finds the categories of an account by their path, such as
+// "Food:Groceries", and creates the ones that are missing so that imported
+// files keep their categories.
+type categoryResolver struct {
+	query     *db.Queries
+	accountID int32
+	ids       map[string]int32
+}
+
+func newCategoryResolver(context context.Context, query *db.Queries, accountID int32) (*categoryResolver, error) {
+	categories, err := query.ListCategoryByAccount(context, accountID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &categoryResolver{
+		query:     query,
+		accountID: accountID,
+		ids:       make(map[string]int32, len(categories)),
+	}
+
+	for categoryID, path := range categoryPaths(categories) {
+		resolver.ids[strings.ToLower(path)] = categoryID
+	}
+
+	return resolver, nil
+}
+
+// resolve returns the category at path, creating it and its missing
+// ancestors. Names are matched regardless of case. An empty path has no
+// category.
+func (c *categoryResolver) resolve(context context.Context, path string) (pgtype.Int4, error) {
+	var category pgtype.Int4
+	known := ""
+
+	for _, name := range strings.Split(path, ":") {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		if known != "" {
+			known += ":"
+		}
+
+		known += strings.ToLower(name)
+
+		if categoryID, ok := c.ids[known]; ok {
+			category = pgtype.Int4{Int32: categoryID, Valid: true}
+			continue
+		}
+
+		newCategory := db.CreateCategoryParams{
+			AccountID: c.accountID,
+			ParentID:  category,
+			Name:      name,
+		}
+
+		created, err := c.query.CreateCategory(context, newCategory)
+
+		if err != nil {
+			return pgtype.Int4{}, err
+		}
+
+		c.ids[known] = created.ID
+		category = pgtype.Int4{Int32: created.ID, Valid: true}
+	}
+
+	return category, nil
+}
+
+// skipImported takes the rows whose external id the account already has, or
+// that appear twice in the statement, out of the statement rows and returns
+// them.
+func skipImported(context context.Context, query *db.Queries, accountID int32, statement *statements.Statement) ([]statements.Row, error) {
+	skipped := []statements.Row{}
+	var externalIDs []string
+
+	for _, row := range statement.Rows {
+		if row.ExternalID != "" {
+			externalIDs = append(externalIDs, row.ExternalID)
+		}
+	}
+
+	if len(externalIDs) == 0 {
+		return skipped, nil
+	}
+
+	externalIDParams := db.ListTransactionExternalIDsParams{
+		AccountID:   accountID,
+		ExternalIds: externalIDs,
+	}
+
+	existing, err := query.ListTransactionExternalIDs(context, externalIDParams)
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(existing))
+
+	for _, externalID := range existing {
+		seen[externalID] = true
+	}
+
+	rows := statement.Rows[:0]
+
+	for _, row := range statement.Rows {
+		if row.ExternalID != "" && seen[row.ExternalID] {
+			skipped = append(skipped, row)
+			continue
+		}
+
+		if row.ExternalID != "" {
+			seen[row.ExternalID] = true
+		}
+
+		rows = append(rows, row)
+	}
+
+	statement.Rows = rows
+
+	return skipped, nil
+}
+
+// checkBalances compares the balances reported by a statement with the
+// account. A balance whose currency the account cannot be converted to is
+// reported without a comparison.
+func checkBalances(context context.Context, query *db.Queries, account db.Account, balances []statements.Balance) []balanceCheck {
+	checks := make([]balanceCheck, 0, len(balances))
+
+	for _, balance := range balances {
+		check := balanceCheck{Balance: balance}
+		currency := account.Currency
+
+		if balance.Currency != "" {
+			currency = balance.Currency
+		}
+
+		cashpalBalance, err := accountBalance(context, query, account.ID, balance.Date, currency)
+
+		if err != nil {
+			log.Println(err.Error())
+		} else {
+			check.CashpalBalance = money.NullAmount{Amount: cashpalBalance, Valid: true}
+			check.Difference = money.NullAmount{Amount: balance.Amount - cashpalBalance, Valid: true}
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// importRows records every row as an income or an expense of the account,
+// each in its own journal entry, with its category and splits. Categories
+// the account does not have yet are created. Rows that look like a duplicate
+// of a transaction the account already had are recorded too and returned
+// with the transactions they look like, found within window days of their
+// date. The queries should be bound to a database transaction so that a
+// failing row leaves nothing behind.
+func importRows(context context.Context, query *db.Queries, account db.Account, userID int32, rows []statements.Row, window int) ([]db.Transaction, []importDuplicate, int, error) {
+	incomeType, err := query.GetTransactionTypeByName(context, transactionTypeIncome)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, nil, http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	expenseType, err := query.GetTransactionTypeByName(context, transactionTypeExpense)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, nil, http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	categories, err := newCategoryResolver(context, query, account.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, nil, http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	envelopes, err := budget.Lock(context, query, account.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, nil, http.StatusUnprocessableEntity, errors.New("envelope balances cannot be computed in the account currency")
+	}
+
+	created := make([]db.Transaction, 0, len(rows))
+	createdIDs := make([]int32, 0, len(rows))
+	possibleDuplicates := []importDuplicate{}
+
+	for _, row := range rows {
+		newTransaction := db.CreateTransactionParams{
+			AccountID:         account.ID,
+			UserID:            userID,
+			TransactionDate:   row.Date,
+			TransactionTypeID: incomeType.ID,
+			Description:       row.Description,
+			Currency:          pgtype.Text{String: row.Currency, Valid: row.Currency != ""},
+			ExternalID:        pgtype.Text{String: row.ExternalID, Valid: row.ExternalID != ""},
+		}
+
+		if len(row.Metadata) > 0 {
+			newTransaction.Metadata, err = json.Marshal(row.Metadata)
+
+			if err != nil {
+				log.Println(err.Error())
+				return nil, nil, http.StatusInternalServerError, errors.New("statement import failed")
+			}
+		}
+
+		transactionType := incomeType
+		amount := row.Amount
+
+		if row.Amount < 0 {
+			transactionType = expenseType
+			amount = -row.Amount
+		}
+
+		newTransaction.TransactionTypeID = transactionType.ID
+
+		currency := account.Currency
+
+		if row.Currency != "" {
+			currency = row.Currency
+		}
+
+		lines, statusCode, err := importLines(context, query, categories, account, currency, amount, row)
+
+		if err != nil {
+			return nil, nil, statusCode, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+
+		// Rows of the same statement are not duplicates of each other: two
+		// equal payments on one day are each listed by the bank.
+		found, err := findDuplicates(context, query, account, newTransaction, amount, window, createdIDs)
+
+		if err != nil {
+			log.Println(err.Error())
+			return nil, nil, http.StatusInternalServerError, errors.New("service unavailable")
+		}
+
+		if len(found) > 0 {
+			possibleDuplicates = append(possibleDuplicates, importDuplicate{Line: row.Line, Transactions: found})
+		}
+
+		leg := ledger.Leg{
+			Transaction: newTransaction,
+			Postings:    ledger.IncomeExpense(0, account.ID, currency, transactionType.Sign, lines),
+		}
+
+		transactions, err := ledger.Record(context, query, []ledger.Leg{leg})
+
+		if err != nil {
+			log.Println(err.Error())
+
+			if isUniqueViolation(err) {
+				return nil, nil, http.StatusConflict, fmt.Errorf("line %d was imported by someone else in the meantime", row.Line)
+			}
+
+			return nil, nil, http.StatusInternalServerError, fmt.Errorf("line %d could not be imported", row.Line)
+		}
+
+		splits, err := query.ListTransactionSplits(context, transactions[0].ID)
+
+		if err != nil {
+			log.Println(err.Error())
+			return nil, nil, http.StatusInternalServerError, errors.New("statement import failed")
+		}
+
+		event := audit.Event{
+			AccountID:   account.ID,
+			UserID:      userID,
+			Type:        audit.TransactionCreated,
+			Description: fmt.Sprintf("transaction %d imported from line %d", transactions[0].ID, row.Line),
+			After:       newTransactionResponse(transactions[0], splits),
+		}
+
+		if _, err := audit.Record(context, query, event); err != nil {
+			log.Println(err.Error())
+			return nil, nil, http.StatusInternalServerError, errors.New("statement import failed")
+		}
+
+		created = append(created, transactions[0])
+		createdIDs = append(createdIDs, transactions[0].ID)
+	}
+
+	statusCode, err := verifyEnvelopes(context, query, envelopes)
+
+	if err != nil {
+		return nil, nil, statusCode, err
+	}
+
+	return created, possibleDuplicates, http.StatusOK, nil
+}
+
+// importLines returns the lines an imported row is booked with, creating
+// the categories it names. Split amounts of a statement are signed like the
+// row, those of a transaction like its amount; a split row takes its
+// categories from its splits.
+func importLines(context context.Context, query *db.Queries, categories *categoryResolver, account db.Account, currency string, amount money.Amount, row statements.Row) ([]ledger.Line, int, error) {
+	var categoryID pgtype.Int4
+	var splits []splitRequest
+
+	for _, split := range row.Splits {
+		splitCategoryID, err := categories.resolve(context, split.Category)
+
+		if err != nil {
+			log.Println(err.Error())
+			return nil, http.StatusInternalServerError, fmt.Errorf("category %q could not be created", split.Category)
+		}
+
+		splitAmount := split.Amount
+
+		if row.Amount < 0 {
+			splitAmount = -splitAmount
+		}
+
+		splits = append(splits, splitRequest{
+			CategoryID: splitCategoryID,
+			Amount:     splitAmount,
+			Memo:       split.Memo,
+		})
+	}
+
+	if len(splits) == 0 {
+		var err error
+
+		categoryID, err = categories.resolve(context, row.Category)
+
+		if err != nil {
+			log.Println(err.Error())
+			return nil, http.StatusInternalServerError, fmt.Errorf("category %q could not be created", row.Category)
+		}
+	}
+
+	return transactionLines(context, query, account.ID, currency, amount, categoryID, splits)
+}
+
+func ListImportProfiles(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	profiles, err := query.ListImportProfiles(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if profiles == nil {
+		profiles = []db.ImportProfile{}
+	}
+
+	serializedProfiles, err := json.Marshal(profiles)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedProfiles)
+}
+
+// CreateImportProfile saves how the CSV statements of one bank are laid
+// out. Column indexes start at 0. A statement either has a single signed
+// amount_column, which negate_amounts flips for banks that show spending as
+// positive, or a debit_column and a credit_column.
+func CreateImportProfile(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request importProfileRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	newProfile := request.CreateImportProfileParams
+
+	newProfile.AccountID = int32(accountID)
+	newProfile.SkipRows = defaultImportSkipRows
+
+	if request.SkipRows.Valid {
+		newProfile.SkipRows = request.SkipRows.Int32
+	}
+
+	if newProfile.Delimiter == "" {
+		newProfile.Delimiter = defaultImportDelimiter
+	}
+
+	if newProfile.DateFormat == "" {
+		newProfile.DateFormat = defaultImportDateFormat
+	}
+
+	if newProfile.DecimalSeparator == "" {
+		newProfile.DecimalSeparator = "."
+	}
+
+	if newProfile.DescriptionColumns == nil {
+		newProfile.DescriptionColumns = []int32{}
+	}
+
+	if err := statements.ValidateProfile(newProfile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	profile, err := query.CreateImportProfile(r.Context(), newProfile)
+
+	if err != nil {
+		log.Println(err.Error())
+
+		if isUniqueViolation(err) {
+			http.Error(w, "an import profile with this name already exists here", http.StatusConflict)
+			return
+		}
+
+		http.Error(w, "import profile creation failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedProfile, err := json.Marshal(profile)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedProfile)
+}
+
+func DeleteImportProfile(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	profileID, err := strconv.ParseInt(r.PathValue("profileID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "import profile id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	deleteProfileParams := db.DeleteImportProfileParams{
+		ID:        int32(profileID),
+		AccountID: int32(accountID),
+	}
+
+	deleted, err := query.DeleteImportProfile(r.Context(), deleteProfileParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "import profile deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 {
+		http.Error(w, "this import profile does not exist", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("import profile deleted"))
+}
+
+// boolParameter reads an optional true or false query parameter.
+func boolParameter(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return false, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+
+	return parsed, nil
+}
+
+// readStatement parses the statement sent as the request body. The format
+// is taken from the format query parameter or guessed from the content type
+// and defaults to CSV, which needs the import profile named by the
+// profile_id query parameter. QIF dates are read month first unless
+// day_first=true.
+func readStatement(w http.ResponseWriter, r *http.Request, query *db.Queries, accountID int32) (statements.Statement, int, error) {
+	format := r.URL.Query().Get("format")
+
+	if format == "" {
+		format = statements.DetectFormat(r.Header.Get("Content-Type"))
+	}
+
+	if format == "" {
+		format = statements.FormatCSV
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxStatementFileSize)
+
+	if format == statements.FormatQIF {
+		dayFirst, err := boolParameter(r, "day_first")
+
+		if err != nil {
+			return statements.Statement{}, http.StatusBadRequest, err
+		}
+
+		statement, err := statements.ParseQIF(body, dayFirst)
+
+		if err != nil {
+			log.Println(err.Error())
+			return statement, http.StatusBadRequest, err
+		}
+
+		return statement, http.StatusOK, nil
+	}
+
+	if format != statements.FormatCSV {
+		statement, err := statements.Parse(format, body)
+
+		if err != nil {
+			log.Println(err.Error())
+			return statement, http.StatusBadRequest, err
+		}
+
+		return statement, http.StatusOK, nil
+	}
+
+	profileID, err := strconv.ParseInt(r.URL.Query().Get("profile_id"), 10, 32)
+
+	if err != nil {
+		return statements.Statement{}, http.StatusBadRequest, errors.New("profile_id is missing or malformed")
+	}
+
+	getProfileParams := db.GetImportProfileParams{
+		ID:        int32(profileID),
+		AccountID: accountID,
+	}
+
+	profile, err := query.GetImportProfile(r.Context(), getProfileParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		return statements.Statement{}, http.StatusNotFound, errors.New("this import profile does not exist")
+	}
+
+	statement, err := statements.ParseCSV(body, profile)
+
+	if err != nil {
+		log.Println(err.Error())
+		return statement, http.StatusBadRequest, err
+	}
+
+	return statement, http.StatusOK, nil
+}
+
+// ImportStatement reads a statement sent as the request body, see
+// readStatement for the formats. By default it only previews the import: the
+// rows are recorded and the statement balances compared with the account,
+// then everything is rolled back. With commit=true the rows are kept, all in
+// one database transaction, and statements that still have errors are
+// refused. Rows whose external id the account already has are never
+// imported again. Rows that only look like a transaction the account already
+// has are listed as possible duplicates, and with strict=true they keep the
+// statement from being committed.
+func ImportStatement(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	commit, err := boolParameter(r, "commit")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	strict, err := boolParameter(r, "strict")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	statusCode, err := verifyMembership(r.Context(), qtx, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if commit {
+		statusCode, err = verifyWritable(r.Context(), qtx, int32(accountID))
+
+		if err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+	}
+
+	account, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	statement, statusCode, err := readStatement(w, r, qtx, account.ID)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	verifyStatementRows(&statement, account.Currency)
+
+	alreadyImported, err := skipImported(r.Context(), qtx, account.ID, &statement)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if commit && len(statement.Errors) > 0 {
+		http.Error(w, fmt.Sprintf("%d lines of the statement cannot be imported, preview it to see why", len(statement.Errors)), http.StatusUnprocessableEntity)
+		return
+	}
+
+	transactions, possibleDuplicates, statusCode, err := importRows(r.Context(), qtx, account, contextUserID, statement.Rows, config.GetDuplicateWindow())
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if commit && strict && len(possibleDuplicates) > 0 {
+		http.Error(w, fmt.Sprintf("%d lines of the statement look like transactions the account already has, preview it to see which", len(possibleDuplicates)), http.StatusConflict)
+		return
+	}
+
+	response := importResponse{
+		Statement:          statement,
+		AlreadyImported:    alreadyImported,
+		PossibleDuplicates: possibleDuplicates,
+		BalanceChecks:      checkBalances(r.Context(), qtx, account, statement.Balances),
+		Transactions:       []db.Transaction{},
+	}
+
+	if commit {
+		if err := tx.Commit(r.Context()); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "statement import failed", http.StatusInternalServerError)
+			return
+		}
+
+		response.Committed = true
+		response.Transactions = transactions
+	}
+
+	serializedImport, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedImport)
+}
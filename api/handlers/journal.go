@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+type journalEntryResponse struct {
+	db.JournalEntry
+	Postings []db.ListPostingsByJournalEntryRow `json:"postings"`
+}
+
+// GetTransactionPostings shows the journal entry behind a transaction, with
+// every posting it is made of and the account and kind of ledger account each
+// one is booked on.
+func GetTransactionPostings(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := query.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := query.GetJournalEntry(r.Context(), transaction.JournalEntryID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	postings, err := query.ListPostingsByJournalEntry(r.Context(), entry.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	serializedEntry, err := json.Marshal(journalEntryResponse{JournalEntry: entry, Postings: postings})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedEntry)
+}
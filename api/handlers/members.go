@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cashpal/audit"
 	"cashpal/database"
 	db "cashpal/database/generated"
 	"cashpal/middleware"
@@ -150,13 +151,20 @@ func verifyAdminRole(context context.Context, query db.Queries, accountID int32)
 	}
 
 	if logedMember.MemberRoleID != 1 {
-		return errors.New("administrator privileges are needed to modify the member list")
+		return errors.New("administrator privileges are needed to modify this account")
 	}
 
 	return nil
 }
 
 func AddMember(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
 	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
 
 	if err != nil {
@@ -175,7 +183,7 @@ func AddMember(w http.ResponseWriter, r *http.Request) {
 
 	newMember.AccountID = int32(accountID)
 
-	query, connClose, err := database.GetNewConnection(r.Context())
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
 
 	if err != nil {
 		log.Println(err.Error())
@@ -184,13 +192,23 @@ func AddMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
 
-	if err := verifyAdminRole(r.Context(), *query, int32(accountID)); err != nil {
+	if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	member, err := query.CreateMember(r.Context(), newMember)
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	member, err := qtx.CreateMember(r.Context(), newMember)
 
 	if err != nil {
 		fmt.Println(err)
@@ -198,6 +216,26 @@ func AddMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	event := audit.Event{
+		AccountID:   member.AccountID,
+		UserID:      contextUserID,
+		Type:        audit.MemberAdded,
+		Description: fmt.Sprintf("user %d added as a member", member.UserID),
+		After:       member,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error adding this member", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error adding this member", http.StatusInternalServerError)
+		return
+	}
+
 	serializedMember, err := json.Marshal(member)
 
 	if err != nil {
@@ -211,6 +249,13 @@ func AddMember(w http.ResponseWriter, r *http.Request) {
 }
 
 func UpdateMember(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
 	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
 
 	if err != nil {
@@ -238,7 +283,7 @@ func UpdateMember(w http.ResponseWriter, r *http.Request) {
 	updatedMemberData.AccountID = int32(accountID)
 	updatedMemberData.UserID = int32(userID)
 
-	query, connClose, err := database.GetNewConnection(r.Context())
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
 
 	if err != nil {
 		log.Println(err.Error())
@@ -247,19 +292,63 @@ func UpdateMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
 
-	if err := verifyAdminRole(r.Context(), *query, int32(accountID)); err != nil {
+	if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	member, err := query.UpdateMember(r.Context(), updatedMemberData)
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	currentMemberParams := db.GetMemberParams{
+		AccountID: int32(accountID),
+		UserID:    int32(userID),
+	}
+
+	currentMember, err := qtx.GetMember(r.Context(), currentMemberParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this member does not exist", http.StatusNotFound)
+		return
+	}
+
+	member, err := qtx.UpdateMember(r.Context(), updatedMemberData)
 	if err != nil {
 		fmt.Println(err)
 		http.Error(w, "user update failed", http.StatusNotFound)
 		return
 	}
 
+	event := audit.Event{
+		AccountID:   member.AccountID,
+		UserID:      contextUserID,
+		Type:        audit.MemberUpdated,
+		Description: fmt.Sprintf("member %d updated", member.UserID),
+		Before:      currentMember,
+		After:       member,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "user update failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "user update failed", http.StatusInternalServerError)
+		return
+	}
+
 	serializedMember, err := json.Marshal(member)
 
 	if err != nil {
@@ -273,6 +362,13 @@ func UpdateMember(w http.ResponseWriter, r *http.Request) {
 }
 
 func DeleteMember(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
 	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
 
 	if err != nil {
@@ -289,7 +385,7 @@ func DeleteMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query, connClose, err := database.GetNewConnection(r.Context())
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
 
 	if err != nil {
 		log.Println(err.Error())
@@ -298,23 +394,66 @@ func DeleteMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer connClose()
+	defer tx.Rollback(r.Context())
 
-	if err := verifyAdminRole(r.Context(), *query, int32(accountID)); err != nil {
+	qtx := query.WithTx(tx)
+
+	if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	currentMemberParams := db.GetMemberParams{
+		AccountID: int32(accountID),
+		UserID:    int32(userID),
+	}
+
+	currentMember, err := qtx.GetMember(r.Context(), currentMemberParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this member does not exist", http.StatusNotFound)
+		return
+	}
+
 	memberData := db.DeleteMemberParams{
 		AccountID: int32(accountID),
 		UserID:    int32(userID),
 	}
 
-	if err := query.DeleteMember(r.Context(), memberData); err != nil {
+	if err := qtx.DeleteMember(r.Context(), memberData); err != nil {
 		fmt.Println(err.Error())
 		http.Error(w, "error when deleting member", http.StatusNotFound)
 		return
 	}
 
+	event := audit.Event{
+		AccountID:   currentMember.AccountID,
+		UserID:      contextUserID,
+		Type:        audit.MemberRemoved,
+		Description: fmt.Sprintf("member %d removed", currentMember.UserID),
+		Before:      currentMember,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error when deleting member", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error when deleting member", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("member deleted"))
 }
@@ -0,0 +1,605 @@
+package handlers
+
+import (
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/recurrence"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const defaultUpcomingDays = 30
+
+type upcomingOccurrence struct {
+	RuleID            int32        `json:"rule_id"`
+	OccurrenceDate    pgtype.Date  `json:"occurrence_date"`
+	TransactionTypeID int32        `json:"transaction_type_id"`
+	Amount            money.Amount `json:"amount"`
+	Currency          pgtype.Text  `json:"currency"`
+	Description       string       `json:"description"`
+	CategoryID        pgtype.Int4  `json:"category_id"`
+}
+
+type skipOccurrenceRequest struct {
+	OccurrenceDate pgtype.Date `json:"occurrence_date"`
+}
+
+// verifyRecurringRule checks everything about a new rule that the database
+// constraints would otherwise reject with a less helpful error.
+func verifyRecurringRule(context context.Context, query *db.Queries, account db.Account, newRule *db.CreateRecurringRuleParams) (int, error) {
+	if newRule.IntervalCount == 0 {
+		newRule.IntervalCount = 1
+	}
+
+	schedule := recurrence.Rule{
+		Frequency:  newRule.Frequency,
+		Interval:   int(newRule.IntervalCount),
+		DayOfMonth: int(newRule.DayOfMonth.Int32),
+		Start:      newRule.StartDate.Time,
+		Count:      int(newRule.OccurrenceCount.Int32),
+	}
+
+	if newRule.EndDate.Valid {
+		schedule.End = newRule.EndDate.Time
+	}
+
+	if err := schedule.Validate(); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if newRule.DayOfMonth.Valid && newRule.DayOfMonth.Int32 == 0 {
+		return http.StatusBadRequest, errors.New("day_of_month must be between 1 and 31")
+	}
+
+	if newRule.OccurrenceCount.Valid && newRule.OccurrenceCount.Int32 <= 0 {
+		return http.StatusBadRequest, errors.New("occurrence_count must be greater than zero")
+	}
+
+	transactionType, err := query.GetTransactionType(context, newRule.TransactionTypeID)
+
+	if err != nil {
+		return http.StatusBadRequest, errors.New("transaction_type_id is not a known transaction type")
+	}
+
+	if transactionType.Name == transactionTypeTransferIn || transactionType.Name == transactionTypeTransferOut {
+		return http.StatusBadRequest, errors.New("recurring transfers are not supported")
+	}
+
+	if transactionType.Name == transactionTypeSettlement {
+		return http.StatusBadRequest, errors.New("recurring settle-up payments are not supported")
+	}
+
+	if newRule.Amount <= 0 {
+		return http.StatusBadRequest, errors.New("amount must be greater than zero")
+	}
+
+	currency := account.Currency
+
+	if newRule.Currency.Valid {
+		currency, err = money.NormalizeCurrency(newRule.Currency.String)
+
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+
+		newRule.Currency.String = currency
+	}
+
+	if err := newRule.Amount.Validate(currency); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return verifyCategory(context, query, account.ID, newRule.CategoryID)
+}
+
+func ListRecurringRules(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	rules, err := query.ListRecurringRulesByAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if rules == nil {
+		rules = []db.RecurringRule{}
+	}
+
+	serializedRules, err := json.Marshal(rules)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedRules)
+}
+
+func GetRecurringRule(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(r.PathValue("ruleID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "rule id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	getRuleParams := db.GetRecurringRuleParams{
+		ID:        int32(ruleID),
+		AccountID: int32(accountID),
+	}
+
+	rule, err := query.GetRecurringRule(r.Context(), getRuleParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this recurring rule does not exist", http.StatusNotFound)
+		return
+	}
+
+	serializedRule, err := json.Marshal(rule)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedRule)
+}
+
+// CreateRecurringRule stores a rule. Its occurrences are posted by the
+// scheduler, starting with any that are already due.
+func CreateRecurringRule(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var newRule db.CreateRecurringRuleParams
+
+	if err := json.NewDecoder(r.Body).Decode(&newRule); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	newRule.AccountID = int32(accountID)
+	newRule.UserID = contextUserID
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	statusCode, err = verifyRecurringRule(r.Context(), query, account, &newRule)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	rule, err := query.CreateRecurringRule(r.Context(), newRule)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "recurring rule creation failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedRule, err := json.Marshal(rule)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedRule)
+}
+
+// DeleteRecurringRule stops a rule. Transactions it already posted are kept.
+func DeleteRecurringRule(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(r.PathValue("ruleID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "rule id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	deleteRuleParams := db.DeleteRecurringRuleParams{
+		ID:        int32(ruleID),
+		AccountID: int32(accountID),
+	}
+
+	if err := query.DeleteRecurringRule(r.Context(), deleteRuleParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "recurring rule deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("recurring rule deleted"))
+}
+
+// ListUpcomingOccurrences lists the occurrences of every rule of an account
+// from today through ?until= (30 days ahead by default) that were neither
+// posted nor skipped yet.
+func ListUpcomingOccurrences(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	from := today()
+	until := pgtype.Date{Time: from.Time.AddDate(0, 0, defaultUpcomingDays), Valid: true}
+
+	if value := r.URL.Query().Get("until"); value != "" {
+		if until, err = parseDateFilter(value); err != nil {
+			http.Error(w, "until must be a date formatted as YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	rules, err := query.ListRecurringRulesByAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	occurrencesParams := db.ListRecurringOccurrencesByAccountParams{
+		AccountID: int32(accountID),
+		FromDate:  from,
+		ToDate:    until,
+	}
+
+	occurrences, err := query.ListRecurringOccurrencesByAccount(r.Context(), occurrencesParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	type occurrenceKey struct {
+		ruleID int32
+		date   time.Time
+	}
+
+	handled := make(map[occurrenceKey]bool)
+
+	for _, occurrence := range occurrences {
+		handled[occurrenceKey{occurrence.RuleID, occurrence.OccurrenceDate.Time}] = true
+	}
+
+	upcoming := []upcomingOccurrence{}
+
+	for _, rule := range rules {
+		for _, date := range recurrence.NewRule(rule).Between(from.Time, until.Time) {
+			if handled[occurrenceKey{rule.ID, date}] {
+				continue
+			}
+
+			upcoming = append(upcoming, upcomingOccurrence{
+				RuleID:            rule.ID,
+				OccurrenceDate:    pgtype.Date{Time: date, Valid: true},
+				TransactionTypeID: rule.TransactionTypeID,
+				Amount:            rule.Amount,
+				Currency:          rule.Currency,
+				Description:       rule.Description,
+				CategoryID:        rule.CategoryID,
+			})
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].OccurrenceDate.Time.Before(upcoming[j].OccurrenceDate.Time)
+	})
+
+	serializedUpcoming, err := json.Marshal(upcoming)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedUpcoming)
+}
+
+// SkipOccurrence marks one date of a rule so the scheduler never posts it.
+func SkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(r.PathValue("ruleID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "rule id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request skipOccurrenceRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	if !request.OccurrenceDate.Valid {
+		http.Error(w, "occurrence_date was not provided", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	getRuleParams := db.GetRecurringRuleParams{
+		ID:        int32(ruleID),
+		AccountID: int32(accountID),
+	}
+
+	rule, err := query.GetRecurringRule(r.Context(), getRuleParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this recurring rule does not exist", http.StatusNotFound)
+		return
+	}
+
+	if !recurrence.NewRule(rule).Includes(request.OccurrenceDate.Time) {
+		http.Error(w, "the rule has no occurrence on this date", http.StatusBadRequest)
+		return
+	}
+
+	claim := db.ClaimRecurringOccurrenceParams{
+		RuleID:         rule.ID,
+		OccurrenceDate: request.OccurrenceDate,
+		Status:         recurrence.StatusSkipped,
+	}
+
+	occurrence, err := query.ClaimRecurringOccurrence(r.Context(), claim)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "this occurrence was already posted or skipped", http.StatusConflict)
+		return
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "skipping the occurrence failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedOccurrence, err := json.Marshal(occurrence)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedOccurrence)
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/settle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type settlementPaymentRequest struct {
+	FromUserID      int32        `json:"from_user_id"`
+	ToUserID        int32        `json:"to_user_id"`
+	Amount          money.Amount `json:"amount"`
+	Currency        pgtype.Text  `json:"currency"`
+	TransactionDate pgtype.Date  `json:"transaction_date"`
+	Description     string       `json:"description"`
+}
+
+type settlementPaymentResponse struct {
+	db.Transaction
+	ToUserID int32 `json:"to_user_id"`
+}
+
+type memberLedgerEntry struct {
+	TransactionID   int32        `json:"transaction_id"`
+	TransactionDate pgtype.Date  `json:"transaction_date"`
+	TransactionType string       `json:"transaction_type"`
+	Description     string       `json:"description"`
+	PaidBy          int32        `json:"paid_by"`
+	Amount          money.Amount `json:"amount"`
+	Balance         money.Amount `json:"balance"`
+}
+
+type memberLedgerResponse struct {
+	UserID   int32               `json:"user_id"`
+	Currency string              `json:"currency"`
+	Balance  money.Amount        `json:"balance"`
+	Entries  []memberLedgerEntry `json:"entries"`
+}
+
+// CreateSettlementPayment records that one member paid another back. The
+// payment is a settlement transaction: it has no postings, so it never
+// changes the account balance or counts as spending, and its only share
+// names the member who was paid. Only the payer, the payee or an
+// administrator can record it.
+func CreateSettlementPayment(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request settlementPaymentRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.FromUserID == 0 {
+		request.FromUserID = contextUserID
+	}
+
+	if request.FromUserID == request.ToUserID {
+		http.Error(w, "a member cannot pay themselves back", http.StatusBadRequest)
+		return
+	}
+
+	if request.Amount <= 0 {
+		http.Error(w, "amount must be greater than zero", http.StatusBadRequest)
+		return
+	}
+
+	if !request.TransactionDate.Valid {
+		request.TransactionDate = today()
+	}
+
+	if request.Description == "" {
+		request.Description = "Settle-up payment"
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	statusCode, err := verifyMembership(r.Context(), qtx, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	statusCode, err = verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	// Members record their own payments; anyone else's takes an admin.
+	if contextUserID != request.FromUserID && contextUserID != request.ToUserID {
+		if err := verifyAdminRole(r.Context(), *qtx, int32(accountID)); err != nil {
+			http.Error(w, "only the payer, the payee or an administrator can record this payment", http.StatusForbidden)
+			return
+		}
+	}
+
+	memberParams := db.GetMemberParams{
+		AccountID: int32(accountID),
+		UserID:    request.FromUserID,
+	}
+
+	if _, err := qtx.GetMember(r.Context(), memberParams); err != nil {
+		http.Error(w, fmt.Sprintf("user %d is not a member of this account", request.FromUserID), http.StatusBadRequest)
+		return
+	}
+
+	account, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	currency := account.Currency
+
+	if request.Currency.Valid {
+		currency, err = money.NormalizeCurrency(request.Currency.String)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		request.Currency.String = currency
+	}
+
+	if err := request.Amount.Validate(currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settlementType, err := qtx.GetTransactionTypeByName(r.Context(), transactionTypeSettlement)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	newTransaction := db.CreateTransactionParams{
+		AccountID:         account.ID,
+		UserID:            request.FromUserID,
+		TransactionDate:   request.TransactionDate,
+		TransactionTypeID: settlementType.ID,
+		Description:       request.Description,
+		Currency:          request.Currency,
+	}
+
+	// Money changing hands between members does not move money of the
+	// account, so the payment has no postings; its amount lives in the share
+	// of the payee.
+	transactions, err := ledger.Record(r.Context(), qtx, []ledger.Leg{{Transaction: newTransaction}})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "settle-up payment failed", http.StatusInternalServerError)
+		return
+	}
+
+	payee := sharesRequest{
+		Method:       settle.Equal,
+		Participants: []shareParticipant{{UserID: request.ToUserID}},
+	}
+
+	statusCode, err = verifyParticipants(r.Context(), qtx, account.ID, payee.Participants)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	if _, statusCode, err := saveShares(r.Context(), qtx, transactions[0], request.Amount, currency, payee); err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	payment, err := qtx.GetTransaction(r.Context(), transactions[0].ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "settle-up payment failed", http.StatusInternalServerError)
+		return
+	}
+
+	response := settlementPaymentResponse{
+		Transaction: payment,
+		ToUserID:    request.ToUserID,
+	}
+
+	event := audit.Event{
+		AccountID:   account.ID,
+		UserID:      contextUserID,
+		Type:        audit.TransactionCreated,
+		Description: fmt.Sprintf("settle-up payment %d from user %d to user %d", response.ID, request.FromUserID, request.ToUserID),
+		After:       response,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "settle-up payment failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "settle-up payment failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedPayment, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedPayment)
+}
+
+// GetMemberLedger lists the shared transactions and settle-up payments of a
+// member, oldest first, with what each one changed in what the member is
+// owed and the running balance.
+func GetMemberLedger(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseInt(r.PathValue("userID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "user id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	shares, err := accountShares(r.Context(), query, account)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	response := memberLedgerResponse{
+		UserID:   int32(userID),
+		Currency: account.Currency,
+		Entries:  []memberLedgerEntry{},
+	}
+
+	// Shares come ordered by transaction, so the shares of one transaction
+	// always follow each other.
+	for _, share := range shares {
+		if share.UserID != response.UserID && share.PayerID != response.UserID {
+			continue
+		}
+
+		effect := shareEffect(share, response.UserID)
+		response.Balance += effect
+
+		last := len(response.Entries) - 1
+
+		if last >= 0 && response.Entries[last].TransactionID == share.TransactionID {
+			response.Entries[last].Amount += effect
+			response.Entries[last].Balance = response.Balance
+			continue
+		}
+
+		response.Entries = append(response.Entries, memberLedgerEntry{
+			TransactionID:   share.TransactionID,
+			TransactionDate: share.TransactionDate,
+			TransactionType: share.TransactionType,
+			Description:     share.Description,
+			PaidBy:          share.PayerID,
+			Amount:          effect,
+			Balance:         response.Balance,
+		})
+	}
+
+	serializedLedger, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedLedger)
+}
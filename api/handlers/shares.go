@@ -0,0 +1,638 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
+	"cashpal/settle"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+type shareParticipant struct {
+	UserID int32        `json:"user_id"`
+	Value  money.Amount `json:"value"`
+}
+
+type sharesRequest struct {
+	Method       string             `json:"method"`
+	Participants []shareParticipant `json:"participants"`
+}
+
+type memberBalance struct {
+	UserID  int32        `json:"user_id"`
+	Balance money.Amount `json:"balance"`
+}
+
+type settlementsResponse struct {
+	Currency string           `json:"currency"`
+	Balances []memberBalance  `json:"balances"`
+	Payments []settle.Payment `json:"payments"`
+}
+
+// verifyParticipants rejects a split that lists someone twice or shares with
+// someone who is not a member of the account.
+func verifyParticipants(context context.Context, query *db.Queries, accountID int32, participants []shareParticipant) (int, error) {
+	seen := make(map[int32]bool)
+
+	for _, participant := range participants {
+		if seen[participant.UserID] {
+			return http.StatusBadRequest, fmt.Errorf("user %d is listed more than once", participant.UserID)
+		}
+
+		seen[participant.UserID] = true
+
+		memberParams := db.GetMemberParams{
+			AccountID: accountID,
+			UserID:    participant.UserID,
+		}
+
+		if _, err := query.GetMember(context, memberParams); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("user %d is not a member of this account", participant.UserID)
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// saveShares replaces the shares of a transaction with the given split of
+// amount, which is the amount of the transaction except for a settle-up
+// payment whose amount is only known through its share. New participants are
+// checked with verifyParticipants first.
+func saveShares(context context.Context, query *db.Queries, transaction db.Transaction, amount money.Amount, currency string, request sharesRequest) ([]db.TransactionShare, int, error) {
+	values := make([]money.Amount, len(request.Participants))
+
+	for i, participant := range request.Participants {
+		values[i] = participant.Value
+	}
+
+	amounts, err := settle.Allocate(amount, request.Method, values, currency)
+
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	if err := query.DeleteTransactionShares(context, transaction.ID); err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("transaction shares could not be saved")
+	}
+
+	saved := make([]db.TransactionShare, 0, len(amounts))
+
+	for i, participant := range request.Participants {
+		newShare := db.CreateTransactionShareParams{
+			TransactionID: transaction.ID,
+			UserID:        participant.UserID,
+			Method:        request.Method,
+			Value:         money.NullAmount{Amount: participant.Value, Valid: request.Method != settle.Equal},
+			Amount:        amounts[i],
+		}
+
+		share, err := query.CreateTransactionShare(context, newShare)
+
+		if err != nil {
+			log.Println(err.Error())
+			return nil, http.StatusInternalServerError, errors.New("transaction shares could not be saved")
+		}
+
+		saved = append(saved, share)
+	}
+
+	return saved, http.StatusOK, nil
+}
+
+// reallocateShares splits the new amount of a transaction between its
+// participants again, with the method and values they were shared with.
+// Members who have left the account since keep their share.
+func reallocateShares(context context.Context, query *db.Queries, transaction db.Transaction, currency string) (int, error) {
+	shares, err := query.ListTransactionShares(context, transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	if len(shares) == 0 {
+		return http.StatusOK, nil
+	}
+
+	request := sharesRequest{Method: shares[0].Method}
+
+	for _, share := range shares {
+		request.Participants = append(request.Participants, shareParticipant{UserID: share.UserID, Value: share.Value.Amount})
+	}
+
+	_, statusCode, err := saveShares(context, query, transaction, transaction.Amount, currency, request)
+
+	return statusCode, err
+}
+
+// accountShares returns the shares of every transaction of an account in the
+// account currency, with the sign of their transaction: shared expenses are
+// negative for their participants and shared income positive. A settle-up
+// payment counts like an expense the payee shared with the payer, so
+// receiving it lowers what the payee is owed.
+func accountShares(context context.Context, query *db.Queries, account db.Account) ([]db.ListAccountSharesRow, error) {
+	shares, err := query.ListAccountShares(context, account.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := []string{account.Currency}
+
+	for _, share := range shares {
+		if share.Currency != account.Currency {
+			currencies = append(currencies, share.Currency)
+		}
+	}
+
+	var rateTable *money.RateTable
+
+	if len(currencies) > 1 {
+		rateTable, err = rates.LoadTable(context, query, currencies, today())
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, share := range shares {
+		if share.Currency != account.Currency {
+			converted, err := rateTable.Convert(share.Amount, share.Currency, account.Currency, share.TransactionDate.Time)
+
+			if err != nil {
+				return nil, err
+			}
+
+			shares[i].Amount = converted.RoundTo(account.Currency)
+			shares[i].Currency = account.Currency
+		}
+
+		if share.TransactionType == transactionTypeSettlement {
+			shares[i].Sign = -1
+		}
+	}
+
+	return shares, nil
+}
+
+// shareEffect is how much a share changes what a member is owed. The member
+// who recorded a shared transaction paid or received all of it, so they are
+// credited with exactly what its participants are charged and balances
+// always add up to zero.
+func shareEffect(share db.ListAccountSharesRow, userID int32) money.Amount {
+	var effect money.Amount
+
+	if share.UserID == userID {
+		effect += share.Amount * money.Amount(share.Sign)
+	}
+
+	if share.PayerID == userID {
+		effect -= share.Amount * money.Amount(share.Sign)
+	}
+
+	return effect
+}
+
+// memberBalances works out what every member who took part in a shared
+// transaction is owed.
+func memberBalances(shares []db.ListAccountSharesRow) map[int32]money.Amount {
+	balances := make(map[int32]money.Amount)
+
+	for _, share := range shares {
+		balances[share.UserID] += shareEffect(share, share.UserID)
+
+		if share.PayerID != share.UserID {
+			balances[share.PayerID] += shareEffect(share, share.PayerID)
+		}
+	}
+
+	return balances
+}
+
+// verifySharable rejects sharing transfers, which only move money between
+// accounts, and settle-up payments, whose single share names the payee.
+func verifySharable(context context.Context, query *db.Queries, transaction db.Transaction) (int, error) {
+	if transaction.TransferID.Valid {
+		return http.StatusBadRequest, errors.New("transfers cannot be shared")
+	}
+
+	transactionType, err := query.GetTransactionType(context, transaction.TransactionTypeID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	if transactionType.Name == transactionTypeSettlement {
+		return http.StatusBadRequest, errors.New("settle-up payments cannot be shared")
+	}
+
+	return http.StatusOK, nil
+}
+
+func ListTransactionShares(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := query.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	shares, err := query.ListTransactionShares(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if shares == nil {
+		shares = []db.TransactionShare{}
+	}
+
+	serializedShares, err := json.Marshal(shares)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedShares)
+}
+
+// SetTransactionShares splits a transaction between account members, equally
+// or by exact amounts, percentages or weights.
+func SetTransactionShares(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request sharesRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	statusCode, err = verifySharable(r.Context(), qtx, transaction)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	currentShares, err := qtx.ListTransactionShares(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	currency, err := transactionCurrency(r.Context(), qtx, transaction)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	statusCode, err = verifyParticipants(r.Context(), qtx, transaction.AccountID, request.Participants)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	shares, statusCode, err := saveShares(r.Context(), qtx, transaction, transaction.Amount, currency, request)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	event := audit.Event{
+		AccountID:   transaction.AccountID,
+		UserID:      contextUserID,
+		Type:        audit.TransactionUpdated,
+		Description: fmt.Sprintf("transaction %d shared between %d members", transaction.ID, len(shares)),
+		Before:      map[string]any{"shares": currentShares},
+		After:       map[string]any{"shares": shares},
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction shares could not be saved", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction shares could not be saved", http.StatusInternalServerError)
+		return
+	}
+
+	serializedShares, err := json.Marshal(shares)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedShares)
+}
+
+func DeleteTransactionShares(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	statusCode, err = verifySharable(r.Context(), qtx, transaction)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	currentShares, err := qtx.ListTransactionShares(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if len(currentShares) == 0 {
+		http.Error(w, "this transaction is not shared", http.StatusNotFound)
+		return
+	}
+
+	if err := qtx.DeleteTransactionShares(r.Context(), transaction.ID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction shares could not be removed", http.StatusInternalServerError)
+		return
+	}
+
+	event := audit.Event{
+		AccountID:   transaction.AccountID,
+		UserID:      contextUserID,
+		Type:        audit.TransactionUpdated,
+		Description: fmt.Sprintf("transaction %d no longer shared", transaction.ID),
+		Before:      map[string]any{"shares": currentShares},
+		After:       map[string]any{"shares": []db.TransactionShare{}},
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction shares could not be removed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction shares could not be removed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("transaction shares removed"))
+}
+
+// GetSettlements returns what every member is owed through shared
+// transactions and the fewest repayments that settle all of it.
+func GetSettlements(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	account, err := query.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	shares, err := accountShares(r.Context(), query, account)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	balances := memberBalances(shares)
+
+	response := settlementsResponse{
+		Currency: account.Currency,
+		Balances: make([]memberBalance, 0, len(balances)),
+		Payments: settle.Simplify(balances),
+	}
+
+	for userID, balance := range balances {
+		response.Balances = append(response.Balances, memberBalance{UserID: userID, Balance: balance})
+	}
+
+	sort.Slice(response.Balances, func(i, j int) bool { return response.Balances[i].UserID < response.Balances[j].UserID })
+
+	serializedSettlements, err := json.Marshal(response)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedSettlements)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"reflect"
+	"testing"
+)
+
+func TestMemberBalances(t *testing.T) {
+	shares := []db.ListAccountSharesRow{
+		// User 1 paid 90 for dinner, shared by users 1, 2 and 3.
+		{TransactionID: 1, UserID: 1, PayerID: 1, Sign: -1, Amount: money.MustParse("30")},
+		{TransactionID: 1, UserID: 2, PayerID: 1, Sign: -1, Amount: money.MustParse("30")},
+		{TransactionID: 1, UserID: 3, PayerID: 1, Sign: -1, Amount: money.MustParse("30")},
+		// User 2 received a 20 refund that belongs to user 3.
+		{TransactionID: 2, UserID: 3, PayerID: 2, Sign: 1, Amount: money.MustParse("20")},
+	}
+
+	want := map[int32]money.Amount{
+		1: money.MustParse("60"),
+		2: money.MustParse("-50"),
+		3: money.MustParse("-10"),
+	}
+
+	got := memberBalances(shares)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("memberBalances = %v, want %v", got, want)
+	}
+
+	var total money.Amount
+
+	for _, balance := range got {
+		total += balance
+	}
+
+	if total != 0 {
+		t.Errorf("balances add up to %s, want 0", total)
+	}
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/middleware"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const maxTagLength = 64
+
+type transactionTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// normalizeTag trims and lower-cases a tag so "Vacation-2026" and
+// "vacation-2026 " end up as the same tag.
+func normalizeTag(name string) (string, error) {
+	tag := strings.ToLower(strings.TrimSpace(name))
+
+	if tag == "" {
+		return "", errors.New("tags cannot be empty")
+	}
+
+	if len(tag) > maxTagLength {
+		return "", errors.New("tags cannot be longer than 64 characters")
+	}
+
+	if strings.Contains(tag, ",") {
+		return "", errors.New("tags cannot contain commas")
+	}
+
+	return tag, nil
+}
+
+// parseTagFilter reads the tag filter of a transaction listing. Tags can be
+// given as repeated ?tag= parameters or comma separated, and ?tag_match=all
+// requires every tag instead of any of them.
+func parseTagFilter(r *http.Request) ([]string, bool, error) {
+	var tags []string
+
+	for _, value := range r.URL.Query()["tag"] {
+		for _, name := range strings.Split(value, ",") {
+			tag, err := normalizeTag(name)
+
+			if err != nil {
+				return nil, false, err
+			}
+
+			tags = append(tags, tag)
+		}
+	}
+
+	switch r.URL.Query().Get("tag_match") {
+	case "", "any":
+		return tags, false, nil
+	case "all":
+		return tags, true, nil
+	default:
+		return nil, false, errors.New("tag_match must be any or all")
+	}
+}
+
+func ListTags(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	listTagsParams := db.ListTagsByAccountParams{
+		AccountID: int32(accountID),
+		Prefix:    strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q"))),
+	}
+
+	tags, err := query.ListTagsByAccount(r.Context(), listTagsParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if tags == nil {
+		tags = []db.ListTagsByAccountRow{}
+	}
+
+	serializedTags, err := json.Marshal(tags)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTags)
+}
+
+func ListTransactionTags(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	if _, err := query.GetTransactionWithCheck(r.Context(), getTransactionParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	tags, err := query.ListTransactionTags(r.Context(), int32(transactionID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if tags == nil {
+		tags = []db.Tag{}
+	}
+
+	serializedTags, err := json.Marshal(tags)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTags)
+}
+
+func AddTransactionTags(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	var request transactionTagsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(request.Tags) == 0 {
+		http.Error(w, "at least one tag is required", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	if _, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	for _, name := range request.Tags {
+		tagName, err := normalizeTag(name)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		upsertTagParams := db.UpsertTagParams{
+			AccountID: int32(accountID),
+			Name:      tagName,
+		}
+
+		tag, err := qtx.UpsertTag(r.Context(), upsertTagParams)
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "tagging failed", http.StatusInternalServerError)
+			return
+		}
+
+		addTagParams := db.AddTransactionTagParams{
+			TransactionID: int32(transactionID),
+			TagID:         tag.ID,
+		}
+
+		if err := qtx.AddTransactionTag(r.Context(), addTagParams); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "tagging failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	tags, err := qtx.ListTransactionTags(r.Context(), int32(transactionID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "tagging failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedTags, err := json.Marshal(tags)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTags)
+}
+
+func RemoveTransactionTag(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	tagName, err := normalizeTag(r.PathValue("tag"))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	if _, err := query.GetTransactionWithCheck(r.Context(), getTransactionParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	statusCode, err := verifyWritable(r.Context(), query, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	removeTagParams := db.RemoveTransactionTagParams{
+		TransactionID: int32(transactionID),
+		Name:          tagName,
+	}
+
+	if err := query.RemoveTransactionTag(r.Context(), removeTagParams); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "tag removal failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("tag removed"))
+}
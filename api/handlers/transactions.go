@@ -1,17 +1,289 @@
 package handlers
 
 import (
+	"cashpal/audit"
+	"cashpal/budget"
+	"cashpal/config"
 	"cashpal/database"
 	db "cashpal/database/generated"
+	"cashpal/ledger"
 	"cashpal/middleware"
+	"cashpal/money"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	transactionTypeIncome      = "income"
+	transactionTypeExpense     = "expense"
+	transactionTypeTransferIn  = "transfer_in"
+	transactionTypeTransferOut = "transfer_out"
+	transactionTypeSettlement  = "settlement"
+)
+
+const (
+	defaultTransactionPageSize = 50
+	maxTransactionPageSize     = 200
 )
 
+type transactionListResponse struct {
+	Transactions []db.Transaction `json:"transactions"`
+	NextCursor   *string          `json:"next_cursor"`
+}
+
+// transactionCursor marks where a page of transactions ended. It carries the
+// sort it was produced with so it cannot be replayed against another order.
+type transactionCursor struct {
+	SortBy     string `json:"sort_by"`
+	Descending bool   `json:"descending"`
+	Value      string `json:"value"`
+	ID         int32  `json:"id"`
+}
+
+type transactionRequest struct {
+	db.CreateTransactionParams
+	Amount     money.Amount   `json:"amount"`
+	CategoryID pgtype.Int4    `json:"category_id"`
+	Splits     []splitRequest `json:"splits"`
+}
+
+type transactionUpdateRequest struct {
+	db.UpdateTransactionParams
+	Amount        money.NullAmount `json:"amount"`
+	ClearCategory bool             `json:"clear_category"`
+	CategoryID    pgtype.Int4      `json:"category_id"`
+	Splits        []splitRequest   `json:"splits"`
+}
+
+// splitRequest is the part of a transaction that goes to one category.
+type splitRequest struct {
+	CategoryID pgtype.Int4  `json:"category_id"`
+	Amount     money.Amount `json:"amount"`
+	Memo       string       `json:"memo"`
+}
+
+type transactionResponse struct {
+	db.Transaction
+	Splits []db.TransactionSplit `json:"splits"`
+}
+
+// createTransactionResponse warns about the transactions the new one looks
+// like a duplicate of.
+type createTransactionResponse struct {
+	transactionResponse
+	Duplicates []db.Transaction `json:"duplicates"`
+}
+
+func newTransactionResponse(transaction db.Transaction, splits []db.TransactionSplit) transactionResponse {
+	if splits == nil {
+		splits = []db.TransactionSplit{}
+	}
+
+	return transactionResponse{Transaction: transaction, Splits: splits}
+}
+
+func encodeTransactionCursor(params db.ListTransactionByAccountParams, last db.Transaction) (string, error) {
+	cursor := transactionCursor{
+		SortBy:     params.SortBy,
+		Descending: params.Descending,
+		ID:         last.ID,
+	}
+
+	switch params.SortBy {
+	case "amount":
+		cursor.Value = last.Amount.String()
+	case "created_at":
+		cursor.Value = last.CreatedAt.Time.Format(time.RFC3339Nano)
+	default:
+		cursor.Value = last.TransactionDate.Time.Format(time.DateOnly)
+	}
+
+	serializedCursor, err := json.Marshal(cursor)
+
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(serializedCursor), nil
+}
+
+func decodeTransactionCursor(value string, params *db.ListTransactionByAccountParams) error {
+	serializedCursor, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return errors.New("cursor is invalid or malformed")
+	}
+
+	var cursor transactionCursor
+
+	if err := json.Unmarshal(serializedCursor, &cursor); err != nil {
+		return errors.New("cursor is invalid or malformed")
+	}
+
+	if cursor.SortBy != params.SortBy || cursor.Descending != params.Descending {
+		return errors.New("cursor was created for a different sort order")
+	}
+
+	switch cursor.SortBy {
+	case "amount":
+		amount, err := money.Parse(cursor.Value)
+
+		if err != nil {
+			return errors.New("cursor is invalid or malformed")
+		}
+
+		params.CursorAmount = money.NullAmount{Amount: amount, Valid: true}
+	case "created_at":
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+
+		if err != nil {
+			return errors.New("cursor is invalid or malformed")
+		}
+
+		params.CursorCreatedAt = pgtype.Timestamp{Time: createdAt, Valid: true}
+	default:
+		date, err := time.Parse(time.DateOnly, cursor.Value)
+
+		if err != nil {
+			return errors.New("cursor is invalid or malformed")
+		}
+
+		params.CursorDate = pgtype.Date{Time: date, Valid: true}
+	}
+
+	params.CursorID = pgtype.Int4{Int32: cursor.ID, Valid: true}
+
+	return nil
+}
+
+func parseDateFilter(value string) (pgtype.Date, error) {
+	if value == "" {
+		return pgtype.Date{}, nil
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+
+	if err != nil {
+		return pgtype.Date{}, err
+	}
+
+	return pgtype.Date{Time: date, Valid: true}, nil
+}
+
+func parseAmountFilter(value string) (money.NullAmount, error) {
+	if value == "" {
+		return money.NullAmount{}, nil
+	}
+
+	amount, err := money.Parse(value)
+
+	if err != nil {
+		return money.NullAmount{}, err
+	}
+
+	return money.NullAmount{Amount: amount, Valid: true}, nil
+}
+
+func parseIDFilter(value string) (pgtype.Int4, error) {
+	if value == "" {
+		return pgtype.Int4{}, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 32)
+
+	if err != nil {
+		return pgtype.Int4{}, err
+	}
+
+	return pgtype.Int4{Int32: int32(id), Valid: true}, nil
+}
+
+// parseTransactionFilters reads the filters, sort order and page of a
+// transaction listing from the query string. A category_id filter matches
+// the category and every category below it, however deep.
+func parseTransactionFilters(r *http.Request, params *db.ListTransactionByAccountParams) error {
+	values := r.URL.Query()
+	var err error
+
+	if params.FromDate, err = parseDateFilter(values.Get("from_date")); err != nil {
+		return errors.New("from_date must be a date like 2006-01-02")
+	}
+
+	if params.ToDate, err = parseDateFilter(values.Get("to_date")); err != nil {
+		return errors.New("to_date must be a date like 2006-01-02")
+	}
+
+	if params.MinAmount, err = parseAmountFilter(values.Get("min_amount")); err != nil {
+		return errors.New("min_amount must be a decimal amount")
+	}
+
+	if params.MaxAmount, err = parseAmountFilter(values.Get("max_amount")); err != nil {
+		return errors.New("max_amount must be a decimal amount")
+	}
+
+	if params.TransactionTypeID, err = parseIDFilter(values.Get("transaction_type_id")); err != nil {
+		return errors.New("transaction_type_id is invalid or malformed")
+	}
+
+	if params.CategoryID, err = parseIDFilter(values.Get("category_id")); err != nil {
+		return errors.New("category_id is invalid or malformed")
+	}
+
+	if params.CreatorID, err = parseIDFilter(values.Get("user_id")); err != nil {
+		return errors.New("user_id is invalid or malformed")
+	}
+
+	if description := values.Get("description"); description != "" {
+		params.Search = pgtype.Text{String: description, Valid: true}
+	}
+
+	params.SortBy = values.Get("sort")
+
+	switch params.SortBy {
+	case "":
+		params.SortBy = "date"
+	case "date", "amount", "created_at":
+	default:
+		return errors.New("sort must be date, amount or created_at")
+	}
+
+	switch values.Get("order") {
+	case "", "desc":
+		params.Descending = true
+	case "asc":
+		params.Descending = false
+	default:
+		return errors.New("order must be asc or desc")
+	}
+
+	params.PageSize = defaultTransactionPageSize
+
+	if limit := values.Get("limit"); limit != "" {
+		pageSize, err := strconv.ParseInt(limit, 10, 32)
+
+		if err != nil || pageSize < 1 || pageSize > maxTransactionPageSize {
+			return fmt.Errorf("limit must be between 1 and %d", maxTransactionPageSize)
+		}
+
+		params.PageSize = int32(pageSize)
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		return decodeTransactionCursor(cursor, params)
+	}
+
+	return nil
+}
+
 func ListTransactions(w http.ResponseWriter, r *http.Request) {
 	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
 
@@ -38,11 +310,30 @@ func ListTransactions(w http.ResponseWriter, r *http.Request) {
 
 	defer connClose()
 
+	tags, matchAll, err := parseTagFilter(r)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	listTransactionParams := db.ListTransactionByAccountParams{
 		AccountID: int32(accountID),
 		UserID:    contextUserID,
+		Tags:      tags,
+		MatchAll:  matchAll,
 	}
 
+	if err := parseTransactionFilters(r, &listTransactionParams); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pageSize := listTransactionParams.PageSize
+
+	// One extra row tells whether there is another page after this one.
+	listTransactionParams.PageSize++
+
 	transactions, err := query.ListTransactionByAccount(r.Context(), listTransactionParams)
 
 	if err != nil {
@@ -51,13 +342,32 @@ func ListTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	print(transactions)
+	response := transactionListResponse{Transactions: transactions}
 
-	serializedTransactions, err := json.Marshal(transactions)
+	if len(transactions) > int(pageSize) {
+		response.Transactions = transactions[:pageSize]
+
+		nextCursor, err := encodeTransactionCursor(listTransactionParams, response.Transactions[pageSize-1])
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "data serialization failed", http.StatusInternalServerError)
+			return
+		}
+
+		response.NextCursor = &nextCursor
+	}
+
+	if response.Transactions == nil {
+		response.Transactions = []db.Transaction{}
+	}
+
+	serializedTransactions, err := json.Marshal(response)
 
 	if err != nil {
 		log.Println(err.Error())
 		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -112,7 +422,15 @@ func GetTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serializedTransactions, err := json.Marshal(transaction)
+	splits, err := query.ListTransactionSplits(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	serializedTransactions, err := json.Marshal(newTransactionResponse(transaction, splits))
 
 	if err != nil {
 		log.Println(err.Error())
@@ -139,6 +457,75 @@ func verifyMembership(context context.Context, query *db.Queries, userID int32,
 	return http.StatusOK, nil
 }
 
+// counterAccount returns the kind of ledger account the other side of a
+// transaction of the given type is booked on: income or expense, or equity
+// for the leg of a transfer whose other account was deleted.
+func counterAccount(transactionType db.TransactionType) string {
+	switch {
+	case transactionType.Name == transactionTypeTransferIn || transactionType.Name == transactionTypeTransferOut:
+		return ledger.Equity
+	case transactionType.Sign < 0:
+		return ledger.Expense
+	default:
+		return ledger.Income
+	}
+}
+
+// transactionCurrency returns the currency a transaction is denominated in,
+// which is its own currency when set and the account currency otherwise.
+func transactionCurrency(context context.Context, query *db.Queries, transaction db.Transaction) (string, error) {
+	if transaction.Currency.Valid {
+		return transaction.Currency.String, nil
+	}
+
+	account, err := query.GetAccount(context, transaction.AccountID)
+
+	if err != nil {
+		return "", err
+	}
+
+	return account.Currency, nil
+}
+
+// transactionLines returns the lines a transaction is booked with: one per
+// split, which must add up to its amount, or a single line for the whole
+// amount when it is not split. A split transaction takes its categories from
+// its splits.
+func transactionLines(context context.Context, query *db.Queries, accountID int32, currency string, amount money.Amount, categoryID pgtype.Int4, splits []splitRequest) ([]ledger.Line, int, error) {
+	if len(splits) == 0 {
+		return []ledger.Line{{CategoryID: categoryID, Amount: amount}}, http.StatusOK, nil
+	}
+
+	if categoryID.Valid {
+		return nil, http.StatusBadRequest, errors.New("a split transaction takes its categories from its splits")
+	}
+
+	lines := make([]ledger.Line, 0, len(splits))
+
+	for _, split := range splits {
+		if err := split.Amount.Validate(currency); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+
+		statusCode, err := verifyCategory(context, query, accountID, split.CategoryID)
+
+		if err != nil {
+			return nil, statusCode, err
+		}
+
+		lines = append(lines, ledger.Line{CategoryID: split.CategoryID, Amount: split.Amount, Memo: split.Memo})
+	}
+
+	if err := ledger.ValidateSplits(amount, lines); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	return lines, http.StatusOK, nil
+}
+
+// CreateTransactions records a transaction and returns, as a warning, the
+// transactions of the account it looks like a duplicate of. With strict=true
+// a transaction that looks like a duplicate is refused instead.
 func CreateTransactions(w http.ResponseWriter, r *http.Request) {
 	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
 
@@ -155,7 +542,7 @@ func CreateTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query, connClose, err := database.GetNewConnection(r.Context())
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
 
 	if err != nil {
 		log.Println(err.Error())
@@ -164,53 +551,191 @@ func CreateTransactions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
 
-	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+	statusCode, err := verifyMembership(r.Context(), qtx, contextUserID, int32(accountID))
 
 	if err != nil {
 		http.Error(w, err.Error(), statusCode)
 		return
 	}
 
-	var newTransaction db.CreateTransactionParams
+	statusCode, err = verifyWritable(r.Context(), qtx, int32(accountID))
 
-	if err := json.NewDecoder(r.Body).Decode(&newTransaction); err != nil {
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	strict, err := boolParameter(r, "strict")
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var request transactionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		log.Println(err.Error())
 		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
 		return
 	}
 
+	newTransaction := request.CreateTransactionParams
+
 	newTransaction.AccountID = int32(accountID)
 	newTransaction.UserID = contextUserID
+	newTransaction.TransferID = pgtype.Int4{}
 
-	transaction, err := query.CreateTransaction(r.Context(), newTransaction)
+	transactionType, err := qtx.GetTransactionType(r.Context(), newTransaction.TransactionTypeID)
+
+	if err != nil {
+		http.Error(w, "transaction_type_id is not a known transaction type", http.StatusBadRequest)
+		return
+	}
+
+	// Both legs of a transfer and the share of a settle-up payment are only
+	// written by their own endpoints.
+	if transactionType.Name == transactionTypeTransferIn || transactionType.Name == transactionTypeTransferOut {
+		http.Error(w, "transfers are created with POST /transfers", http.StatusBadRequest)
+		return
+	}
+
+	if transactionType.Name == transactionTypeSettlement {
+		http.Error(w, "settle-up payments are created with POST /accounts/{accountID}/settlements", http.StatusBadRequest)
+		return
+	}
+
+	account, err := qtx.GetAccount(r.Context(), int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this account does not exist", http.StatusNotFound)
+		return
+	}
+
+	currency := account.Currency
+
+	if newTransaction.Currency.Valid {
+		currency, err = money.NormalizeCurrency(newTransaction.Currency.String)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		newTransaction.Currency.String = currency
+	}
+
+	if err := request.Amount.Validate(currency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err = verifyCategory(r.Context(), qtx, int32(accountID), request.CategoryID)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	lines, statusCode, err := transactionLines(r.Context(), qtx, int32(accountID), currency, request.Amount, request.CategoryID, request.Splits)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	duplicates, err := findDuplicates(r.Context(), qtx, account, newTransaction, request.Amount, config.GetDuplicateWindow(), nil)
 
 	if err != nil {
 		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if strict && len(duplicates) > 0 {
+		http.Error(w, duplicateMessage(duplicates), http.StatusConflict)
+		return
+	}
+
+	envelopes, err := budget.Lock(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	leg := ledger.Leg{
+		Transaction: newTransaction,
+		Postings:    ledger.IncomeExpense(0, int32(accountID), currency, transactionType.Sign, lines),
+	}
+
+	transactions, err := ledger.Record(r.Context(), qtx, []ledger.Leg{leg})
+
+	if err != nil {
+		log.Println(err.Error())
+
+		if isUniqueViolation(err) {
+			http.Error(w, "a transaction with this external id already exists here", http.StatusConflict)
+			return
+		}
+
 		http.Error(w, "transaction creation failed", http.StatusInternalServerError)
 		return
 	}
 
-	serializedTransaction, err := json.Marshal(transaction)
+	splits, err := qtx.ListTransactionSplits(r.Context(), transactions[0].ID)
 
 	if err != nil {
 		log.Println(err.Error())
-		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		http.Error(w, "transaction creation failed", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write(serializedTransaction)
-}
+	statusCode, err = verifyEnvelopes(r.Context(), qtx, envelopes)
 
-func compareTransactionData(currentTransaction *db.Transaction, updatedTransaction *db.UpdateTransactionParams) {
-	if updatedTransaction.Amount == 0 {
-		updatedTransaction.Amount = currentTransaction.Amount
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	response := newTransactionResponse(transactions[0], splits)
+
+	event := audit.Event{
+		AccountID:   int32(accountID),
+		UserID:      contextUserID,
+		Type:        audit.TransactionCreated,
+		Description: fmt.Sprintf("transaction %d created", response.ID),
+		After:       response,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction creation failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction creation failed", http.StatusInternalServerError)
+		return
 	}
 
-	if updatedTransaction.Description == "" {
-		updatedTransaction.Description = currentTransaction.Description
+	serializedTransaction, err := json.Marshal(createTransactionResponse{transactionResponse: response, Duplicates: duplicates})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
 	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTransaction)
 }
 
 func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
@@ -237,7 +762,7 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query, connClose, err := database.GetNewConnection(r.Context())
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
 
 	if err != nil {
 		log.Println(err.Error())
@@ -246,17 +771,33 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
 
-	statusCode, err := verifyMembership(r.Context(), query, contextUserID, int32(accountID))
+	statusCode, err := verifyMembership(r.Context(), qtx, contextUserID, int32(accountID))
 
 	if err != nil {
 		http.Error(w, err.Error(), statusCode)
 		return
 	}
 
-	var updatedData db.UpdateTransactionParams
+	statusCode, err = verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
 
-	json.NewDecoder(r.Body).Decode(&updatedData)
+	var request transactionUpdateRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	updatedData := request.UpdateTransactionParams
 
 	getTransactionParams := db.GetTransactionWithCheckParams{
 		AccountID: int32(accountID),
@@ -264,7 +805,7 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		UserID:    contextUserID,
 	}
 
-	transaction, err := query.GetTransactionWithCheck(r.Context(), getTransactionParams)
+	transaction, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -272,11 +813,125 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	compareTransactionData(&transaction, &updatedData)
+	currentSplits, err := qtx.ListTransactionSplits(r.Context(), transaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	currency, err := transactionCurrency(r.Context(), qtx, transaction)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	transactionType, err := qtx.GetTransactionType(r.Context(), transaction.TransactionTypeID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	amount := transaction.Amount
+
+	if request.Amount.Valid {
+		if err := request.Amount.Amount.Validate(currency); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		amount = request.Amount.Amount
+	}
+
+	// A missing or null category_id keeps the category, clear_category
+	// removes it.
+	if request.ClearCategory && request.CategoryID.Valid {
+		http.Error(w, "category_id cannot be set together with clear_category", http.StatusBadRequest)
+		return
+	}
+
+	statusCode, err = verifyCategory(r.Context(), qtx, int32(accountID), request.CategoryID)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	categoryID := transaction.CategoryID
+
+	if request.ClearCategory {
+		categoryID = pgtype.Int4{}
+	} else if request.CategoryID.Valid {
+		categoryID = request.CategoryID
+	}
+
+	// A missing "splits" keeps the current ones, an empty list removes them.
+	newSplits := request.Splits
+
+	if newSplits == nil {
+		for _, split := range currentSplits {
+			newSplits = append(newSplits, splitRequest{CategoryID: split.CategoryID, Amount: split.Amount, Memo: split.Memo})
+		}
+	}
+
+	if transaction.TransferID.Valid && len(newSplits) > 0 {
+		http.Error(w, "transfers cannot be split", http.StatusBadRequest)
+		return
+	}
+
+	lines, statusCode, err := transactionLines(r.Context(), qtx, int32(accountID), currency, amount, categoryID, newSplits)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	envelopes, err := budget.Lock(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
 
 	updatedData.ID = int32(transactionID)
 
-	updatedTransaction, err := query.UpdateTransaction(r.Context(), updatedData)
+	if err := qtx.UpdateTransaction(r.Context(), updatedData); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction update failed", http.StatusInternalServerError)
+		return
+	}
+
+	if transaction.TransferID.Valid {
+		side := ledger.Side{
+			TransactionID: transaction.ID,
+			AccountID:     transaction.AccountID,
+			Currency:      currency,
+			Amount:        amount,
+			CategoryID:    categoryID,
+		}
+
+		if err := syncTransferLegs(r.Context(), qtx, transaction, side, updatedData.Description); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transfer update failed", http.StatusUnprocessableEntity)
+			return
+		}
+	} else {
+		postings := ledger.Book(transaction.ID, transaction.AccountID, currency, transactionType.Sign, counterAccount(transactionType), lines)
+
+		if err := ledger.Rebook(r.Context(), qtx, transaction.JournalEntryID, postings); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction update failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	updatedTransaction, err := qtx.GetTransaction(r.Context(), transaction.ID)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -284,7 +939,54 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serializedUpdatedTransaction, err := json.Marshal(updatedTransaction)
+	if request.Amount.Valid {
+		statusCode, err = reallocateShares(r.Context(), qtx, updatedTransaction, currency)
+
+		if err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+	}
+
+	statusCode, err = verifyEnvelopes(r.Context(), qtx, envelopes)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	splits, err := qtx.ListTransactionSplits(r.Context(), updatedTransaction.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	response := newTransactionResponse(updatedTransaction, splits)
+
+	event := audit.Event{
+		AccountID:   int32(accountID),
+		UserID:      contextUserID,
+		Type:        audit.TransactionUpdated,
+		Description: fmt.Sprintf("transaction %d updated", response.ID),
+		Before:      newTransactionResponse(transaction, currentSplits),
+		After:       response,
+	}
+
+	if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction update failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction update failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedUpdatedTransaction, err := json.Marshal(response)
 
 	if err != nil {
 		log.Println(err.Error())
@@ -296,7 +998,175 @@ func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Write(serializedUpdatedTransaction)
 }
 
+// DeleteTransaction moves a transaction to the trash and returns it as it is
+// there. Transfer legs share a journal entry, so deleting one leg trashes the
+// whole transfer.
 func DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	getTransactionParams := db.GetTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := qtx.GetTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "access denied", http.StatusUnauthorized)
+		return
+	}
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	entryTransactions, err := qtx.ListTransactionByJournalEntry(r.Context(), transaction.JournalEntryID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	accountIDs := make([]int32, 0, len(entryTransactions))
+
+	// The other leg of a transfer is trashed along with this one, so its
+	// account has to be open to the user as well.
+	for _, entryTransaction := range entryTransactions {
+		legAccountID := entryTransaction.Transaction.AccountID
+
+		if legAccountID != int32(accountID) {
+			statusCode, err = verifyMembership(r.Context(), qtx, contextUserID, legAccountID)
+
+			if err != nil {
+				http.Error(w, err.Error(), statusCode)
+				return
+			}
+
+			statusCode, err = verifyWritable(r.Context(), qtx, legAccountID)
+
+			if err != nil {
+				http.Error(w, err.Error(), statusCode)
+				return
+			}
+		}
+
+		accountIDs = append(accountIDs, legAccountID)
+	}
+
+	envelopes, err := budget.Lock(r.Context(), qtx, accountIDs...)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := ledger.Trash(r.Context(), qtx, transaction.JournalEntryID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	statusCode, err = verifyEnvelopes(r.Context(), qtx, envelopes)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	// The other leg of a transfer goes to the trash as well, so its account
+	// gets an event of its own.
+	for _, entryTransaction := range entryTransactions {
+		trashed := entryTransaction.Transaction
+		description := fmt.Sprintf("transaction %d moved to trash", trashed.ID)
+
+		if trashed.ID != transaction.ID {
+			description = fmt.Sprintf("transaction %d moved to trash with transaction %d of transfer %d", trashed.ID, transaction.ID, transaction.TransferID.Int32)
+		}
+
+		event := audit.Event{
+			AccountID:   trashed.AccountID,
+			UserID:      contextUserID,
+			Type:        audit.TransactionDeleted,
+			Description: description,
+			Before:      trashed,
+		}
+
+		if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction deletion failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	trashedParams := db.GetTrashedTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	trashedTransaction, err := qtx.GetTrashedTransactionWithCheck(r.Context(), trashedParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction deletion failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedTransaction, err := json.Marshal(trashedTransaction)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("the transactions cannot be deleted yet"))
+	w.Write(serializedTransaction)
 }
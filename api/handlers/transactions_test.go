@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"cashpal/money"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestTransactionCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	last := db.Transaction{
+		ID:              42,
+		Amount:          money.MustParse("-12.5"),
+		CreatedAt:       pgtype.Timestamp{Time: created, Valid: true},
+		TransactionDate: pgtype.Date{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+	}
+
+	for _, sortBy := range []string{"date", "amount", "created_at"} {
+		t.Run(sortBy, func(t *testing.T) {
+			params := db.ListTransactionByAccountParams{SortBy: sortBy, Descending: true}
+
+			cursor, err := encodeTransactionCursor(params, last)
+
+			if err != nil {
+				t.Fatalf("encodeTransactionCursor = %v", err)
+			}
+
+			if err := decodeTransactionCursor(cursor, &params); err != nil {
+				t.Fatalf("decodeTransactionCursor = %v", err)
+			}
+
+			if params.CursorID != (pgtype.Int4{Int32: 42, Valid: true}) {
+				t.Errorf("CursorID = %v, want 42", params.CursorID)
+			}
+
+			switch sortBy {
+			case "amount":
+				if params.CursorAmount != (money.NullAmount{Amount: last.Amount, Valid: true}) {
+					t.Errorf("CursorAmount = %v, want %s", params.CursorAmount, last.Amount)
+				}
+			case "created_at":
+				if !params.CursorCreatedAt.Time.Equal(created) {
+					t.Errorf("CursorCreatedAt = %v, want %v", params.CursorCreatedAt.Time, created)
+				}
+			default:
+				if params.CursorDate != last.TransactionDate {
+					t.Errorf("CursorDate = %v, want %v", params.CursorDate, last.TransactionDate)
+				}
+			}
+		})
+	}
+
+	cursor, _ := encodeTransactionCursor(db.ListTransactionByAccountParams{SortBy: "amount"}, last)
+
+	if err := decodeTransactionCursor(cursor, &db.ListTransactionByAccountParams{SortBy: "date"}); err == nil {
+		t.Error("a cursor was accepted for a different sort order")
+	}
+
+	if err := decodeTransactionCursor("not a cursor", &db.ListTransactionByAccountParams{}); err == nil {
+		t.Error("a malformed cursor was accepted")
+	}
+}
+
+func TestParseTransactionFilters(t *testing.T) {
+	var params db.ListTransactionByAccountParams
+
+	request := httptest.NewRequest("GET", "/transactions?from_date=2024-01-01&min_amount=10.5&category_id=3&description=rent&sort=amount&order=asc&limit=20", nil)
+
+	if err := parseTransactionFilters(request, &params); err != nil {
+		t.Fatalf("parseTransactionFilters = %v", err)
+	}
+
+	if !params.FromDate.Valid || params.ToDate.Valid {
+		t.Errorf("FromDate = %v, ToDate = %v, want only a from date", params.FromDate, params.ToDate)
+	}
+
+	if params.MinAmount != (money.NullAmount{Amount: money.MustParse("10.5"), Valid: true}) {
+		t.Errorf("MinAmount = %v, want 10.5", params.MinAmount)
+	}
+
+	if params.CategoryID != (pgtype.Int4{Int32: 3, Valid: true}) || params.Search.String != "rent" {
+		t.Errorf("CategoryID = %v, Search = %v", params.CategoryID, params.Search)
+	}
+
+	if params.SortBy != "amount" || params.Descending || params.PageSize != 20 {
+		t.Errorf("SortBy = %q, Descending = %v, PageSize = %d", params.SortBy, params.Descending, params.PageSize)
+	}
+
+	for _, query := range []string{
+		"from_date=01/02/2024",
+		"max_amount=ten",
+		"category_id=food",
+		"sort=name",
+		"order=up",
+		"limit=0",
+	} {
+		request := httptest.NewRequest("GET", "/transactions?"+query, nil)
+
+		if err := parseTransactionFilters(request, &db.ListTransactionByAccountParams{}); err == nil {
+			t.Errorf("parseTransactionFilters accepted %s", query)
+		}
+	}
+}
+
+func TestCounterAccount(t *testing.T) {
+	tests := []struct {
+		transactionType db.TransactionType
+		want            string
+	}{
+		{db.TransactionType{Name: "Expense", Sign: -1}, ledger.Expense},
+		{db.TransactionType{Name: "Income", Sign: 1}, ledger.Income},
+		{db.TransactionType{Name: transactionTypeTransferOut, Sign: -1}, ledger.Equity},
+		{db.TransactionType{Name: transactionTypeTransferIn, Sign: 1}, ledger.Equity},
+	}
+
+	for _, test := range tests {
+		if got := counterAccount(test.transactionType); got != test.want {
+			t.Errorf("counterAccount(%s) = %s, want %s", test.transactionType.Name, got, test.want)
+		}
+	}
+}
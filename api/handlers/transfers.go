@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/budget"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"cashpal/middleware"
+	"cashpal/money"
+	"cashpal/rates"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type transferRequest struct {
+	FromAccountID   int32            `json:"from_account_id"`
+	ToAccountID     int32            `json:"to_account_id"`
+	TransactionDate pgtype.Date      `json:"transaction_date"`
+	Amount          money.Amount     `json:"amount"`
+	ToAmount        money.NullAmount `json:"to_amount"`
+	Description     string           `json:"description"`
+}
+
+type transferResponse struct {
+	db.Transfer
+	Transactions []db.Transaction `json:"transactions"`
+}
+
+// transferAmounts returns the amount debited from the source account and the
+// amount credited to the destination account. Between accounts in different
+// currencies the credit is either given explicitly or converted with the rate
+// valid on the transfer date.
+func transferAmounts(context context.Context, query *db.Queries, from db.Account, to db.Account, request transferRequest) (money.Amount, money.Amount, int, error) {
+	if request.Amount <= 0 {
+		return 0, 0, http.StatusBadRequest, errors.New("amount must be greater than zero")
+	}
+
+	if err := request.Amount.Validate(from.Currency); err != nil {
+		return 0, 0, http.StatusBadRequest, err
+	}
+
+	if from.Currency == to.Currency {
+		if request.ToAmount.Valid && request.ToAmount.Amount != request.Amount {
+			return 0, 0, http.StatusBadRequest, errors.New("to_amount must match amount between accounts with the same currency")
+		}
+
+		return request.Amount, request.Amount, http.StatusOK, nil
+	}
+
+	if request.ToAmount.Valid {
+		if request.ToAmount.Amount <= 0 {
+			return 0, 0, http.StatusBadRequest, errors.New("to_amount must be greater than zero")
+		}
+
+		if err := request.ToAmount.Amount.Validate(to.Currency); err != nil {
+			return 0, 0, http.StatusBadRequest, err
+		}
+
+		return request.Amount, request.ToAmount.Amount, http.StatusOK, nil
+	}
+
+	rateTable, err := rates.LoadTable(context, query, []string{from.Currency, to.Currency}, request.TransactionDate)
+
+	if err != nil {
+		log.Println(err.Error())
+		return 0, 0, http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	toAmount, err := rateTable.Convert(request.Amount, from.Currency, to.Currency, request.TransactionDate.Time)
+
+	if err != nil {
+		return 0, 0, http.StatusUnprocessableEntity, err
+	}
+
+	return request.Amount, toAmount.RoundTo(to.Currency), http.StatusOK, nil
+}
+
+func saveTransfer(context context.Context, request transferRequest) (*transferResponse, int, error) {
+	userID, ok := context.Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		return nil, http.StatusInternalServerError, errors.New("user id cannot be loaded from the session")
+	}
+
+	if request.FromAccountID == request.ToAccountID {
+		return nil, http.StatusBadRequest, errors.New("a transfer needs two different accounts")
+	}
+
+	if !request.TransactionDate.Valid {
+		return nil, http.StatusBadRequest, errors.New("transaction_date was not provided")
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(context)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("service unavailable")
+	}
+
+	defer connClose()
+	defer tx.Rollback(context)
+
+	qtx := query.WithTx(tx)
+
+	for _, accountID := range []int32{request.FromAccountID, request.ToAccountID} {
+		if statusCode, err := verifyMembership(context, qtx, userID, accountID); err != nil {
+			return nil, statusCode, err
+		}
+	}
+
+	from, err := qtx.GetAccount(context, request.FromAccountID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusNotFound, errors.New("the source account does not exist")
+	}
+
+	to, err := qtx.GetAccount(context, request.ToAccountID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusNotFound, errors.New("the destination account does not exist")
+	}
+
+	if from.ArchivedAt.Valid || to.ArchivedAt.Valid {
+		return nil, http.StatusConflict, errAccountArchived
+	}
+
+	fromAmount, toAmount, statusCode, err := transferAmounts(context, qtx, from, to, request)
+
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	transferOut, err := qtx.GetTransactionTypeByName(context, transactionTypeTransferOut)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("transfer creation failed")
+	}
+
+	transferIn, err := qtx.GetTransactionTypeByName(context, transactionTypeTransferIn)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("transfer creation failed")
+	}
+
+	envelopes, err := budget.Lock(context, qtx, from.ID, to.ID)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusUnprocessableEntity, errors.New("envelope balances cannot be computed in the account currency")
+	}
+
+	newTransfer := db.CreateTransferParams{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		UserID:        userID,
+	}
+
+	transfer, err := qtx.CreateTransfer(context, newTransfer)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("transfer creation failed")
+	}
+
+	out, in := ledger.Transfer(
+		ledger.Side{AccountID: from.ID, Currency: from.Currency, Amount: fromAmount},
+		ledger.Side{AccountID: to.ID, Currency: to.Currency, Amount: toAmount},
+	)
+
+	legs := []ledger.Leg{
+		{
+			Transaction: db.CreateTransactionParams{
+				AccountID:         from.ID,
+				UserID:            userID,
+				TransactionDate:   request.TransactionDate,
+				TransactionTypeID: transferOut.ID,
+				Description:       request.Description,
+				TransferID:        pgtype.Int4{Int32: transfer.ID, Valid: true},
+			},
+			Postings: out,
+		},
+		{
+			Transaction: db.CreateTransactionParams{
+				AccountID:         to.ID,
+				UserID:            userID,
+				TransactionDate:   request.TransactionDate,
+				TransactionTypeID: transferIn.ID,
+				Description:       request.Description,
+				TransferID:        pgtype.Int4{Int32: transfer.ID, Valid: true},
+			},
+			Postings: in,
+		},
+	}
+
+	transactions, err := ledger.Record(context, qtx, legs)
+
+	if err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("transfer creation failed")
+	}
+
+	statusCode, err = verifyEnvelopes(context, qtx, envelopes)
+
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	for _, transaction := range transactions {
+		event := audit.Event{
+			AccountID:   transaction.AccountID,
+			UserID:      userID,
+			Type:        audit.TransactionCreated,
+			Description: fmt.Sprintf("transaction %d created by transfer %d", transaction.ID, transfer.ID),
+			After:       newTransactionResponse(transaction, nil),
+		}
+
+		if _, err := audit.Record(context, qtx, event); err != nil {
+			log.Println(err.Error())
+			return nil, http.StatusInternalServerError, errors.New("transfer creation failed")
+		}
+	}
+
+	response := transferResponse{Transfer: transfer, Transactions: transactions}
+
+	if err := tx.Commit(context); err != nil {
+		log.Println(err.Error())
+		return nil, http.StatusInternalServerError, errors.New("transfer creation failed")
+	}
+
+	return &response, http.StatusOK, nil
+}
+
+// syncTransferLegs copies an edit made to one leg of a transfer onto the
+// other leg, converting the amount when the accounts use different
+// currencies, and books the transfer again. updated is the leg as it was
+// before the edit and side what it is now. The change is recorded in the
+// audit trail of the other leg's account.
+func syncTransferLegs(context context.Context, query *db.Queries, updated db.Transaction, side ledger.Side, description pgtype.Text) error {
+	userID, ok := context.Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		return errors.New("user id cannot be loaded from the session")
+	}
+
+	legs, err := query.ListTransactionByTransfer(context, updated.TransferID)
+
+	if err != nil {
+		return err
+	}
+
+	transferOut, err := query.GetTransactionTypeByName(context, transactionTypeTransferOut)
+
+	if err != nil {
+		return err
+	}
+
+	var from, to ledger.Side
+	var others []db.Transaction
+
+	for _, leg := range legs {
+		legSide := side
+
+		if leg.ID != updated.ID {
+			if _, err := verifyWritable(context, query, leg.AccountID); err != nil {
+				return err
+			}
+
+			legCurrency, err := transactionCurrency(context, query, leg)
+
+			if err != nil {
+				return err
+			}
+
+			legSide = ledger.Side{
+				TransactionID: leg.ID,
+				AccountID:     leg.AccountID,
+				Currency:      legCurrency,
+				Amount:        leg.Amount,
+				CategoryID:    leg.CategoryID,
+			}
+
+			if side.Amount != updated.Amount {
+				legSide.Amount = side.Amount
+
+				if legCurrency != side.Currency {
+					rateTable, err := rates.LoadTable(context, query, []string{side.Currency, legCurrency}, updated.TransactionDate)
+
+					if err != nil {
+						return err
+					}
+
+					amount, err := rateTable.Convert(side.Amount, side.Currency, legCurrency, updated.TransactionDate.Time)
+
+					if err != nil {
+						return err
+					}
+
+					legSide.Amount = amount.RoundTo(legCurrency)
+				}
+			}
+
+			legChanges := db.UpdateTransactionParams{
+				Description: description,
+				ID:          leg.ID,
+			}
+
+			if err := query.UpdateTransaction(context, legChanges); err != nil {
+				return err
+			}
+
+			others = append(others, leg)
+		}
+
+		if leg.TransactionTypeID == transferOut.ID {
+			from = legSide
+		} else {
+			to = legSide
+		}
+	}
+
+	out, in := ledger.Transfer(from, to)
+
+	if err := ledger.Rebook(context, query, updated.JournalEntryID, append(out, in...)); err != nil {
+		return err
+	}
+
+	for _, leg := range others {
+		updatedLeg, err := query.GetTransaction(context, leg.ID)
+
+		if err != nil {
+			return err
+		}
+
+		event := audit.Event{
+			AccountID:   leg.AccountID,
+			UserID:      userID,
+			Type:        audit.TransactionUpdated,
+			Description: fmt.Sprintf("transaction %d updated with transaction %d of transfer %d", leg.ID, updated.ID, updated.TransferID.Int32),
+			Before:      newTransactionResponse(leg, nil),
+			After:       newTransactionResponse(updatedLeg, nil),
+		}
+
+		if _, err := audit.Record(context, query, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var newTransfer transferRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&newTransfer); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "error parsing json from request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, statusCode, err := saveTransfer(r.Context(), newTransfer)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	serializedTransfer, err := json.Marshal(transfer)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTransfer)
+}
+
+func GetTransfer(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	transferID, err := strconv.ParseInt(r.PathValue("transferID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transfer id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	transfer, err := query.GetTransfer(r.Context(), int32(transferID))
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this transfer does not exist", http.StatusNotFound)
+		return
+	}
+
+	for _, accountID := range []int32{transfer.FromAccountID, transfer.ToAccountID} {
+		if statusCode, err := verifyMembership(r.Context(), query, contextUserID, accountID); err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+	}
+
+	legs, err := query.ListTransactionByTransfer(r.Context(), pgtype.Int4{Int32: transfer.ID, Valid: true})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	serializedTransfer, err := json.Marshal(transferResponse{Transfer: transfer, Transactions: legs})
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTransfer)
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"cashpal/audit"
+	"cashpal/budget"
+	"cashpal/config"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"cashpal/middleware"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type trashedTransactionResponse struct {
+	db.Transaction
+	PurgeAt pgtype.Timestamp `json:"purge_at"`
+}
+
+func ListTrash(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, err := database.GetNewConnection(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+
+	listTrashParams := db.ListTrashedTransactionByAccountParams{
+		AccountID: int32(accountID),
+		UserID:    contextUserID,
+	}
+
+	transactions, err := query.ListTrashedTransactionByAccount(r.Context(), listTrashParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	retention := config.GetTrashRetention()
+	trash := make([]trashedTransactionResponse, 0, len(transactions))
+
+	for _, transaction := range transactions {
+		purgeAt := pgtype.Timestamp{Time: transaction.DeletedAt.Time.Add(retention), Valid: true}
+		trash = append(trash, trashedTransactionResponse{Transaction: transaction, PurgeAt: purgeAt})
+	}
+
+	serializedTrash, err := json.Marshal(trash)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTrash)
+}
+
+// RestoreTransaction takes a transaction out of the trash, together with the
+// other leg when it belongs to a transfer.
+func RestoreTransaction(w http.ResponseWriter, r *http.Request) {
+	contextUserID, ok := r.Context().Value(middleware.UserIDContextKey).(int32)
+
+	if !ok {
+		http.Error(w, "user user id cannot be loaded from the session data", http.StatusInternalServerError)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(r.PathValue("accountID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "account id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(r.PathValue("transactionID"), 10, 32)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction id is invalid or malformed", http.StatusBadRequest)
+		return
+	}
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(r.Context())
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	defer connClose()
+	defer tx.Rollback(r.Context())
+
+	qtx := query.WithTx(tx)
+
+	getTransactionParams := db.GetTrashedTransactionWithCheckParams{
+		AccountID: int32(accountID),
+		ID:        int32(transactionID),
+		UserID:    contextUserID,
+	}
+
+	transaction, err := qtx.GetTrashedTransactionWithCheck(r.Context(), getTransactionParams)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "this transaction is not in the trash", http.StatusNotFound)
+		return
+	}
+
+	statusCode, err := verifyWritable(r.Context(), qtx, int32(accountID))
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	entryTransactions, err := qtx.ListTransactionByJournalEntry(r.Context(), transaction.JournalEntryID)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "service unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	accountIDs := make([]int32, 0, len(entryTransactions))
+
+	// The other leg of a transfer comes back along with this one, so its
+	// account has to be open to the user as well.
+	for _, entryTransaction := range entryTransactions {
+		legAccountID := entryTransaction.Transaction.AccountID
+
+		if legAccountID != int32(accountID) {
+			statusCode, err = verifyMembership(r.Context(), qtx, contextUserID, legAccountID)
+
+			if err != nil {
+				http.Error(w, err.Error(), statusCode)
+				return
+			}
+
+			statusCode, err = verifyWritable(r.Context(), qtx, legAccountID)
+
+			if err != nil {
+				http.Error(w, err.Error(), statusCode)
+				return
+			}
+		}
+
+		accountIDs = append(accountIDs, legAccountID)
+	}
+
+	envelopes, err := budget.Lock(r.Context(), qtx, accountIDs...)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "envelope balances cannot be computed in the account currency", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := ledger.Restore(r.Context(), qtx, transaction.JournalEntryID); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction restore failed", http.StatusInternalServerError)
+		return
+	}
+
+	statusCode, err = verifyEnvelopes(r.Context(), qtx, envelopes)
+
+	if err != nil {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	var restoredTransaction db.Transaction
+
+	// Restoring one leg of a transfer brings back the other one too, so its
+	// account gets an event of its own.
+	for _, entryTransaction := range entryTransactions {
+		trashed := entryTransaction.Transaction
+
+		restored, err := qtx.GetTransaction(r.Context(), trashed.ID)
+
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "service unavailable", http.StatusInternalServerError)
+			return
+		}
+
+		description := fmt.Sprintf("transaction %d restored from trash", restored.ID)
+
+		if restored.ID == transaction.ID {
+			restoredTransaction = restored
+		} else {
+			description = fmt.Sprintf("transaction %d restored from trash with transaction %d of transfer %d", restored.ID, transaction.ID, transaction.TransferID.Int32)
+		}
+
+		event := audit.Event{
+			AccountID:   restored.AccountID,
+			UserID:      contextUserID,
+			Type:        audit.TransactionRestored,
+			Description: description,
+			Before:      trashed,
+			After:       restored,
+		}
+
+		if _, err := audit.Record(r.Context(), qtx, event); err != nil {
+			log.Println(err.Error())
+			http.Error(w, "transaction restore failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Println(err.Error())
+		http.Error(w, "transaction restore failed", http.StatusInternalServerError)
+		return
+	}
+
+	serializedTransaction, err := json.Marshal(restoredTransaction)
+
+	if err != nil {
+		log.Println(err.Error())
+		http.Error(w, "data serialization failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(serializedTransaction)
+}
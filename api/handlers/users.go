@@ -5,6 +5,7 @@ import (
 	"cashpal/database"
 	db "cashpal/database/generated"
 	"cashpal/middleware"
+	"cashpal/money"
 	"context"
 	"encoding/json"
 	"errors"
@@ -184,12 +185,23 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	defer connClose()
 
-	user.Password, err = utils.HashPassword(user.Password)
+	if user.Password.Valid {
+		user.Password.String, err = utils.HashPassword(user.Password.String)
 
-	if err != nil {
-		log.Println(err.Error())
-		http.Error(w, "failed to hash password", http.StatusInternalServerError)
-		return
+		if err != nil {
+			log.Println(err.Error())
+			http.Error(w, "failed to hash password", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if user.ReportingCurrency.Valid {
+		user.ReportingCurrency.String, err = money.NormalizeCurrency(user.ReportingCurrency.String)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
 	updatedUser, err := query.UpdateUser(r.Context(), user)
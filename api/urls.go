@@ -21,9 +21,14 @@ func SetupURLs(router *http.ServeMux) {
 	// Accounts
 	protected.HandleFunc("GET /accounts", handlers.ListAccounts)
 	protected.HandleFunc("GET /accounts/{accountID}", handlers.GetAccount)
+	protected.HandleFunc("GET /accounts/{accountID}/balance", handlers.GetAccountBalance)
 	protected.HandleFunc("POST /accounts", handlers.CreateAccount)
 	protected.HandleFunc("PATCH /accounts/{accountID}", handlers.UpdateAccount)
 	protected.HandleFunc("DELETE /accounts/{accountID}", handlers.DeleteAccount)
+	protected.HandleFunc("POST /accounts/{accountID}/archive", handlers.ArchiveAccount)
+	protected.HandleFunc("POST /accounts/{accountID}/unarchive", handlers.UnarchiveAccount)
+	protected.HandleFunc("GET /accounts/{accountID}/events", handlers.ListAccountEvents)
+	protected.HandleFunc("GET /accounts/{accountID}/events/verify", handlers.VerifyAccountEvents)
 
 	// Members
 	protected.HandleFunc("GET /accounts/{accountID}/members", handlers.ListMembers)
@@ -32,12 +37,81 @@ func SetupURLs(router *http.ServeMux) {
 	protected.HandleFunc("PATCH /accounts/{accountID}/members/{userID}", handlers.UpdateMember)
 	protected.HandleFunc("DELETE /accounts/{accountID}/members/{userID}", handlers.DeleteMember)
 
+	// Categories
+	protected.HandleFunc("GET /accounts/{accountID}/categories", handlers.ListCategories)
+	protected.HandleFunc("GET /accounts/{accountID}/categories/{categoryID}", handlers.GetCategory)
+	protected.HandleFunc("POST /accounts/{accountID}/categories", handlers.CreateCategory)
+	protected.HandleFunc("PATCH /accounts/{accountID}/categories/{categoryID}", handlers.UpdateCategory)
+	protected.HandleFunc("DELETE /accounts/{accountID}/categories/{categoryID}", handlers.DeleteCategory)
+
 	// Transactions
 	protected.HandleFunc("GET /accounts/{accountID}/transactions", handlers.ListTransactions)
 	protected.HandleFunc("GET /accounts/{accountID}/transactions/{transactionID}", handlers.GetTransaction)
 	protected.HandleFunc("POST /accounts/{accountID}/transactions", handlers.CreateTransactions)
 	protected.HandleFunc("PATCH /accounts/{accountID}/transactions/{transactionID}", handlers.UpdateTransaction)
 	protected.HandleFunc("DELETE /accounts/{accountID}/transactions/{transactionID}", handlers.DeleteTransaction)
+	protected.HandleFunc("GET /accounts/{accountID}/transactions/{transactionID}/postings", handlers.GetTransactionPostings)
+	protected.HandleFunc("POST /accounts/{accountID}/transactions/{transactionID}/merge", handlers.MergeTransaction)
+
+	// Trash
+	protected.HandleFunc("GET /accounts/{accountID}/trash", handlers.ListTrash)
+	protected.HandleFunc("POST /accounts/{accountID}/trash/{transactionID}/restore", handlers.RestoreTransaction)
+
+	// Recurring transactions
+	protected.HandleFunc("GET /accounts/{accountID}/recurring", handlers.ListRecurringRules)
+	protected.HandleFunc("GET /accounts/{accountID}/recurring/upcoming", handlers.ListUpcomingOccurrences)
+	protected.HandleFunc("GET /accounts/{accountID}/recurring/{ruleID}", handlers.GetRecurringRule)
+	protected.HandleFunc("POST /accounts/{accountID}/recurring", handlers.CreateRecurringRule)
+	protected.HandleFunc("DELETE /accounts/{accountID}/recurring/{ruleID}", handlers.DeleteRecurringRule)
+	protected.HandleFunc("POST /accounts/{accountID}/recurring/{ruleID}/skip", handlers.SkipOccurrence)
+
+	// Budgets
+	protected.HandleFunc("GET /accounts/{accountID}/budgets/{month}", handlers.GetBudget)
+	protected.HandleFunc("PUT /accounts/{accountID}/budgets/{month}/{categoryID}", handlers.SetBudget)
+	protected.HandleFunc("DELETE /accounts/{accountID}/budgets/{month}/{categoryID}", handlers.DeleteBudget)
+
+	// Envelopes
+	protected.HandleFunc("PUT /accounts/{accountID}/budgeting", handlers.SetBudgetingMode)
+	protected.HandleFunc("GET /accounts/{accountID}/envelopes", handlers.GetEnvelopes)
+	protected.HandleFunc("GET /accounts/{accountID}/envelopes/moves", handlers.ListEnvelopeMoves)
+	protected.HandleFunc("POST /accounts/{accountID}/envelopes/moves", handlers.MoveEnvelopeMoney)
+
+	// Goals
+	protected.HandleFunc("GET /accounts/{accountID}/goals", handlers.ListGoals)
+	protected.HandleFunc("GET /accounts/{accountID}/goals/{goalID}", handlers.GetGoal)
+	protected.HandleFunc("POST /accounts/{accountID}/goals", handlers.CreateGoal)
+	protected.HandleFunc("PATCH /accounts/{accountID}/goals/{goalID}", handlers.UpdateGoal)
+	protected.HandleFunc("DELETE /accounts/{accountID}/goals/{goalID}", handlers.DeleteGoal)
+
+	// Shares and settlements
+	protected.HandleFunc("GET /accounts/{accountID}/transactions/{transactionID}/shares", handlers.ListTransactionShares)
+	protected.HandleFunc("PUT /accounts/{accountID}/transactions/{transactionID}/shares", handlers.SetTransactionShares)
+	protected.HandleFunc("DELETE /accounts/{accountID}/transactions/{transactionID}/shares", handlers.DeleteTransactionShares)
+	protected.HandleFunc("GET /accounts/{accountID}/settlements", handlers.GetSettlements)
+	protected.HandleFunc("POST /accounts/{accountID}/settlements", handlers.CreateSettlementPayment)
+	protected.HandleFunc("GET /accounts/{accountID}/members/{userID}/ledger", handlers.GetMemberLedger)
+
+	// Statement imports and exports
+	protected.HandleFunc("GET /accounts/{accountID}/import-profiles", handlers.ListImportProfiles)
+	protected.HandleFunc("POST /accounts/{accountID}/import-profiles", handlers.CreateImportProfile)
+	protected.HandleFunc("DELETE /accounts/{accountID}/import-profiles/{profileID}", handlers.DeleteImportProfile)
+	protected.HandleFunc("POST /accounts/{accountID}/imports", handlers.ImportStatement)
+	protected.HandleFunc("GET /accounts/{accountID}/export", handlers.ExportTransactions)
+
+	// Tags
+	protected.HandleFunc("GET /accounts/{accountID}/tags", handlers.ListTags)
+	protected.HandleFunc("GET /accounts/{accountID}/transactions/{transactionID}/tags", handlers.ListTransactionTags)
+	protected.HandleFunc("POST /accounts/{accountID}/transactions/{transactionID}/tags", handlers.AddTransactionTags)
+	protected.HandleFunc("DELETE /accounts/{accountID}/transactions/{transactionID}/tags/{tag}", handlers.RemoveTransactionTag)
+
+	// Transfers
+	protected.HandleFunc("POST /transfers", handlers.CreateTransfer)
+	protected.HandleFunc("GET /transfers/{transferID}", handlers.GetTransfer)
+
+	// Exchange rates
+	protected.HandleFunc("GET /exchange-rates", handlers.ListExchangeRates)
+	protected.HandleFunc("POST /exchange-rates", handlers.CreateExchangeRates)
+	protected.HandleFunc("POST /exchange-rates/import", handlers.ImportExchangeRates)
 
 	// Authentication
 	router.HandleFunc("GET /login", handlers.Login)
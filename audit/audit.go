@@ -0,0 +1,184 @@
+// Package audit keeps the trail of changes made to an account.
+//
+// Every mutation of an account, its members or its transactions is recorded
+// as an Account_Events row holding who made it, what kind of change it was
+// and a diff of the fields it touched. Events are written with the same
+// queries as the change itself, so they commit or roll back together.
+//
+// The events of an account form a hash chain: the database stores with each
+// event a hash of its content and of the previous event's hash. Verify walks
+// the chain and finds the first event that was rewritten, removed or
+// inserted afterwards. Removing the most recent events leaves no trace.
+package audit
+
+import (
+	"bytes"
+	db "cashpal/database/generated"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	AccountCreated      = "account_created"
+	AccountUpdated      = "account_updated"
+	AccountArchived     = "account_archived"
+	AccountUnarchived   = "account_unarchived"
+	MemberAdded         = "member_added"
+	MemberUpdated       = "member_updated"
+	MemberRemoved       = "member_removed"
+	TransactionCreated  = "transaction_created"
+	TransactionUpdated  = "transaction_updated"
+	TransactionDeleted  = "transaction_deleted"
+	TransactionRestored = "transaction_restored"
+	TransactionMerged   = "transaction_merged"
+)
+
+// ignoredFields change on every write and would only add noise to a diff.
+var ignoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// Event describes a change to an account. Before is nil for things that were
+// created and After is nil for things that were removed.
+type Event struct {
+	AccountID   int32
+	UserID      int32
+	Type        string
+	Description string
+	Before      any
+	After       any
+}
+
+// Change holds the old and new JSON value of a field.
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+func fields(value any) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+
+	if value == nil {
+		return fields, nil
+	}
+
+	serialized, err := json.Marshal(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(serialized, &fields); err != nil {
+		return nil, fmt.Errorf("audit values must serialize to a JSON object: %w", err)
+	}
+
+	return fields, nil
+}
+
+// Diff compares the JSON form of two values field by field and returns the
+// fields that differ, keyed by their JSON name.
+func Diff(before any, after any) (json.RawMessage, error) {
+	beforeFields, err := fields(before)
+
+	if err != nil {
+		return nil, err
+	}
+
+	afterFields, err := fields(after)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]Change)
+
+	for name, value := range beforeFields {
+		if ignoredFields[name] || bytes.Equal(value, afterFields[name]) {
+			continue
+		}
+
+		changes[name] = Change{Before: value, After: afterFields[name]}
+	}
+
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; ok || ignoredFields[name] {
+			continue
+		}
+
+		changes[name] = Change{After: value}
+	}
+
+	return json.Marshal(changes)
+}
+
+// Record stores an event. The queries should be bound to the database
+// transaction that makes the change.
+func Record(ctx context.Context, query *db.Queries, event Event) (db.AccountEvent, error) {
+	eventType, err := query.GetEventTypeByName(ctx, event.Type)
+
+	if err != nil {
+		return db.AccountEvent{}, fmt.Errorf("unknown event type %q: %w", event.Type, err)
+	}
+
+	diff, err := Diff(event.Before, event.After)
+
+	if err != nil {
+		return db.AccountEvent{}, err
+	}
+
+	newEvent := db.CreateAccountEventParams{
+		AccountID:   event.AccountID,
+		EventTypeID: eventType.ID,
+		Description: event.Description,
+		UserID:      pgtype.Int4{Int32: event.UserID, Valid: true},
+		Diff:        diff,
+	}
+
+	return query.CreateAccountEvent(ctx, newEvent)
+}
+
+// Verification is the result of walking the event chain of an account.
+type Verification struct {
+	AccountID     int32  `json:"account_id"`
+	Valid         bool   `json:"valid"`
+	EventsChecked int    `json:"events_checked"`
+	BrokenEventID *int32 `json:"broken_event_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Verify recomputes the hash of every event of an account and checks that
+// each one links to the event before it. It stops at the first broken link.
+func Verify(ctx context.Context, query *db.Queries, accountID int32) (Verification, error) {
+	verification := Verification{AccountID: accountID, Valid: true}
+
+	chain, err := query.ListAccountEventChain(ctx, accountID)
+
+	if err != nil {
+		return verification, err
+	}
+
+	previous := pgtype.Text{}
+
+	for _, link := range chain {
+		switch {
+		case link.PrevHash != previous:
+			verification.Reason = "the event does not link to the event before it"
+		case link.Hash != link.ComputedHash:
+			verification.Reason = "the event content does not match its hash"
+		}
+
+		if verification.Reason != "" {
+			verification.Valid = false
+			verification.BrokenEventID = &link.ID
+			return verification, nil
+		}
+
+		verification.EventsChecked++
+		previous = pgtype.Text{String: link.Hash, Valid: true}
+	}
+
+	return verification, nil
+}
@@ -0,0 +1,162 @@
+package audit
+
+import (
+	db "cashpal/database/generated"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestDiff(t *testing.T) {
+	type account struct {
+		Name      string `json:"name"`
+		Currency  string `json:"currency"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	before := account{Name: "Groceries", Currency: "EUR", UpdatedAt: "monday"}
+	after := account{Name: "Food", Currency: "EUR", UpdatedAt: "tuesday"}
+
+	tests := []struct {
+		name   string
+		before any
+		after  any
+		want   map[string]Change
+	}{
+		{"updated", before, after, map[string]Change{
+			"name": {Before: json.RawMessage(`"Groceries"`), After: json.RawMessage(`"Food"`)},
+		}},
+		{"created", nil, after, map[string]Change{
+			"name":     {After: json.RawMessage(`"Food"`)},
+			"currency": {After: json.RawMessage(`"EUR"`)},
+		}},
+		{"removed", before, nil, map[string]Change{
+			"name":     {Before: json.RawMessage(`"Groceries"`)},
+			"currency": {Before: json.RawMessage(`"EUR"`)},
+		}},
+		{"unchanged", before, before, map[string]Change{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff, err := Diff(test.before, test.after)
+
+			if err != nil {
+				t.Fatalf("Diff = %v", err)
+			}
+
+			var got map[string]Change
+
+			if err := json.Unmarshal(diff, &got); err != nil {
+				t.Fatalf("diff %s is not a JSON object: %v", diff, err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Diff = %s, want %v", diff, test.want)
+			}
+		})
+	}
+
+	if _, err := Diff([]int{1}, nil); err == nil {
+		t.Error("Diff of a value that is not an object succeeded")
+	}
+}
+
+// chainDB answers ListAccountEventChain with a fixed chain.
+type chainDB struct {
+	chain []db.ListAccountEventChainRow
+}
+
+func (c chainDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("chainDB only lists event chains")
+}
+
+func (c chainDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return &chainRows{chain: c.chain, index: -1}, nil
+}
+
+func (c chainDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return nil
+}
+
+type chainRows struct {
+	chain []db.ListAccountEventChainRow
+	index int
+}
+
+func (r *chainRows) Close()                                       {}
+func (r *chainRows) Err() error                                   { return nil }
+func (r *chainRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *chainRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *chainRows) Values() ([]any, error)                       { return nil, nil }
+func (r *chainRows) RawValues() [][]byte                          { return nil }
+func (r *chainRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *chainRows) Next() bool {
+	r.index++
+	return r.index < len(r.chain)
+}
+
+func (r *chainRows) Scan(dest ...any) error {
+	link := r.chain[r.index]
+
+	*dest[0].(*int32) = link.ID
+	*dest[1].(*pgtype.Text) = link.PrevHash
+	*dest[2].(*string) = link.Hash
+	*dest[3].(*string) = link.ComputedHash
+
+	return nil
+}
+
+func link(id int32, prevHash string, hash string, computedHash string) db.ListAccountEventChainRow {
+	return db.ListAccountEventChainRow{
+		ID:           id,
+		PrevHash:     pgtype.Text{String: prevHash, Valid: prevHash != ""},
+		Hash:         hash,
+		ComputedHash: computedHash,
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name      string
+		chain     []db.ListAccountEventChainRow
+		wantValid bool
+		wantCount int
+		wantEvent int32
+	}{
+		{"empty", nil, true, 0, 0},
+		{"intact", []db.ListAccountEventChainRow{link(1, "", "a", "a"), link(2, "a", "b", "b"), link(3, "b", "c", "c")}, true, 3, 0},
+		{"rewritten", []db.ListAccountEventChainRow{link(1, "", "a", "a"), link(2, "a", "b", "x"), link(3, "b", "c", "c")}, false, 1, 2},
+		{"removed", []db.ListAccountEventChainRow{link(1, "", "a", "a"), link(3, "b", "c", "c")}, false, 1, 3},
+		{"first removed", []db.ListAccountEventChainRow{link(2, "a", "b", "b")}, false, 0, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verification, err := Verify(context.Background(), db.New(chainDB{chain: test.chain}), 7)
+
+			if err != nil {
+				t.Fatalf("Verify = %v", err)
+			}
+
+			if verification.Valid != test.wantValid || verification.EventsChecked != test.wantCount {
+				t.Errorf("Verify = %+v, want valid %v after %d events", verification, test.wantValid, test.wantCount)
+			}
+
+			if test.wantValid {
+				if verification.BrokenEventID != nil {
+					t.Errorf("BrokenEventID = %d, want none", *verification.BrokenEventID)
+				}
+			} else if verification.BrokenEventID == nil || *verification.BrokenEventID != test.wantEvent {
+				t.Errorf("BrokenEventID = %v, want %d", verification.BrokenEventID, test.wantEvent)
+			}
+		})
+	}
+}
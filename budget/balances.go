@@ -0,0 +1,167 @@
+package budget
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"cashpal/rates"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// OverdrawnError reports the envelopes of an account that a change left short.
+type OverdrawnError struct {
+	AccountID   int32
+	CategoryIDs []int32
+}
+
+func (e *OverdrawnError) Error() string {
+	names := make([]string, 0, len(e.CategoryIDs))
+
+	for _, categoryID := range e.CategoryIDs {
+		if categoryID == ToBeAssigned {
+			names = append(names, "to be assigned")
+		} else {
+			names = append(names, fmt.Sprintf("category %d", categoryID))
+		}
+	}
+
+	return fmt.Sprintf("not enough money in the envelope of %s", strings.Join(names, ", "))
+}
+
+// Balances computes the balance of every envelope of an account in its
+// currency. Postings in other currencies are converted with the rate of
+// their day.
+func Balances(ctx context.Context, query *db.Queries, account db.Account) (Envelopes, error) {
+	activity, err := query.ListEnvelopeActivity(ctx, account.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	currencies := []string{account.Currency}
+
+	for _, item := range activity {
+		if item.Currency != account.Currency {
+			currencies = append(currencies, item.Currency)
+		}
+	}
+
+	var rateTable *money.RateTable
+
+	if len(currencies) > 1 {
+		today := pgtype.Date{Time: time.Now().UTC().Truncate(24 * time.Hour), Valid: true}
+		rateTable, err = rates.LoadTable(ctx, query, currencies, today)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	envelopes := Envelopes{ToBeAssigned: 0}
+
+	for _, item := range activity {
+		amount := item.Amount
+
+		if item.Currency != account.Currency {
+			amount, err = rateTable.Convert(item.Amount, item.Currency, account.Currency, item.EntryDate.Time)
+
+			if err != nil {
+				return nil, err
+			}
+
+			amount = amount.RoundTo(account.Currency)
+		}
+
+		envelopes.Post(item.CategoryID, amount)
+	}
+
+	moves, err := query.ListEnvelopeMoveTotals(ctx, account.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, move := range moves {
+		envelopes[move.CategoryID] += move.Amount
+	}
+
+	return envelopes, nil
+}
+
+// Guard holds the envelopes of the accounts a change touches as they were
+// before it, keyed by account id. Accounts that may overdraw their
+// envelopes are left out.
+type Guard map[int32]Envelopes
+
+// Lock locks the accounts until the end of the database transaction, in id
+// order so that two changes touching the same accounts cannot deadlock, and
+// returns their envelopes. The queries should be bound to that transaction.
+func Lock(ctx context.Context, query *db.Queries, accountIDs ...int32) (Guard, error) {
+	ids := append([]int32(nil), accountIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	guard := Guard{}
+
+	for i, accountID := range ids {
+		if i > 0 && accountID == ids[i-1] {
+			continue
+		}
+
+		account, err := query.GetAccountForUpdate(ctx, accountID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if account.BudgetingMode != ModeEnvelope || account.AllowNegativeEnvelopes {
+			continue
+		}
+
+		envelopes, err := Balances(ctx, query, account)
+
+		if err != nil {
+			return nil, err
+		}
+
+		guard[account.ID] = envelopes
+	}
+
+	return guard, nil
+}
+
+// Verify returns an *OverdrawnError when the change left an envelope of a
+// guarded account below zero, or lower than it was when it already was.
+func (guard Guard) Verify(ctx context.Context, query *db.Queries) error {
+	accountIDs := make([]int32, 0, len(guard))
+
+	for accountID := range guard {
+		accountIDs = append(accountIDs, accountID)
+	}
+
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	for _, accountID := range accountIDs {
+		account, err := query.GetAccount(ctx, accountID)
+
+		if err != nil {
+			return err
+		}
+
+		after, err := Balances(ctx, query, account)
+
+		if err != nil {
+			return err
+		}
+
+		if overdrawn := after.Overdrawn(guard[accountID]); len(overdrawn) > 0 {
+			return &OverdrawnError{AccountID: accountID, CategoryIDs: overdrawn}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package budget compares what was planned for each category of an account
+// with what was actually spent, month by month.
+//
+// Spending on a category includes its sub-categories, so a budget on
+// "Food" also covers "Food > Groceries". A budget with rollover starts the
+// month with whatever was left unspent the month before; overspending is
+// never carried over.
+package budget
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"sort"
+	"time"
+)
+
+// Spending is the amount spent on one category on one day, in the account
+// currency. Income and refunds in the category are negative spending.
+type Spending struct {
+	CategoryID int32
+	Date       time.Time
+	Amount     money.Amount
+}
+
+// Line is the state of one budgeted category in a month.
+type Line struct {
+	CategoryID int32        `json:"category_id"`
+	Planned    money.Amount `json:"planned"`
+	CarriedIn  money.Amount `json:"carried_in"`
+	Spent      money.Amount `json:"spent"`
+	Remaining  money.Amount `json:"remaining"`
+	Rollover   bool         `json:"rollover"`
+	OverBudget bool         `json:"over_budget"`
+}
+
+// Report is the budget of an account for a month.
+type Report struct {
+	Lines           []Line       `json:"lines"`
+	Planned         money.Amount `json:"planned"`
+	Spent           money.Amount `json:"spent"`
+	Remaining       money.Amount `json:"remaining"`
+	Unbudgeted      money.Amount `json:"unbudgeted"`
+	OverBudgetCount int          `json:"over_budget_count"`
+}
+
+// MonthOf returns the first day of the month of t.
+func MonthOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// budgetedAncestor returns the closest category, starting with the category
+// itself, that has a budget in the month.
+func budgetedAncestor(categoryID int32, parents map[int32]int32, budgeted map[int32]bool) (int32, bool) {
+	seen := make(map[int32]bool)
+
+	for !seen[categoryID] {
+		if budgeted[categoryID] {
+			return categoryID, true
+		}
+
+		seen[categoryID] = true
+
+		parent, ok := parents[categoryID]
+
+		if !ok {
+			break
+		}
+
+		categoryID = parent
+	}
+
+	return 0, false
+}
+
+// Summarize builds the report of month from every budget of the account up
+// to that month and the spending since the first of them. Earlier months
+// are only needed for rollover.
+func Summarize(month time.Time, budgets []db.Budget, categories []db.Category, spending []Spending) Report {
+	month = MonthOf(month)
+
+	parents := make(map[int32]int32)
+
+	for _, category := range categories {
+		if category.ParentID.Valid {
+			parents[category.ID] = category.ParentID.Int32
+		}
+	}
+
+	byMonth := make(map[time.Time]map[int32]db.Budget)
+
+	for _, budget := range budgets {
+		key := MonthOf(budget.Month.Time)
+
+		if byMonth[key] == nil {
+			byMonth[key] = make(map[int32]db.Budget)
+		}
+
+		byMonth[key][budget.CategoryID] = budget
+	}
+
+	months := make([]time.Time, 0, len(byMonth)+1)
+
+	for key := range byMonth {
+		if key.Before(month) {
+			months = append(months, key)
+		}
+	}
+
+	months = append(months, month)
+
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	report := Report{Lines: []Line{}}
+	remaining := make(map[int32]money.Amount)
+	previous := time.Time{}
+
+	for _, current := range months {
+		monthBudgets := byMonth[current]
+
+		budgeted := make(map[int32]bool, len(monthBudgets))
+
+		for categoryID := range monthBudgets {
+			budgeted[categoryID] = true
+		}
+
+		spent := make(map[int32]money.Amount)
+		var unbudgeted money.Amount
+
+		for _, item := range spending {
+			if !MonthOf(item.Date).Equal(current) {
+				continue
+			}
+
+			if categoryID, ok := budgetedAncestor(item.CategoryID, parents, budgeted); ok {
+				spent[categoryID] += item.Amount
+			} else {
+				unbudgeted += item.Amount
+			}
+		}
+
+		// Only the month right before can roll over into this one.
+		consecutive := !previous.IsZero() && previous.AddDate(0, 1, 0).Equal(current)
+		next := make(map[int32]money.Amount)
+		lines := make([]Line, 0, len(monthBudgets))
+
+		for categoryID, budget := range monthBudgets {
+			line := Line{
+				CategoryID: categoryID,
+				Planned:    budget.Amount,
+				Spent:      spent[categoryID],
+				Rollover:   budget.Rollover,
+			}
+
+			if budget.Rollover && consecutive && remaining[categoryID] > 0 {
+				line.CarriedIn = remaining[categoryID]
+			}
+
+			line.Remaining = line.Planned + line.CarriedIn - line.Spent
+			line.OverBudget = line.Remaining < 0
+			next[categoryID] = line.Remaining
+			lines = append(lines, line)
+		}
+
+		remaining = next
+		previous = current
+
+		if !current.Equal(month) {
+			continue
+		}
+
+		sort.Slice(lines, func(i, j int) bool { return lines[i].CategoryID < lines[j].CategoryID })
+
+		report.Lines = lines
+		report.Unbudgeted = unbudgeted
+
+		for _, line := range lines {
+			report.Planned += line.Planned
+			report.Spent += line.Spent
+			report.Remaining += line.Remaining
+
+			if line.OverBudget {
+				report.OverBudgetCount++
+			}
+		}
+	}
+
+	return report
+}
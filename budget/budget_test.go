@@ -0,0 +1,179 @@
+package budget
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	food      int32 = 1
+	groceries int32 = 2
+	rent      int32 = 3
+	fun       int32 = 4
+)
+
+var categories = []db.Category{
+	{ID: food, Name: "Food"},
+	{ID: groceries, ParentID: pgtype.Int4{Int32: food, Valid: true}, Name: "Groceries"},
+	{ID: rent, Name: "Rent"},
+	{ID: fun, Name: "Fun"},
+}
+
+func month(year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func plan(categoryID int32, start time.Time, amount string, rollover bool) db.Budget {
+	return db.Budget{
+		CategoryID: categoryID,
+		Month:      pgtype.Date{Time: start, Valid: true},
+		Amount:     money.MustParse(amount),
+		Rollover:   rollover,
+	}
+}
+
+func spend(categoryID int32, date time.Time, amount string) Spending {
+	return Spending{CategoryID: categoryID, Date: date, Amount: money.MustParse(amount)}
+}
+
+func TestMonthOf(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 1, 31, 23, 59, 0, 0, time.UTC), month(2026, 1)},
+		{time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), month(2026, 2)},
+		{time.Date(2026, 12, 15, 8, 0, 0, 0, time.FixedZone("CET", 3600)), month(2026, 12)},
+	}
+
+	for _, test := range tests {
+		if got := MonthOf(test.t); !got.Equal(test.want) {
+			t.Errorf("MonthOf(%s) = %s, want %s", test.t, got, test.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	december, january := month(2025, 12), month(2026, 1)
+
+	tests := []struct {
+		name     string
+		month    time.Time
+		budgets  []db.Budget
+		spending []Spending
+		want     Report
+	}{
+		{
+			name:  "rollover and sub-categories",
+			month: january.AddDate(0, 0, 14),
+			budgets: []db.Budget{
+				plan(food, december, "100", true),
+				plan(rent, december, "500", true),
+				plan(food, january, "100", true),
+				plan(rent, january, "500", false),
+			},
+			spending: []Spending{
+				spend(groceries, december.AddDate(0, 0, 3), "60"),
+				spend(rent, december, "520"),
+				spend(food, january, "30"),
+				spend(groceries, january.AddDate(0, 0, 9), "50"),
+				spend(food, january.AddDate(0, 0, 20), "-10"),
+				spend(rent, january, "500"),
+				spend(fun, january, "20"),
+				spend(fun, month(2026, 2), "99"),
+			},
+			want: Report{
+				Lines: []Line{
+					{CategoryID: food, Planned: money.MustParse("100"), CarriedIn: money.MustParse("40"), Spent: money.MustParse("70"), Remaining: money.MustParse("70"), Rollover: true},
+					{CategoryID: rent, Planned: money.MustParse("500"), Spent: money.MustParse("500")},
+				},
+				Planned:    money.MustParse("600"),
+				Spent:      money.MustParse("570"),
+				Remaining:  money.MustParse("70"),
+				Unbudgeted: money.MustParse("20"),
+			},
+		},
+		{
+			name:  "overspending is not carried over",
+			month: january,
+			budgets: []db.Budget{
+				plan(rent, december, "500", true),
+				plan(rent, january, "500", true),
+			},
+			spending: []Spending{
+				spend(rent, december, "520"),
+				spend(rent, january, "510"),
+			},
+			want: Report{
+				Lines: []Line{
+					{CategoryID: rent, Planned: money.MustParse("500"), Spent: money.MustParse("510"), Remaining: money.MustParse("-10"), Rollover: true, OverBudget: true},
+				},
+				Planned:         money.MustParse("500"),
+				Spent:           money.MustParse("510"),
+				Remaining:       money.MustParse("-10"),
+				OverBudgetCount: 1,
+			},
+		},
+		{
+			name:  "only the month before rolls over",
+			month: january,
+			budgets: []db.Budget{
+				plan(food, month(2025, 11), "100", true),
+				plan(food, january, "100", true),
+			},
+			want: Report{
+				Lines: []Line{
+					{CategoryID: food, Planned: money.MustParse("100"), Remaining: money.MustParse("100"), Rollover: true},
+				},
+				Planned:   money.MustParse("100"),
+				Remaining: money.MustParse("100"),
+			},
+		},
+		{
+			name:  "sub-category with its own budget",
+			month: january,
+			budgets: []db.Budget{
+				plan(food, january, "100", false),
+				plan(groceries, january, "80", false),
+			},
+			spending: []Spending{
+				spend(groceries, january, "90"),
+				spend(food, january, "5"),
+			},
+			want: Report{
+				Lines: []Line{
+					{CategoryID: food, Planned: money.MustParse("100"), Spent: money.MustParse("5"), Remaining: money.MustParse("95")},
+					{CategoryID: groceries, Planned: money.MustParse("80"), Spent: money.MustParse("90"), Remaining: money.MustParse("-10"), OverBudget: true},
+				},
+				Planned:         money.MustParse("180"),
+				Spent:           money.MustParse("95"),
+				Remaining:       money.MustParse("85"),
+				OverBudgetCount: 1,
+			},
+		},
+		{
+			name:     "no budgets",
+			month:    january,
+			spending: []Spending{spend(fun, january, "20")},
+			want: Report{
+				Lines:      []Line{},
+				Unbudgeted: money.MustParse("20"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Summarize(test.month, test.budgets, categories, test.spending)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Summarize = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package budget
+
+import (
+	"cashpal/money"
+	"errors"
+	"sort"
+)
+
+const (
+	ModeMonthly  = "monthly"
+	ModeEnvelope = "envelope"
+)
+
+// ToBeAssigned is the key of the pool holding money that was not assigned to
+// any envelope yet. Category ids start at 1, so it never names a category.
+const ToBeAssigned int32 = 0
+
+var ErrInsufficientFunds = errors.New("the envelope does not hold enough money")
+
+// Envelope is the balance of one category in envelope mode.
+type Envelope struct {
+	CategoryID int32        `json:"category_id"`
+	Balance    money.Amount `json:"balance"`
+}
+
+// Envelopes holds the balance of every envelope of an account, keyed by
+// category id, with ToBeAssigned for the pool.
+//
+// Every unit of income lands in the pool and has to be moved to an envelope
+// before it can be spent from there. Spending is taken from the envelope of
+// its category, or from the pool when it has none.
+type Envelopes map[int32]money.Amount
+
+// Post applies a posting of the account in category categoryID, or
+// ToBeAssigned when it is uncategorised.
+func (envelopes Envelopes) Post(categoryID int32, amount money.Amount) {
+	if amount > 0 {
+		categoryID = ToBeAssigned
+	}
+
+	envelopes[categoryID] += amount
+}
+
+// Move takes amount out of one envelope and puts it in another. Unless
+// allowNegative is set, the source has to hold at least amount.
+func (envelopes Envelopes) Move(from int32, to int32, amount money.Amount, allowNegative bool) error {
+	if amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+
+	if from == to {
+		return errors.New("money must move between two different envelopes")
+	}
+
+	if !allowNegative && envelopes[from] < amount {
+		return ErrInsufficientFunds
+	}
+
+	envelopes[from] -= amount
+	envelopes[to] += amount
+
+	return nil
+}
+
+// Overdrawn returns the envelopes that are negative and lower than they are
+// in before, in order. Envelopes that were already overdrawn by the same
+// amount or more are left out.
+func (envelopes Envelopes) Overdrawn(before Envelopes) []int32 {
+	var overdrawn []int32
+
+	for categoryID, balance := range envelopes {
+		if balance < 0 && balance < before[categoryID] {
+			overdrawn = append(overdrawn, categoryID)
+		}
+	}
+
+	sort.Slice(overdrawn, func(i, j int) bool { return overdrawn[i] < overdrawn[j] })
+
+	return overdrawn
+}
+
+// List returns every envelope except the pool, ordered by category.
+func (envelopes Envelopes) List() []Envelope {
+	list := make([]Envelope, 0, len(envelopes))
+
+	for categoryID, balance := range envelopes {
+		if categoryID != ToBeAssigned {
+			list = append(list, Envelope{CategoryID: categoryID, Balance: balance})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CategoryID < list[j].CategoryID })
+
+	return list
+}
@@ -0,0 +1,135 @@
+package budget
+
+import (
+	"cashpal/money"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEnvelopesPost(t *testing.T) {
+	envelopes := Envelopes{}
+
+	envelopes.Post(food, money.MustParse("100"))
+	envelopes.Post(ToBeAssigned, money.MustParse("50"))
+	envelopes.Post(food, money.MustParse("-30"))
+	envelopes.Post(ToBeAssigned, money.MustParse("-5"))
+
+	want := Envelopes{ToBeAssigned: money.MustParse("145"), food: money.MustParse("-30")}
+
+	if !reflect.DeepEqual(envelopes, want) {
+		t.Errorf("envelopes = %v, want %v", envelopes, want)
+	}
+}
+
+func TestEnvelopesMove(t *testing.T) {
+	tests := []struct {
+		name          string
+		from          int32
+		to            int32
+		amount        string
+		allowNegative bool
+		want          Envelopes
+		wantErr       error
+	}{
+		{
+			name:   "assign from the pool",
+			from:   ToBeAssigned,
+			to:     food,
+			amount: "60",
+			want:   Envelopes{ToBeAssigned: money.MustParse("40"), food: money.MustParse("80")},
+		},
+		{
+			name:   "everything an envelope holds",
+			from:   food,
+			to:     rent,
+			amount: "20",
+			want:   Envelopes{ToBeAssigned: money.MustParse("100"), food: 0, rent: money.MustParse("20")},
+		},
+		{
+			name:    "more than an envelope holds",
+			from:    food,
+			to:      rent,
+			amount:  "20.01",
+			wantErr: ErrInsufficientFunds,
+		},
+		{
+			name:          "overdraw when allowed",
+			from:          food,
+			to:            rent,
+			amount:        "25",
+			allowNegative: true,
+			want:          Envelopes{ToBeAssigned: money.MustParse("100"), food: money.MustParse("-5"), rent: money.MustParse("25")},
+		},
+		{
+			name:   "zero amount",
+			from:   ToBeAssigned,
+			to:     food,
+			amount: "0",
+		},
+		{
+			name:   "same envelope",
+			from:   food,
+			to:     food,
+			amount: "1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			envelopes := Envelopes{ToBeAssigned: money.MustParse("100"), food: money.MustParse("20")}
+
+			err := envelopes.Move(test.from, test.to, money.MustParse(test.amount), test.allowNegative)
+
+			if test.want == nil {
+				if err == nil || (test.wantErr != nil && !errors.Is(err, test.wantErr)) {
+					t.Fatalf("err = %v, want %v", err, test.wantErr)
+				}
+
+				test.want = Envelopes{ToBeAssigned: money.MustParse("100"), food: money.MustParse("20")}
+			} else if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			if !reflect.DeepEqual(envelopes, test.want) {
+				t.Errorf("envelopes = %v, want %v", envelopes, test.want)
+			}
+		})
+	}
+}
+
+func TestEnvelopesOverdrawn(t *testing.T) {
+	before := Envelopes{ToBeAssigned: money.MustParse("10"), food: money.MustParse("5"), rent: money.MustParse("-20"), fun: money.MustParse("-1")}
+	after := Envelopes{ToBeAssigned: money.MustParse("-3"), food: money.MustParse("-5"), rent: money.MustParse("-20"), fun: money.MustParse("-2"), groceries: money.MustParse("-1")}
+
+	want := []int32{ToBeAssigned, food, groceries, fun}
+
+	if got := after.Overdrawn(before); !reflect.DeepEqual(got, want) {
+		t.Errorf("Overdrawn = %v, want %v", got, want)
+	}
+
+	if got := before.Overdrawn(before); got != nil {
+		t.Errorf("Overdrawn without changes = %v, want none", got)
+	}
+}
+
+func TestEnvelopesList(t *testing.T) {
+	envelopes := Envelopes{rent: money.MustParse("1"), ToBeAssigned: money.MustParse("2"), food: money.MustParse("3")}
+
+	want := []Envelope{{CategoryID: food, Balance: money.MustParse("3")}, {CategoryID: rent, Balance: money.MustParse("1")}}
+
+	if got := envelopes.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %v, want %v", got, want)
+	}
+}
+
+func TestOverdrawnErrorNamesEnvelopes(t *testing.T) {
+	err := &OverdrawnError{AccountID: 1, CategoryIDs: []int32{ToBeAssigned, food}}
+
+	want := fmt.Sprintf("not enough money in the envelope of to be assigned, category %d", food)
+
+	if got := err.Error(); got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"cashpal/audit"
+	"cashpal/config"
+	"cashpal/database"
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"cashpal/rates"
+	"cashpal/recurrence"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const usage = `usage: cashpal [command]
+
+Without a command the HTTP server is started.
+
+commands:
+  rates import [-format ecb|csv] <file>   import exchange rates from a file
+  trash purge [-days n]                   permanently remove transactions trashed more than n days ago
+  events verify [account id]              check the audit trail of one account, or of every account
+  recurring post                          create the transactions of recurring rules that are due
+  operators add|remove <username>         allow or stop a user changing exchange rates
+`
+
+func runCommand(args []string) int {
+	switch {
+	case len(args) >= 2 && args[0] == "rates" && args[1] == "import":
+		return importRates(args[2:])
+	case len(args) >= 2 && args[0] == "trash" && args[1] == "purge":
+		return purgeTrashCommand(args[2:])
+	case len(args) >= 2 && args[0] == "events" && args[1] == "verify":
+		return verifyEvents(args[2:])
+	case len(args) == 2 && args[0] == "recurring" && args[1] == "post":
+		return postRecurringCommand()
+	case len(args) == 3 && args[0] == "operators" && (args[1] == "add" || args[1] == "remove"):
+		return operatorsCommand(args[1], args[2])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+func importRates(args []string) int {
+	flags := flag.NewFlagSet("rates import", flag.ContinueOnError)
+	format := flags.String("format", "", "file format, ecb or csv (guessed from the file extension by default)")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	path := flags.Arg(0)
+
+	if *format == "" {
+		*format = rates.DetectFormat(path)
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer file.Close()
+
+	exchangeRates, err := rates.Parse(*format, file)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx := context.Background()
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(ctx)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer connClose()
+	defer tx.Rollback(ctx)
+
+	summary, err := rates.Import(ctx, query.WithTx(tx), exchangeRates)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%d added, %d changed, %d unchanged, %d skipped\n", summary.Added, summary.Changed, summary.Unchanged, summary.Skipped)
+
+	return 0
+}
+
+func operatorsCommand(action string, username string) int {
+	ctx := context.Background()
+
+	query, connClose, err := database.GetNewConnection(ctx)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer connClose()
+
+	user, err := query.GetUserByUsername(ctx, username)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "user %s does not exist\n", username)
+		return 1
+	}
+
+	if action == "add" {
+		if err := query.AddOperator(ctx, user.ID); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		fmt.Printf("%s is an operator\n", username)
+
+		return 0
+	}
+
+	removed, err := query.RemoveOperator(ctx, user.ID)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if removed == 0 {
+		fmt.Printf("%s was not an operator\n", username)
+	} else {
+		fmt.Printf("%s is no longer an operator\n", username)
+	}
+
+	return 0
+}
+
+func purgeTrashCommand(args []string) int {
+	flags := flag.NewFlagSet("trash purge", flag.ContinueOnError)
+	days := flags.Int("days", -1, "retention in days (TRASH_RETENTION_DAYS by default)")
+
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	retention := config.GetTrashRetention()
+
+	if *days >= 0 {
+		retention = time.Duration(*days) * 24 * time.Hour
+	}
+
+	purged, err := purgeTrash(retention)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%d transactions purged\n", purged)
+
+	return 0
+}
+
+func purgeTrash(retention time.Duration) (int64, error) {
+	ctx := context.Background()
+
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer connClose()
+	defer tx.Rollback(ctx)
+
+	purged, err := ledger.Purge(ctx, query.WithTx(tx), time.Now().Add(-retention))
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// purgeTrashDaily empties the trash of everything past the retention period
+// once a day while the server is running.
+func purgeTrashDaily() {
+	for {
+		purged, err := purgeTrash(config.GetTrashRetention())
+
+		if err != nil {
+			log.Println(err.Error())
+		} else if purged > 0 {
+			log.Printf("%d trashed transactions purged\n", purged)
+		}
+
+		time.Sleep(24 * time.Hour)
+	}
+}
+
+func verifyEvents(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	ctx := context.Background()
+
+	query, connClose, err := database.GetNewConnection(ctx)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer connClose()
+
+	var accountIDs []int32
+
+	if len(args) == 1 {
+		accountID, err := strconv.ParseInt(args[0], 10, 32)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "account id is invalid or malformed")
+			return 2
+		}
+
+		accountIDs = append(accountIDs, int32(accountID))
+	} else {
+		accounts, err := query.ListAccount(ctx)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		for _, account := range accounts {
+			accountIDs = append(accountIDs, account.ID)
+		}
+	}
+
+	status := 0
+
+	for _, accountID := range accountIDs {
+		verification, err := audit.Verify(ctx, query, accountID)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		if verification.Valid {
+			fmt.Printf("account %d: ok, %d events checked\n", accountID, verification.EventsChecked)
+			continue
+		}
+
+		fmt.Printf("account %d: broken at event %d: %s\n", accountID, *verification.BrokenEventID, verification.Reason)
+		status = 1
+	}
+
+	return status
+}
+
+func postRecurringCommand() int {
+	posted, err := postRecurring(time.Now().UTC())
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%d recurring transactions posted\n", posted)
+
+	return 0
+}
+
+// postRecurring creates the due transactions of every recurring rule, each
+// rule in its own database transaction so one failing rule does not hold
+// back the others.
+func postRecurring(now time.Time) (int, error) {
+	ctx := context.Background()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	query, connClose, err := database.GetNewConnection(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rules, err := query.ListDueRecurringRules(ctx, pgtype.Date{Time: today, Valid: true})
+
+	connClose()
+
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+
+	for _, row := range rules {
+		dates := recurrence.Due(row.RecurringRule, row.LastOccurrenceDate, today)
+
+		if len(dates) == 0 {
+			continue
+		}
+
+		count, err := postRecurringRule(ctx, row.RecurringRule, dates)
+
+		if err != nil {
+			log.Printf("recurring rule %d: %s\n", row.RecurringRule.ID, err.Error())
+			continue
+		}
+
+		posted += count
+	}
+
+	return posted, nil
+}
+
+func postRecurringRule(ctx context.Context, rule db.RecurringRule, dates []time.Time) (int, error) {
+	query, connClose, tx, err := database.GetNewConnectionWithTransaction(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer connClose()
+	defer tx.Rollback(ctx)
+
+	posted, err := recurrence.Post(ctx, query.WithTx(tx), rule, dates)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return posted, nil
+}
+
+// postRecurringHourly keeps posting recurring transactions while the server
+// is running. Occurrences are claimed in the database, so restarts or a
+// second server never post the same one twice.
+func postRecurringHourly() {
+	for {
+		posted, err := postRecurring(time.Now().UTC())
+
+		if err != nil {
+			log.Println(err.Error())
+		} else if posted > 0 {
+			log.Printf("%d recurring transactions posted\n", posted)
+		}
+
+		time.Sleep(time.Hour)
+	}
+}
@@ -5,9 +5,17 @@ import (
 	"cashpal/middleware"
 	"fmt"
 	"net/http"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	go purgeTrashDaily()
+	go postRecurringHourly()
+
 	router := http.NewServeMux()
 
 	api.SetupURLs(router)
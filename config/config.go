@@ -1,11 +1,25 @@
 package config
 
 import (
+	"errors"
+	"io/fs"
+	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// DefaultTrashRetentionDays is how long deleted transactions stay in the
+// trash when TRASH_RETENTION_DAYS is not set.
+const DefaultTrashRetentionDays = 30
+
+// DefaultDuplicateWindowDays is how many days apart two transactions may be
+// dated and still be taken as duplicates when DUPLICATE_WINDOW_DAYS is not
+// set.
+const DefaultDuplicateWindowDays = 3
+
 func GetSecret(key string) string {
 	err := godotenv.Load()
 
@@ -15,3 +29,41 @@ func GetSecret(key string) string {
 
 	return os.Getenv(key)
 }
+
+// getSetting returns an optional setting from the environment, loading the
+// .env file first when there is one. Without the file only the environment
+// is read; a file that cannot be parsed is logged and left out.
+func getSetting(key string) string {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Println(err.Error())
+	}
+
+	return os.Getenv(key)
+}
+
+// getDays reads an optional number of days, falling back to defaultDays
+// when the setting is missing, malformed or negative.
+func getDays(key string, defaultDays int) int {
+	days, err := strconv.Atoi(getSetting(key))
+
+	if err != nil || days < 0 {
+		return defaultDays
+	}
+
+	return days
+}
+
+// GetTrashRetention returns how long deleted transactions are kept before
+// they are purged, read from TRASH_RETENTION_DAYS.
+func GetTrashRetention() time.Duration {
+	days := getDays("TRASH_RETENTION_DAYS", DefaultTrashRetentionDays)
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GetDuplicateWindow returns how many days before or after a transaction
+// another one may be dated and still be a duplicate of it, read from
+// DUPLICATE_WINDOW_DAYS. Handlers read it once per request and pass it on.
+func GetDuplicateWindow() int {
+	return getDays("DUPLICATE_WINDOW_DAYS", DefaultDuplicateWindowDays)
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetDays(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 7},
+		{"12", 12},
+		{"0", 0},
+		{"-1", 7},
+		{"two", 7},
+	}
+
+	for _, test := range tests {
+		t.Setenv("CASHPAL_TEST_DAYS", test.value)
+
+		if got := getDays("CASHPAL_TEST_DAYS", 7); got != test.want {
+			t.Errorf("getDays with %q = %d, want %d", test.value, got, test.want)
+		}
+	}
+}
+
+// There is no .env file next to the tests, which must not stop optional
+// settings from falling back to their defaults.
+func TestOptionalSettingsWithoutEnvFile(t *testing.T) {
+	t.Setenv("TRASH_RETENTION_DAYS", "")
+	t.Setenv("DUPLICATE_WINDOW_DAYS", "")
+
+	if got := GetTrashRetention(); got != DefaultTrashRetentionDays*24*time.Hour {
+		t.Errorf("GetTrashRetention = %s, want %d days", got, DefaultTrashRetentionDays)
+	}
+
+	if got := GetDuplicateWindow(); got != DefaultDuplicateWindowDays {
+		t.Errorf("GetDuplicateWindow = %d, want %d", got, DefaultDuplicateWindowDays)
+	}
+}
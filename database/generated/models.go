@@ -5,15 +5,31 @@
 package db
 
 import (
+	"encoding/json"
+
+	"cashpal/money"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
-	ID          int32            `json:"id"`
-	AccountName string           `json:"account_name"`
-	AccountType string           `json:"account_type"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	ID                     int32            `json:"id"`
+	AccountName            string           `json:"account_name"`
+	AccountType            string           `json:"account_type"`
+	CreatedAt              pgtype.Timestamp `json:"created_at"`
+	UpdatedAt              pgtype.Timestamp `json:"updated_at"`
+	Currency               string           `json:"currency"`
+	ArchivedAt             pgtype.Timestamp `json:"archived_at"`
+	BudgetingMode          string           `json:"budgeting_mode"`
+	AllowNegativeEnvelopes bool             `json:"allow_negative_envelopes"`
+}
+
+type AccountDeletion struct {
+	ID               int32            `json:"id"`
+	AccountID        int32            `json:"account_id"`
+	AccountName      string           `json:"account_name"`
+	DeletedBy        int32            `json:"deleted_by"`
+	TransactionCount int32            `json:"transaction_count"`
+	DeletedAt        pgtype.Timestamp `json:"deleted_at"`
 }
 
 type AccountEvent struct {
@@ -23,6 +39,41 @@ type AccountEvent struct {
 	Description string           `json:"description"`
 	CreatedAt   pgtype.Timestamp `json:"created_at"`
 	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	UserID      pgtype.Int4      `json:"user_id"`
+	Diff        json.RawMessage  `json:"diff"`
+	PrevHash    pgtype.Text      `json:"prev_hash"`
+	Hash        string           `json:"hash"`
+}
+
+type Budget struct {
+	ID         int32            `json:"id"`
+	AccountID  int32            `json:"account_id"`
+	CategoryID int32            `json:"category_id"`
+	Month      pgtype.Date      `json:"month"`
+	Amount     money.Amount     `json:"amount"`
+	Rollover   bool             `json:"rollover"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
+}
+
+type Category struct {
+	ID        int32            `json:"id"`
+	AccountID int32            `json:"account_id"`
+	ParentID  pgtype.Int4      `json:"parent_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type EnvelopeMove struct {
+	ID             int32            `json:"id"`
+	AccountID      int32            `json:"account_id"`
+	UserID         int32            `json:"user_id"`
+	FromCategoryID pgtype.Int4      `json:"from_category_id"`
+	ToCategoryID   pgtype.Int4      `json:"to_category_id"`
+	Amount         money.Amount     `json:"amount"`
+	Note           string           `json:"note"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
 }
 
 type EventType struct {
@@ -30,6 +81,62 @@ type EventType struct {
 	Name string `json:"name"`
 }
 
+type ExchangeRate struct {
+	ID            int32            `json:"id"`
+	RateDate      pgtype.Date      `json:"rate_date"`
+	BaseCurrency  string           `json:"base_currency"`
+	QuoteCurrency string           `json:"quote_currency"`
+	Rate          money.Rate       `json:"rate"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+}
+
+type Goal struct {
+	ID           int32            `json:"id"`
+	AccountID    int32            `json:"account_id"`
+	Name         string           `json:"name"`
+	TargetAmount money.Amount     `json:"target_amount"`
+	TargetDate   pgtype.Date      `json:"target_date"`
+	CategoryID   pgtype.Int4      `json:"category_id"`
+	StartDate    pgtype.Date      `json:"start_date"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
+type ImportProfile struct {
+	ID                 int32            `json:"id"`
+	AccountID          int32            `json:"account_id"`
+	Name               string           `json:"name"`
+	Delimiter          string           `json:"delimiter"`
+	SkipRows           int32            `json:"skip_rows"`
+	DateColumn         int32            `json:"date_column"`
+	DateFormat         string           `json:"date_format"`
+	DescriptionColumns []int32          `json:"description_columns"`
+	AmountColumn       pgtype.Int4      `json:"amount_column"`
+	DebitColumn        pgtype.Int4      `json:"debit_column"`
+	CreditColumn       pgtype.Int4      `json:"credit_column"`
+	DecimalSeparator   string           `json:"decimal_separator"`
+	NegateAmounts      bool             `json:"negate_amounts"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+}
+
+type JournalEntry struct {
+	ID          int32            `json:"id"`
+	EntryDate   pgtype.Date      `json:"entry_date"`
+	Description string           `json:"description"`
+	UserID      int32            `json:"user_id"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	DeletedAt   pgtype.Timestamp `json:"deleted_at"`
+}
+
+type LedgerAccount struct {
+	ID        int32            `json:"id"`
+	AccountID int32            `json:"account_id"`
+	Kind      string           `json:"kind"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
 type Member struct {
 	ID           int32            `json:"id"`
 	AccountID    int32            `json:"account_id"`
@@ -44,27 +151,140 @@ type MemberRole struct {
 	Name string `json:"name"`
 }
 
+type Operator struct {
+	UserID    int32            `json:"user_id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type Posting struct {
+	ID              int32            `json:"id"`
+	JournalEntryID  int32            `json:"journal_entry_id"`
+	TransactionID   pgtype.Int4      `json:"transaction_id"`
+	Currency        string           `json:"currency"`
+	Amount          money.Amount     `json:"amount"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	LedgerAccountID int32            `json:"ledger_account_id"`
+	CategoryID      pgtype.Int4      `json:"category_id"`
+	Memo            string           `json:"memo"`
+}
+
+type RecurringOccurrence struct {
+	ID             int32            `json:"id"`
+	RuleID         int32            `json:"rule_id"`
+	OccurrenceDate pgtype.Date      `json:"occurrence_date"`
+	Status         string           `json:"status"`
+	TransactionID  pgtype.Int4      `json:"transaction_id"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+}
+
+type RecurringRule struct {
+	ID                int32            `json:"id"`
+	AccountID         int32            `json:"account_id"`
+	UserID            int32            `json:"user_id"`
+	TransactionTypeID int32            `json:"transaction_type_id"`
+	Amount            money.Amount     `json:"amount"`
+	Currency          pgtype.Text      `json:"currency"`
+	Description       string           `json:"description"`
+	CategoryID        pgtype.Int4      `json:"category_id"`
+	Frequency         string           `json:"frequency"`
+	IntervalCount     int32            `json:"interval_count"`
+	DayOfMonth        pgtype.Int4      `json:"day_of_month"`
+	StartDate         pgtype.Date      `json:"start_date"`
+	EndDate           pgtype.Date      `json:"end_date"`
+	OccurrenceCount   pgtype.Int4      `json:"occurrence_count"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+}
+
+type Tag struct {
+	ID        int32            `json:"id"`
+	AccountID int32            `json:"account_id"`
+	Name      string           `json:"name"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
 type Transaction struct {
 	ID                int32            `json:"id"`
 	AccountID         int32            `json:"account_id"`
 	UserID            int32            `json:"user_id"`
 	TransactionDate   pgtype.Date      `json:"transaction_date"`
 	TransactionTypeID int32            `json:"transaction_type_id"`
-	Amount            float64          `json:"amount"`
+	Amount            money.Amount     `json:"amount"`
 	CreatedAt         pgtype.Timestamp `json:"created_at"`
 	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
 	Description       string           `json:"description"`
+	Currency          pgtype.Text      `json:"currency"`
+	TransferID        pgtype.Int4      `json:"transfer_id"`
+	JournalEntryID    int32            `json:"journal_entry_id"`
+	CategoryID        pgtype.Int4      `json:"category_id"`
+	DeletedAt         pgtype.Timestamp `json:"deleted_at"`
+	ExternalID        pgtype.Text      `json:"external_id"`
+	Metadata          json.RawMessage  `json:"metadata"`
+}
+
+type TransactionHeader struct {
+	ID                int32            `json:"id"`
+	AccountID         int32            `json:"account_id"`
+	UserID            int32            `json:"user_id"`
+	TransactionDate   pgtype.Date      `json:"transaction_date"`
+	TransactionTypeID int32            `json:"transaction_type_id"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	Description       string           `json:"description"`
+	Currency          pgtype.Text      `json:"currency"`
+	TransferID        pgtype.Int4      `json:"transfer_id"`
+	JournalEntryID    int32            `json:"journal_entry_id"`
+	DeletedAt         pgtype.Timestamp `json:"deleted_at"`
+	ExternalID        pgtype.Text      `json:"external_id"`
+	Metadata          json.RawMessage  `json:"metadata"`
+}
+
+type TransactionShare struct {
+	ID            int32            `json:"id"`
+	TransactionID int32            `json:"transaction_id"`
+	UserID        int32            `json:"user_id"`
+	Method        string           `json:"method"`
+	Value         money.NullAmount `json:"value"`
+	Amount        money.Amount     `json:"amount"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+}
+
+type TransactionSplit struct {
+	ID            int32            `json:"id"`
+	TransactionID int32            `json:"transaction_id"`
+	CategoryID    pgtype.Int4      `json:"category_id"`
+	Amount        money.Amount     `json:"amount"`
+	Memo          string           `json:"memo"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+}
+
+type TransactionTag struct {
+	TransactionID int32            `json:"transaction_id"`
+	TagID         int32            `json:"tag_id"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
 }
 
 type TransactionType struct {
 	ID   int32  `json:"id"`
 	Name string `json:"name"`
+	Sign int32  `json:"sign"`
+}
+
+type Transfer struct {
+	ID            int32            `json:"id"`
+	FromAccountID int32            `json:"from_account_id"`
+	ToAccountID   int32            `json:"to_account_id"`
+	UserID        int32            `json:"user_id"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
 }
 
 type User struct {
-	ID        int32            `json:"id"`
-	Username  string           `json:"username"`
-	Password  string           `json:"password"`
-	CreatedAt pgtype.Timestamp `json:"created_at"`
-	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	ID                int32            `json:"id"`
+	Username          string           `json:"username"`
+	Password          string           `json:"password"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+	UpdatedAt         pgtype.Timestamp `json:"updated_at"`
+	ReportingCurrency string           `json:"reporting_currency"`
 }
@@ -7,26 +7,148 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 
+	"cashpal/money"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addOperator = `-- name: AddOperator :exec
+INSERT INTO Operators (user_id)
+VALUES ($1)
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) AddOperator(ctx context.Context, userID int32) error {
+	_, err := q.db.Exec(ctx, addOperator, userID)
+	return err
+}
+
+const addTransactionTag = `-- name: AddTransactionTag :exec
+INSERT INTO Transaction_Tags (
+  transaction_id, tag_id
+) VALUES (
+  $1, $2
+)
+ON CONFLICT DO NOTHING
+`
+
+type AddTransactionTagParams struct {
+	TransactionID int32 `json:"transaction_id"`
+	TagID         int32 `json:"tag_id"`
+}
+
+func (q *Queries) AddTransactionTag(ctx context.Context, arg AddTransactionTagParams) error {
+	_, err := q.db.Exec(ctx, addTransactionTag, arg.TransactionID, arg.TagID)
+	return err
+}
+
+const archiveAccount = `-- name: ArchiveAccount :one
+UPDATE Accounts
+  set archived_at = COALESCE(archived_at, NOW() AT TIME ZONE 'utc'), updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+RETURNING id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes
+`
+
+func (q *Queries) ArchiveAccount(ctx context.Context, id int32) (Account, error) {
+	row := q.db.QueryRow(ctx, archiveAccount, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.AccountName,
+		&i.AccountType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
+	)
+	return i, err
+}
+
+const claimRecurringOccurrence = `-- name: ClaimRecurringOccurrence :one
+INSERT INTO Recurring_Occurrences (
+  rule_id, occurrence_date, status
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (rule_id, occurrence_date) DO NOTHING
+RETURNING id, rule_id, occurrence_date, status, transaction_id, created_at
+`
+
+type ClaimRecurringOccurrenceParams struct {
+	RuleID         int32       `json:"rule_id"`
+	OccurrenceDate pgtype.Date `json:"occurrence_date"`
+	Status         string      `json:"status"`
+}
+
+func (q *Queries) ClaimRecurringOccurrence(ctx context.Context, arg ClaimRecurringOccurrenceParams) (RecurringOccurrence, error) {
+	row := q.db.QueryRow(ctx, claimRecurringOccurrence, arg.RuleID, arg.OccurrenceDate, arg.Status)
+	var i RecurringOccurrence
+	err := row.Scan(
+		&i.ID,
+		&i.RuleID,
+		&i.OccurrenceDate,
+		&i.Status,
+		&i.TransactionID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const clearTransactionExternalID = `-- name: ClearTransactionExternalID :exec
+UPDATE Transaction_Headers
+SET external_id = NULL
+WHERE id = $1;
+`
+
+func (q *Queries) ClearTransactionExternalID(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, clearTransactionExternalID, id)
+	return err
+}
+
+const countCategoryChildren = `-- name: CountCategoryChildren :one
+SELECT COUNT(*) FROM Categories
+WHERE parent_id = $1
+`
+
+func (q *Queries) CountCategoryChildren(ctx context.Context, parentID pgtype.Int4) (int64, error) {
+	row := q.db.QueryRow(ctx, countCategoryChildren, parentID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCategoryEnvelopeMoves = `-- name: CountCategoryEnvelopeMoves :one
+SELECT COUNT(*) FROM Envelope_Moves
+WHERE from_category_id = $1 OR to_category_id = $1
+`
+
+func (q *Queries) CountCategoryEnvelopeMoves(ctx context.Context, fromCategoryID pgtype.Int4) (int64, error) {
+	row := q.db.QueryRow(ctx, countCategoryEnvelopeMoves, fromCategoryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO Accounts (
-  account_name, account_type
+  account_name, account_type, currency
 ) VALUES (
-  $1, $2
+  $1, $2, $3
 )
-RETURNING id, account_name, account_type, created_at, updated_at
+RETURNING id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes
 `
 
 type CreateAccountParams struct {
 	AccountName string `json:"account_name"`
 	AccountType string `json:"account_type"`
+	Currency    string `json:"currency"`
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRow(ctx, createAccount, arg.AccountName, arg.AccountType)
+	row := q.db.QueryRow(ctx, createAccount, arg.AccountName, arg.AccountType, arg.Currency)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -34,27 +156,74 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.AccountType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
+	)
+	return i, err
+}
+
+const createAccountDeletion = `-- name: CreateAccountDeletion :one
+INSERT INTO Account_Deletions (
+  account_id, account_name, deleted_by, transaction_count
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING id, account_id, account_name, deleted_by, transaction_count, deleted_at
+`
+
+type CreateAccountDeletionParams struct {
+	AccountID        int32  `json:"account_id"`
+	AccountName      string `json:"account_name"`
+	DeletedBy        int32  `json:"deleted_by"`
+	TransactionCount int32  `json:"transaction_count"`
+}
+
+func (q *Queries) CreateAccountDeletion(ctx context.Context, arg CreateAccountDeletionParams) (AccountDeletion, error) {
+	row := q.db.QueryRow(ctx, createAccountDeletion,
+		arg.AccountID,
+		arg.AccountName,
+		arg.DeletedBy,
+		arg.TransactionCount,
+	)
+	var i AccountDeletion
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.AccountName,
+		&i.DeletedBy,
+		&i.TransactionCount,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const createAccountEvent = `-- name: CreateAccountEvent :one
 INSERT INTO Account_Events (
-  account_id, event_type_id, description
+  account_id, event_type_id, description, user_id, diff
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 )
-RETURNING id, account_id, event_type_id, description, created_at, updated_at
+RETURNING id, account_id, event_type_id, description, created_at, updated_at, user_id, diff, prev_hash, hash
 `
 
 type CreateAccountEventParams struct {
-	AccountID   int32  `json:"account_id"`
-	EventTypeID int32  `json:"event_type_id"`
-	Description string `json:"description"`
+	AccountID   int32           `json:"account_id"`
+	EventTypeID int32           `json:"event_type_id"`
+	Description string          `json:"description"`
+	UserID      pgtype.Int4     `json:"user_id"`
+	Diff        json.RawMessage `json:"diff"`
 }
 
 func (q *Queries) CreateAccountEvent(ctx context.Context, arg CreateAccountEventParams) (AccountEvent, error) {
-	row := q.db.QueryRow(ctx, createAccountEvent, arg.AccountID, arg.EventTypeID, arg.Description)
+	row := q.db.QueryRow(ctx, createAccountEvent,
+		arg.AccountID,
+		arg.EventTypeID,
+		arg.Description,
+		arg.UserID,
+		arg.Diff,
+	)
 	var i AccountEvent
 	err := row.Scan(
 		&i.ID,
@@ -63,6 +232,212 @@ func (q *Queries) CreateAccountEvent(ctx context.Context, arg CreateAccountEvent
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.UserID,
+		&i.Diff,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
+const createCategory = `-- name: CreateCategory :one
+INSERT INTO Categories (
+  account_id, parent_id, name
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, account_id, parent_id, name, created_at, updated_at
+`
+
+type CreateCategoryParams struct {
+	AccountID int32       `json:"account_id"`
+	ParentID  pgtype.Int4 `json:"parent_id"`
+	Name      string      `json:"name"`
+}
+
+func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, createCategory, arg.AccountID, arg.ParentID, arg.Name)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ParentID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createEnvelopeMove = `-- name: CreateEnvelopeMove :one
+INSERT INTO Envelope_Moves (
+  account_id, user_id, from_category_id, to_category_id, amount, note
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, account_id, user_id, from_category_id, to_category_id, amount, note, created_at
+`
+
+type CreateEnvelopeMoveParams struct {
+	AccountID      int32        `json:"account_id"`
+	UserID         int32        `json:"user_id"`
+	FromCategoryID pgtype.Int4  `json:"from_category_id"`
+	ToCategoryID   pgtype.Int4  `json:"to_category_id"`
+	Amount         money.Amount `json:"amount"`
+	Note           string       `json:"note"`
+}
+
+func (q *Queries) CreateEnvelopeMove(ctx context.Context, arg CreateEnvelopeMoveParams) (EnvelopeMove, error) {
+	row := q.db.QueryRow(ctx, createEnvelopeMove,
+		arg.AccountID,
+		arg.UserID,
+		arg.FromCategoryID,
+		arg.ToCategoryID,
+		arg.Amount,
+		arg.Note,
+	)
+	var i EnvelopeMove
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.UserID,
+		&i.FromCategoryID,
+		&i.ToCategoryID,
+		&i.Amount,
+		&i.Note,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createGoal = `-- name: CreateGoal :one
+INSERT INTO Goals (
+  account_id, name, target_amount, target_date, category_id, start_date
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING id, account_id, name, target_amount, target_date, category_id, start_date, created_at, updated_at
+`
+
+type CreateGoalParams struct {
+	AccountID    int32        `json:"account_id"`
+	Name         string       `json:"name"`
+	TargetAmount money.Amount `json:"target_amount"`
+	TargetDate   pgtype.Date  `json:"target_date"`
+	CategoryID   pgtype.Int4  `json:"category_id"`
+	StartDate    pgtype.Date  `json:"start_date"`
+}
+
+func (q *Queries) CreateGoal(ctx context.Context, arg CreateGoalParams) (Goal, error) {
+	row := q.db.QueryRow(ctx, createGoal,
+		arg.AccountID,
+		arg.Name,
+		arg.TargetAmount,
+		arg.TargetDate,
+		arg.CategoryID,
+		arg.StartDate,
+	)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.TargetDate,
+		&i.CategoryID,
+		&i.StartDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createImportProfile = `-- name: CreateImportProfile :one
+INSERT INTO Import_Profiles (
+  account_id, name, delimiter, skip_rows, date_column, date_format, description_columns,
+  amount_column, debit_column, credit_column, decimal_separator, negate_amounts
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING id, account_id, name, delimiter, skip_rows, date_column, date_format, description_columns, amount_column, debit_column, credit_column, decimal_separator, negate_amounts, created_at
+`
+
+type CreateImportProfileParams struct {
+	AccountID          int32       `json:"account_id"`
+	Name               string      `json:"name"`
+	Delimiter          string      `json:"delimiter"`
+	SkipRows           int32       `json:"skip_rows"`
+	DateColumn         int32       `json:"date_column"`
+	DateFormat         string      `json:"date_format"`
+	DescriptionColumns []int32     `json:"description_columns"`
+	AmountColumn       pgtype.Int4 `json:"amount_column"`
+	DebitColumn        pgtype.Int4 `json:"debit_column"`
+	CreditColumn       pgtype.Int4 `json:"credit_column"`
+	DecimalSeparator   string      `json:"decimal_separator"`
+	NegateAmounts      bool        `json:"negate_amounts"`
+}
+
+func (q *Queries) CreateImportProfile(ctx context.Context, arg CreateImportProfileParams) (ImportProfile, error) {
+	row := q.db.QueryRow(ctx, createImportProfile,
+		arg.AccountID,
+		arg.Name,
+		arg.Delimiter,
+		arg.SkipRows,
+		arg.DateColumn,
+		arg.DateFormat,
+		arg.DescriptionColumns,
+		arg.AmountColumn,
+		arg.DebitColumn,
+		arg.CreditColumn,
+		arg.DecimalSeparator,
+		arg.NegateAmounts,
+	)
+	var i ImportProfile
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.Delimiter,
+		&i.SkipRows,
+		&i.DateColumn,
+		&i.DateFormat,
+		&i.DescriptionColumns,
+		&i.AmountColumn,
+		&i.DebitColumn,
+		&i.CreditColumn,
+		&i.DecimalSeparator,
+		&i.NegateAmounts,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createJournalEntry = `-- name: CreateJournalEntry :one
+INSERT INTO Journal_Entries (
+  entry_date, description, user_id
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, entry_date, description, user_id, created_at, updated_at, deleted_at
+`
+
+type CreateJournalEntryParams struct {
+	EntryDate   pgtype.Date `json:"entry_date"`
+	Description string      `json:"description"`
+	UserID      int32       `json:"user_id"`
+}
+
+func (q *Queries) CreateJournalEntry(ctx context.Context, arg CreateJournalEntryParams) (JournalEntry, error) {
+	row := q.db.QueryRow(ctx, createJournalEntry, arg.EntryDate, arg.Description, arg.UserID)
+	var i JournalEntry
+	err := row.Scan(
+		&i.ID,
+		&i.EntryDate,
+		&i.Description,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -97,49 +472,236 @@ func (q *Queries) CreateMember(ctx context.Context, arg CreateMemberParams) (Mem
 	return i, err
 }
 
-const createTransaction = `-- name: CreateTransaction :one
+const createPosting = `-- name: CreatePosting :one
+INSERT INTO Postings (
+  journal_entry_id, ledger_account_id, transaction_id, currency, amount, category_id, memo
+)
+SELECT $1::int, la.id, $2::int, $3::text, $4::numeric, $5::int, $6::text
+FROM Ledger_Accounts AS la
+WHERE la.account_id = $7::int AND la.kind = $8::text
+RETURNING id, journal_entry_id, transaction_id, currency, amount, created_at, ledger_account_id, category_id, memo
+`
+
+type CreatePostingParams struct {
+	JournalEntryID int32        `json:"journal_entry_id"`
+	TransactionID  int32        `json:"transaction_id"`
+	Currency       string       `json:"currency"`
+	Amount         money.Amount `json:"amount"`
+	CategoryID     pgtype.Int4  `json:"category_id"`
+	Memo           string       `json:"memo"`
+	AccountID      int32        `json:"account_id"`
+	Kind           string       `json:"kind"`
+}
+
+func (q *Queries) CreatePosting(ctx context.Context, arg CreatePostingParams) (Posting, error) {
+	row := q.db.QueryRow(ctx, createPosting,
+		arg.JournalEntryID,
+		arg.TransactionID,
+		arg.Currency,
+		arg.Amount,
+		arg.CategoryID,
+		arg.Memo,
+		arg.AccountID,
+		arg.Kind,
+	)
+	var i Posting
+	err := row.Scan(
+		&i.ID,
+		&i.JournalEntryID,
+		&i.TransactionID,
+		&i.Currency,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.LedgerAccountID,
+		&i.CategoryID,
+		&i.Memo,
+	)
+	return i, err
+}
+
+const createRecurringRule = `-- name: CreateRecurringRule :one
+INSERT INTO Recurring_Rules (
+  account_id, user_id, transaction_type_id, amount, currency, description, category_id,
+  frequency, interval_count, day_of_month, start_date, end_date, occurrence_count
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+RETURNING id, account_id, user_id, transaction_type_id, amount, currency, description, category_id, frequency, interval_count, day_of_month, start_date, end_date, occurrence_count, created_at, updated_at
+`
+
+type CreateRecurringRuleParams struct {
+	AccountID         int32        `json:"account_id"`
+	UserID            int32        `json:"user_id"`
+	TransactionTypeID int32        `json:"transaction_type_id"`
+	Amount            money.Amount `json:"amount"`
+	Currency          pgtype.Text  `json:"currency"`
+	Description       string       `json:"description"`
+	CategoryID        pgtype.Int4  `json:"category_id"`
+	Frequency         string       `json:"frequency"`
+	IntervalCount     int32        `json:"interval_count"`
+	DayOfMonth        pgtype.Int4  `json:"day_of_month"`
+	StartDate         pgtype.Date  `json:"start_date"`
+	EndDate           pgtype.Date  `json:"end_date"`
+	OccurrenceCount   pgtype.Int4  `json:"occurrence_count"`
+}
+
+func (q *Queries) CreateRecurringRule(ctx context.Context, arg CreateRecurringRuleParams) (RecurringRule, error) {
+	row := q.db.QueryRow(ctx, createRecurringRule,
+		arg.AccountID,
+		arg.UserID,
+		arg.TransactionTypeID,
+		arg.Amount,
+		arg.Currency,
+		arg.Description,
+		arg.CategoryID,
+		arg.Frequency,
+		arg.IntervalCount,
+		arg.DayOfMonth,
+		arg.StartDate,
+		arg.EndDate,
+		arg.OccurrenceCount,
+	)
+	var i RecurringRule
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.UserID,
+		&i.TransactionTypeID,
+		&i.Amount,
+		&i.Currency,
+		&i.Description,
+		&i.CategoryID,
+		&i.Frequency,
+		&i.IntervalCount,
+		&i.DayOfMonth,
+		&i.StartDate,
+		&i.EndDate,
+		&i.OccurrenceCount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
 
-INSERT INTO Transactions (
-  account_id, user_id, transaction_date, transaction_type_id, amount, description
+const createTransaction = `-- name: CreateTransaction :one
+INSERT INTO Transaction_Headers (
+  account_id, user_id, transaction_date, transaction_type_id, description, currency, transfer_id, journal_entry_id, external_id, metadata
 )
 VALUES(
-  $1, $2, $3, $4, $5, $6
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::jsonb, '{}')
 )
-returning id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description
+returning id, account_id, user_id, transaction_date, transaction_type_id, created_at, updated_at, description, currency, transfer_id, journal_entry_id, deleted_at, external_id, metadata
 `
 
 type CreateTransactionParams struct {
-	AccountID         int32       `json:"account_id"`
-	UserID            int32       `json:"user_id"`
-	TransactionDate   pgtype.Date `json:"transaction_date"`
-	TransactionTypeID int32       `json:"transaction_type_id"`
-	Amount            float64     `json:"amount"`
-	Description       string      `json:"description"`
-}
-
-// SELECT * FROM Transactions
-// WHERE account_id = $1
-// ORDER BY id;
-func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error) {
+	AccountID         int32           `json:"account_id"`
+	UserID            int32           `json:"user_id"`
+	TransactionDate   pgtype.Date     `json:"transaction_date"`
+	TransactionTypeID int32           `json:"transaction_type_id"`
+	Description       string          `json:"description"`
+	Currency          pgtype.Text     `json:"currency"`
+	TransferID        pgtype.Int4     `json:"transfer_id"`
+	JournalEntryID    int32           `json:"journal_entry_id"`
+	ExternalID        pgtype.Text     `json:"external_id"`
+	Metadata          json.RawMessage `json:"metadata"`
+}
+
+func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionParams) (TransactionHeader, error) {
 	row := q.db.QueryRow(ctx, createTransaction,
 		arg.AccountID,
 		arg.UserID,
 		arg.TransactionDate,
 		arg.TransactionTypeID,
-		arg.Amount,
 		arg.Description,
+		arg.Currency,
+		arg.TransferID,
+		arg.JournalEntryID,
+		arg.ExternalID,
+		arg.Metadata,
 	)
-	var i Transaction
+	var i TransactionHeader
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.UserID,
 		&i.TransactionDate,
 		&i.TransactionTypeID,
-		&i.Amount,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Description,
+		&i.Currency,
+		&i.TransferID,
+		&i.JournalEntryID,
+		&i.DeletedAt,
+		&i.ExternalID,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const createTransactionShare = `-- name: CreateTransactionShare :one
+INSERT INTO Transaction_Shares (
+  transaction_id, user_id, method, value, amount
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING id, transaction_id, user_id, method, value, amount, created_at
+`
+
+type CreateTransactionShareParams struct {
+	TransactionID int32            `json:"transaction_id"`
+	UserID        int32            `json:"user_id"`
+	Method        string           `json:"method"`
+	Value         money.NullAmount `json:"value"`
+	Amount        money.Amount     `json:"amount"`
+}
+
+func (q *Queries) CreateTransactionShare(ctx context.Context, arg CreateTransactionShareParams) (TransactionShare, error) {
+	row := q.db.QueryRow(ctx, createTransactionShare,
+		arg.TransactionID,
+		arg.UserID,
+		arg.Method,
+		arg.Value,
+		arg.Amount,
+	)
+	var i TransactionShare
+	err := row.Scan(
+		&i.ID,
+		&i.TransactionID,
+		&i.UserID,
+		&i.Method,
+		&i.Value,
+		&i.Amount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createTransfer = `-- name: CreateTransfer :one
+INSERT INTO Transfers (
+  from_account_id, to_account_id, user_id
+) VALUES (
+  $1, $2, $3
+)
+RETURNING id, from_account_id, to_account_id, user_id, created_at, updated_at
+`
+
+type CreateTransferParams struct {
+	FromAccountID int32 `json:"from_account_id"`
+	ToAccountID   int32 `json:"to_account_id"`
+	UserID        int32 `json:"user_id"`
+}
+
+func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.UserID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
@@ -150,7 +712,7 @@ INSERT INTO Users (
 ) VALUES (
   $1, $2
 )
-RETURNING id, username, password, created_at, updated_at
+RETURNING id, username, password, created_at, updated_at, reporting_currency
 `
 
 type CreateUserParams struct {
@@ -167,6 +729,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ReportingCurrency,
 	)
 	return i, err
 }
@@ -181,19 +744,99 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int32) error {
 	return err
 }
 
-const deleteAccountEvent = `-- name: DeleteAccountEvent :exec
+const deleteAccountEventsByAccount = `-- name: DeleteAccountEventsByAccount :exec
 DELETE FROM Account_Events
-WHERE id = $1
+WHERE account_id = $1
 `
 
-func (q *Queries) DeleteAccountEvent(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteAccountEvent, id)
+func (q *Queries) DeleteAccountEventsByAccount(ctx context.Context, accountID int32) error {
+	_, err := q.db.Exec(ctx, deleteAccountEventsByAccount, accountID)
 	return err
 }
 
-const deleteMember = `-- name: DeleteMember :exec
-DELETE FROM Members
-WHERE account_id = $1 and user_id = $2
+const deleteBudget = `-- name: DeleteBudget :execrows
+DELETE FROM Budgets
+WHERE account_id = $1 AND category_id = $2 AND month = $3
+`
+
+type DeleteBudgetParams struct {
+	AccountID  int32       `json:"account_id"`
+	CategoryID int32       `json:"category_id"`
+	Month      pgtype.Date `json:"month"`
+}
+
+func (q *Queries) DeleteBudget(ctx context.Context, arg DeleteBudgetParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteBudget, arg.AccountID, arg.CategoryID, arg.Month)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteCategory = `-- name: DeleteCategory :exec
+DELETE FROM Categories
+WHERE id = $1 AND account_id = $2
+`
+
+type DeleteCategoryParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) DeleteCategory(ctx context.Context, arg DeleteCategoryParams) error {
+	_, err := q.db.Exec(ctx, deleteCategory, arg.ID, arg.AccountID)
+	return err
+}
+
+const deleteGoal = `-- name: DeleteGoal :execrows
+DELETE FROM Goals
+WHERE id = $1 AND account_id = $2
+`
+
+type DeleteGoalParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) DeleteGoal(ctx context.Context, arg DeleteGoalParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteGoal, arg.ID, arg.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteImportProfile = `-- name: DeleteImportProfile :execrows
+DELETE FROM Import_Profiles
+WHERE id = $1 AND account_id = $2
+`
+
+type DeleteImportProfileParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) DeleteImportProfile(ctx context.Context, arg DeleteImportProfileParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteImportProfile, arg.ID, arg.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteJournalEntry = `-- name: DeleteJournalEntry :exec
+DELETE FROM Journal_Entries
+WHERE id = $1
+`
+
+func (q *Queries) DeleteJournalEntry(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteJournalEntry, id)
+	return err
+}
+
+const deleteMember = `-- name: DeleteMember :exec
+DELETE FROM Members
+WHERE account_id = $1 and user_id = $2
 `
 
 type DeleteMemberParams struct {
@@ -206,6 +849,88 @@ func (q *Queries) DeleteMember(ctx context.Context, arg DeleteMemberParams) erro
 	return err
 }
 
+const deleteMembersByAccount = `-- name: DeleteMembersByAccount :exec
+DELETE FROM Members
+WHERE account_id = $1
+`
+
+func (q *Queries) DeleteMembersByAccount(ctx context.Context, accountID int32) error {
+	_, err := q.db.Exec(ctx, deleteMembersByAccount, accountID)
+	return err
+}
+
+const deleteOrphanTransfers = `-- name: DeleteOrphanTransfers :exec
+DELETE FROM Transfers AS tr
+WHERE NOT EXISTS (
+	SELECT 1
+	FROM Transaction_Headers AS t
+	WHERE t.transfer_id = tr.id
+)
+`
+
+func (q *Queries) DeleteOrphanTransfers(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, deleteOrphanTransfers)
+	return err
+}
+
+const deletePostingsByJournalEntry = `-- name: DeletePostingsByJournalEntry :exec
+DELETE FROM Postings
+WHERE journal_entry_id = $1
+`
+
+func (q *Queries) DeletePostingsByJournalEntry(ctx context.Context, journalEntryID int32) error {
+	_, err := q.db.Exec(ctx, deletePostingsByJournalEntry, journalEntryID)
+	return err
+}
+
+const deleteRecurringRule = `-- name: DeleteRecurringRule :exec
+DELETE FROM Recurring_Rules
+WHERE id = $1 AND account_id = $2
+`
+
+type DeleteRecurringRuleParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) DeleteRecurringRule(ctx context.Context, arg DeleteRecurringRuleParams) error {
+	_, err := q.db.Exec(ctx, deleteRecurringRule, arg.ID, arg.AccountID)
+	return err
+}
+
+const deleteTransactionShares = `-- name: DeleteTransactionShares :exec
+DELETE FROM Transaction_Shares
+WHERE transaction_id = $1
+`
+
+func (q *Queries) DeleteTransactionShares(ctx context.Context, transactionID int32) error {
+	_, err := q.db.Exec(ctx, deleteTransactionShares, transactionID)
+	return err
+}
+
+const deleteTransactionsByAccount = `-- name: DeleteTransactionsByAccount :execrows
+DELETE FROM Transaction_Headers
+WHERE account_id = $1
+`
+
+func (q *Queries) DeleteTransactionsByAccount(ctx context.Context, accountID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTransactionsByAccount, accountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTransfersByAccount = `-- name: DeleteTransfersByAccount :exec
+DELETE FROM Transfers
+WHERE from_account_id = $1 OR to_account_id = $1
+`
+
+func (q *Queries) DeleteTransfersByAccount(ctx context.Context, fromAccountID int32) error {
+	_, err := q.db.Exec(ctx, deleteTransfersByAccount, fromAccountID)
+	return err
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM Users
 WHERE id = $1
@@ -216,9 +941,24 @@ func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
 	return err
 }
 
+const detachTransfersByAccount = `-- name: DetachTransfersByAccount :exec
+UPDATE Transaction_Headers
+  SET transfer_id = NULL
+WHERE transfer_id IN (
+	SELECT id
+	FROM Transfers
+	WHERE from_account_id = $1 OR to_account_id = $1
+)
+`
+
+func (q *Queries) DetachTransfersByAccount(ctx context.Context, fromAccountID int32) error {
+	_, err := q.db.Exec(ctx, detachTransfersByAccount, fromAccountID)
+	return err
+}
+
 const getAccount = `-- name: GetAccount :one
 
-SELECT id, account_name, account_type, created_at, updated_at FROM Accounts
+SELECT id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes FROM Accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -232,13 +972,17 @@ func (q *Queries) GetAccount(ctx context.Context, id int32) (Account, error) {
 		&i.AccountType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
 	)
 	return i, err
 }
 
 const getAccountEvent = `-- name: GetAccountEvent :one
 
-SELECT id, account_id, event_type_id, description, created_at, updated_at FROM Account_Events
+SELECT id, account_id, event_type_id, description, created_at, updated_at, user_id, diff, prev_hash, hash FROM Account_Events
 WHERE id = $1 LIMIT 1
 `
 
@@ -253,13 +997,40 @@ func (q *Queries) GetAccountEvent(ctx context.Context, id int32) (AccountEvent,
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.UserID,
+		&i.Diff,
+		&i.PrevHash,
+		&i.Hash,
+	)
+	return i, err
+}
+
+const getAccountForUpdate = `-- name: GetAccountForUpdate :one
+SELECT id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes FROM Accounts
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetAccountForUpdate(ctx context.Context, id int32) (Account, error) {
+	row := q.db.QueryRow(ctx, getAccountForUpdate, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.AccountName,
+		&i.AccountType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
 	)
 	return i, err
 }
 
 const getAccountWithUserCheck = `-- name: GetAccountWithUserCheck :one
 SELECT 
-    acc.id, acc.account_name, acc.account_type, acc.created_at, acc.updated_at,
+    acc.id, acc.account_name, acc.account_type, acc.created_at, acc.updated_at, acc.currency, acc.archived_at, acc.budgeting_mode, acc.allow_negative_envelopes,
     CASE 
         WHEN mem.user_id IS NOT NULL THEN 1
         ELSE 0
@@ -275,12 +1046,16 @@ type GetAccountWithUserCheckParams struct {
 }
 
 type GetAccountWithUserCheckRow struct {
-	ID          int32            `json:"id"`
-	AccountName string           `json:"account_name"`
-	AccountType string           `json:"account_type"`
-	CreatedAt   pgtype.Timestamp `json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
-	IsMember    int32            `json:"is_member"`
+	ID                     int32            `json:"id"`
+	AccountName            string           `json:"account_name"`
+	AccountType            string           `json:"account_type"`
+	CreatedAt              pgtype.Timestamp `json:"created_at"`
+	UpdatedAt              pgtype.Timestamp `json:"updated_at"`
+	Currency               string           `json:"currency"`
+	ArchivedAt             pgtype.Timestamp `json:"archived_at"`
+	BudgetingMode          string           `json:"budgeting_mode"`
+	AllowNegativeEnvelopes bool             `json:"allow_negative_envelopes"`
+	IsMember               int32            `json:"is_member"`
 }
 
 func (q *Queries) GetAccountWithUserCheck(ctx context.Context, arg GetAccountWithUserCheckParams) (GetAccountWithUserCheckRow, error) {
@@ -292,11 +1067,130 @@ func (q *Queries) GetAccountWithUserCheck(ctx context.Context, arg GetAccountWit
 		&i.AccountType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
 		&i.IsMember,
 	)
 	return i, err
 }
 
+const getCategory = `-- name: GetCategory :one
+SELECT id, account_id, parent_id, name, created_at, updated_at FROM Categories
+WHERE id = $1 AND account_id = $2 LIMIT 1
+`
+
+type GetCategoryParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) GetCategory(ctx context.Context, arg GetCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, getCategory, arg.ID, arg.AccountID)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.ParentID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getEventTypeByName = `-- name: GetEventTypeByName :one
+SELECT id, name FROM Event_Types
+WHERE name = $1 LIMIT 1
+`
+
+func (q *Queries) GetEventTypeByName(ctx context.Context, name string) (EventType, error) {
+	row := q.db.QueryRow(ctx, getEventTypeByName, name)
+	var i EventType
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const getGoal = `-- name: GetGoal :one
+SELECT id, account_id, name, target_amount, target_date, category_id, start_date, created_at, updated_at FROM Goals
+WHERE id = $1 AND account_id = $2 LIMIT 1
+`
+
+type GetGoalParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) GetGoal(ctx context.Context, arg GetGoalParams) (Goal, error) {
+	row := q.db.QueryRow(ctx, getGoal, arg.ID, arg.AccountID)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.TargetDate,
+		&i.CategoryID,
+		&i.StartDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getImportProfile = `-- name: GetImportProfile :one
+SELECT id, account_id, name, delimiter, skip_rows, date_column, date_format, description_columns, amount_column, debit_column, credit_column, decimal_separator, negate_amounts, created_at FROM Import_Profiles
+WHERE id = $1 AND account_id = $2 LIMIT 1
+`
+
+type GetImportProfileParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) GetImportProfile(ctx context.Context, arg GetImportProfileParams) (ImportProfile, error) {
+	row := q.db.QueryRow(ctx, getImportProfile, arg.ID, arg.AccountID)
+	var i ImportProfile
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.Delimiter,
+		&i.SkipRows,
+		&i.DateColumn,
+		&i.DateFormat,
+		&i.DescriptionColumns,
+		&i.AmountColumn,
+		&i.DebitColumn,
+		&i.CreditColumn,
+		&i.DecimalSeparator,
+		&i.NegateAmounts,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getJournalEntry = `-- name: GetJournalEntry :one
+SELECT id, entry_date, description, user_id, created_at, updated_at, deleted_at FROM Journal_Entries
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetJournalEntry(ctx context.Context, id int32) (JournalEntry, error) {
+	row := q.db.QueryRow(ctx, getJournalEntry, id)
+	var i JournalEntry
+	err := row.Scan(
+		&i.ID,
+		&i.EntryDate,
+		&i.Description,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const getMember = `-- name: GetMember :one
 
 SELECT id, account_id, user_id, member_role_id, created_at, updated_at FROM Members
@@ -351,9 +1245,43 @@ func (q *Queries) GetMemberWithUserCheck(ctx context.Context, arg GetMemberWithU
 	return i, err
 }
 
+const getRecurringRule = `-- name: GetRecurringRule :one
+SELECT id, account_id, user_id, transaction_type_id, amount, currency, description, category_id, frequency, interval_count, day_of_month, start_date, end_date, occurrence_count, created_at, updated_at FROM Recurring_Rules
+WHERE id = $1 AND account_id = $2 LIMIT 1
+`
+
+type GetRecurringRuleParams struct {
+	ID        int32 `json:"id"`
+	AccountID int32 `json:"account_id"`
+}
+
+func (q *Queries) GetRecurringRule(ctx context.Context, arg GetRecurringRuleParams) (RecurringRule, error) {
+	row := q.db.QueryRow(ctx, getRecurringRule, arg.ID, arg.AccountID)
+	var i RecurringRule
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.UserID,
+		&i.TransactionTypeID,
+		&i.Amount,
+		&i.Currency,
+		&i.Description,
+		&i.CategoryID,
+		&i.Frequency,
+		&i.IntervalCount,
+		&i.DayOfMonth,
+		&i.StartDate,
+		&i.EndDate,
+		&i.OccurrenceCount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getTransaction = `-- name: GetTransaction :one
 
-SELECT id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description FROM Transactions
+SELECT id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description, currency, transfer_id, journal_entry_id, category_id, deleted_at, external_id, metadata FROM Transactions
 WHERE id = $1 LIMIT 1
 `
 
@@ -371,14 +1299,45 @@ func (q *Queries) GetTransaction(ctx context.Context, id int32) (Transaction, er
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Description,
+		&i.Currency,
+		&i.TransferID,
+		&i.JournalEntryID,
+		&i.CategoryID,
+		&i.DeletedAt,
+		&i.ExternalID,
+		&i.Metadata,
 	)
 	return i, err
 }
 
+const getTransactionType = `-- name: GetTransactionType :one
+SELECT id, name, sign FROM Transaction_Types
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransactionType(ctx context.Context, id int32) (TransactionType, error) {
+	row := q.db.QueryRow(ctx, getTransactionType, id)
+	var i TransactionType
+	err := row.Scan(&i.ID, &i.Name, &i.Sign)
+	return i, err
+}
+
+const getTransactionTypeByName = `-- name: GetTransactionTypeByName :one
+SELECT id, name, sign FROM Transaction_Types
+WHERE name = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransactionTypeByName(ctx context.Context, name string) (TransactionType, error) {
+	row := q.db.QueryRow(ctx, getTransactionTypeByName, name)
+	var i TransactionType
+	err := row.Scan(&i.ID, &i.Name, &i.Sign)
+	return i, err
+}
+
 const getTransactionWithCheck = `-- name: GetTransactionWithCheck :one
-SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description
+SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description, t.currency, t.transfer_id, t.journal_entry_id, t.category_id, t.deleted_at, t.external_id, t.metadata
 FROM transactions AS t
-WHERE t.account_id = $1 and t.id = $2 AND EXISTS (
+WHERE t.account_id = $1 and t.id = $2 AND t.deleted_at IS NULL AND EXISTS (
 	SELECT 1
 	FROM members AS m
 	WHERE m.account_id = t.account_id AND m.user_id = $3
@@ -404,13 +1363,79 @@ func (q *Queries) GetTransactionWithCheck(ctx context.Context, arg GetTransactio
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Description,
+		&i.Currency,
+		&i.TransferID,
+		&i.JournalEntryID,
+		&i.CategoryID,
+		&i.DeletedAt,
+		&i.ExternalID,
+		&i.Metadata,
+	)
+	return i, err
+}
+
+const getTransfer = `-- name: GetTransfer :one
+SELECT id, from_account_id, to_account_id, user_id, created_at, updated_at FROM Transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransfer(ctx context.Context, id int32) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransfer, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getTrashedTransactionWithCheck = `-- name: GetTrashedTransactionWithCheck :one
+SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description, t.currency, t.transfer_id, t.journal_entry_id, t.category_id, t.deleted_at, t.external_id, t.metadata
+FROM transactions AS t
+WHERE t.account_id = $1 and t.id = $2 AND t.deleted_at IS NOT NULL AND EXISTS (
+	SELECT 1
+	FROM members AS m
+	WHERE m.account_id = t.account_id AND m.user_id = $3
+)
+`
+
+type GetTrashedTransactionWithCheckParams struct {
+	AccountID int32 `json:"account_id"`
+	ID        int32 `json:"id"`
+	UserID    int32 `json:"user_id"`
+}
+
+func (q *Queries) GetTrashedTransactionWithCheck(ctx context.Context, arg GetTrashedTransactionWithCheckParams) (Transaction, error) {
+	row := q.db.QueryRow(ctx, getTrashedTransactionWithCheck, arg.AccountID, arg.ID, arg.UserID)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.UserID,
+		&i.TransactionDate,
+		&i.TransactionTypeID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Description,
+		&i.Currency,
+		&i.TransferID,
+		&i.JournalEntryID,
+		&i.CategoryID,
+		&i.DeletedAt,
+		&i.ExternalID,
+		&i.Metadata,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
 
-SELECT id, username, password, created_at, updated_at FROM Users
+SELECT id, username, password, created_at, updated_at, reporting_currency FROM Users
 WHERE id = $1 LIMIT 1
 `
 
@@ -424,12 +1449,13 @@ func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ReportingCurrency,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, password, created_at, updated_at FROM Users
+SELECT id, username, password, created_at, updated_at, reporting_currency FROM Users
 WHERE username = $1 LIMIT 1
 `
 
@@ -442,12 +1468,27 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ReportingCurrency,
 	)
 	return i, err
 }
 
+const isOperator = `-- name: IsOperator :one
+SELECT EXISTS (
+  SELECT 1 FROM Operators
+  WHERE user_id = $1
+)
+`
+
+func (q *Queries) IsOperator(ctx context.Context, userID int32) (bool, error) {
+	row := q.db.QueryRow(ctx, isOperator, userID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const listAccount = `-- name: ListAccount :many
-SELECT id, account_name, account_type, created_at, updated_at FROM Accounts
+SELECT id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes FROM Accounts
 ORDER BY id
 `
 
@@ -466,6 +1507,10 @@ func (q *Queries) ListAccount(ctx context.Context) ([]Account, error) {
 			&i.AccountType,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Currency,
+			&i.ArchivedAt,
+			&i.BudgetingMode,
+			&i.AllowNegativeEnvelopes,
 		); err != nil {
 			return nil, err
 		}
@@ -477,17 +1522,63 @@ func (q *Queries) ListAccount(ctx context.Context) ([]Account, error) {
 	return items, nil
 }
 
-const listAccountByUser = `-- name: ListAccountByUser :many
-SELECT 
-  	acc.id, acc.account_name, acc.account_type, acc.created_at, acc.updated_at
-FROM Accounts acc
-JOIN Members mem ON acc.id = mem.account_id
-WHERE mem.user_id = $1
+const listAccountBalanceByCurrency = `-- name: ListAccountBalanceByCurrency :many
+SELECT p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = $1::int AND la.kind = 'asset' AND je.entry_date <= $2::date AND je.deleted_at IS NULL
+GROUP BY p.currency, je.entry_date
+ORDER BY je.entry_date
 `
 
-func (q *Queries) ListAccountByUser(ctx context.Context, userID int32) ([]Account, error) {
-	rows, err := q.db.Query(ctx, listAccountByUser, userID)
-	if err != nil {
+type ListAccountBalanceByCurrencyParams struct {
+	AccountID int32       `json:"account_id"`
+	AsOf      pgtype.Date `json:"as_of"`
+}
+
+type ListAccountBalanceByCurrencyRow struct {
+	Currency  string       `json:"currency"`
+	EntryDate pgtype.Date  `json:"entry_date"`
+	Amount    money.Amount `json:"amount"`
+}
+
+func (q *Queries) ListAccountBalanceByCurrency(ctx context.Context, arg ListAccountBalanceByCurrencyParams) ([]ListAccountBalanceByCurrencyRow, error) {
+	rows, err := q.db.Query(ctx, listAccountBalanceByCurrency, arg.AccountID, arg.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAccountBalanceByCurrencyRow
+	for rows.Next() {
+		var i ListAccountBalanceByCurrencyRow
+		if err := rows.Scan(&i.Currency, &i.EntryDate, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountByUser = `-- name: ListAccountByUser :many
+SELECT 
+  	acc.id, acc.account_name, acc.account_type, acc.created_at, acc.updated_at, acc.currency, acc.archived_at, acc.budgeting_mode, acc.allow_negative_envelopes
+FROM Accounts acc
+JOIN Members mem ON acc.id = mem.account_id
+WHERE mem.user_id = $1 AND ($2::boolean OR acc.archived_at IS NULL)
+`
+
+type ListAccountByUserParams struct {
+	UserID          int32 `json:"user_id"`
+	IncludeArchived bool  `json:"include_archived"`
+}
+
+func (q *Queries) ListAccountByUser(ctx context.Context, arg ListAccountByUserParams) ([]Account, error) {
+	rows, err := q.db.Query(ctx, listAccountByUser, arg.UserID, arg.IncludeArchived)
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
@@ -500,6 +1591,10 @@ func (q *Queries) ListAccountByUser(ctx context.Context, userID int32) ([]Accoun
 			&i.AccountType,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Currency,
+			&i.ArchivedAt,
+			&i.BudgetingMode,
+			&i.AllowNegativeEnvelopes,
 		); err != nil {
 			return nil, err
 		}
@@ -512,7 +1607,7 @@ func (q *Queries) ListAccountByUser(ctx context.Context, userID int32) ([]Accoun
 }
 
 const listAccountEvent = `-- name: ListAccountEvent :many
-SELECT id, account_id, event_type_id, description, created_at, updated_at FROM Account_Events
+SELECT id, account_id, event_type_id, description, created_at, updated_at, user_id, diff, prev_hash, hash FROM Account_Events
 ORDER BY id
 `
 
@@ -532,6 +1627,10 @@ func (q *Queries) ListAccountEvent(ctx context.Context) ([]AccountEvent, error)
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.UserID,
+			&i.Diff,
+			&i.PrevHash,
+			&i.Hash,
 		); err != nil {
 			return nil, err
 		}
@@ -544,27 +1643,1392 @@ func (q *Queries) ListAccountEvent(ctx context.Context) ([]AccountEvent, error)
 }
 
 const listAccountEventByAccount = `-- name: ListAccountEventByAccount :many
-SELECT id, account_id, event_type_id, description, created_at, updated_at FROM Account_Events
+SELECT ae.id, ae.account_id, ae.event_type_id, ae.description, ae.created_at, ae.updated_at, ae.user_id, ae.diff, ae.prev_hash, ae.hash, et.name AS event_type
+FROM Account_Events AS ae
+JOIN Event_Types AS et ON et.id = ae.event_type_id
+WHERE ae.account_id = $1
+ORDER BY ae.id
+`
+
+type ListAccountEventByAccountRow struct {
+	ID          int32            `json:"id"`
+	AccountID   int32            `json:"account_id"`
+	EventTypeID int32            `json:"event_type_id"`
+	Description string           `json:"description"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	UserID      pgtype.Int4      `json:"user_id"`
+	Diff        json.RawMessage  `json:"diff"`
+	PrevHash    pgtype.Text      `json:"prev_hash"`
+	Hash        string           `json:"hash"`
+	EventType   string           `json:"event_type"`
+}
+
+func (q *Queries) ListAccountEventByAccount(ctx context.Context, accountID int32) ([]ListAccountEventByAccountRow, error) {
+	rows, err := q.db.Query(ctx, listAccountEventByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAccountEventByAccountRow
+	for rows.Next() {
+		var i ListAccountEventByAccountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.EventTypeID,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Diff,
+			&i.PrevHash,
+			&i.Hash,
+			&i.EventType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountEventChain = `-- name: ListAccountEventChain :many
+SELECT ae.id, ae.prev_hash, ae.hash, account_event_hash(ae)::text AS computed_hash
+FROM Account_Events AS ae
+WHERE ae.account_id = $1
+ORDER BY ae.id
+`
+
+type ListAccountEventChainRow struct {
+	ID           int32       `json:"id"`
+	PrevHash     pgtype.Text `json:"prev_hash"`
+	Hash         string      `json:"hash"`
+	ComputedHash string      `json:"computed_hash"`
+}
+
+func (q *Queries) ListAccountEventChain(ctx context.Context, accountID int32) ([]ListAccountEventChainRow, error) {
+	rows, err := q.db.Query(ctx, listAccountEventChain, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAccountEventChainRow
+	for rows.Next() {
+		var i ListAccountEventChainRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PrevHash,
+			&i.Hash,
+			&i.ComputedHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountShares = `-- name: ListAccountShares :many
+SELECT s.transaction_id, s.user_id, s.amount, t.user_id AS payer_id, tt.sign, tt.name AS transaction_type,
+  COALESCE(t.currency, acc.currency)::text AS currency, t.transaction_date, t.description
+FROM Transaction_Shares AS s
+JOIN Transactions AS t ON t.id = s.transaction_id
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Accounts AS acc ON acc.id = t.account_id
+WHERE t.account_id = $1 AND t.deleted_at IS NULL
+ORDER BY t.transaction_date, s.transaction_id, s.id
+`
+
+type ListAccountSharesRow struct {
+	TransactionID   int32        `json:"transaction_id"`
+	UserID          int32        `json:"user_id"`
+	Amount          money.Amount `json:"amount"`
+	PayerID         int32        `json:"payer_id"`
+	Sign            int32        `json:"sign"`
+	TransactionType string       `json:"transaction_type"`
+	Currency        string       `json:"currency"`
+	TransactionDate pgtype.Date  `json:"transaction_date"`
+	Description     string       `json:"description"`
+}
+
+func (q *Queries) ListAccountShares(ctx context.Context, accountID int32) ([]ListAccountSharesRow, error) {
+	rows, err := q.db.Query(ctx, listAccountShares, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAccountSharesRow
+	for rows.Next() {
+		var i ListAccountSharesRow
+		if err := rows.Scan(
+			&i.TransactionID,
+			&i.UserID,
+			&i.Amount,
+			&i.PayerID,
+			&i.Sign,
+			&i.TransactionType,
+			&i.Currency,
+			&i.TransactionDate,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBudgetsByAccount = `-- name: ListBudgetsByAccount :many
+SELECT id, account_id, category_id, month, amount, rollover, created_at, updated_at FROM Budgets
+WHERE account_id = $1 AND month <= $2
+ORDER BY month, category_id
+`
+
+type ListBudgetsByAccountParams struct {
+	AccountID int32       `json:"account_id"`
+	Through   pgtype.Date `json:"through"`
+}
+
+func (q *Queries) ListBudgetsByAccount(ctx context.Context, arg ListBudgetsByAccountParams) ([]Budget, error) {
+	rows, err := q.db.Query(ctx, listBudgetsByAccount, arg.AccountID, arg.Through)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Budget
+	for rows.Next() {
+		var i Budget
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.CategoryID,
+			&i.Month,
+			&i.Amount,
+			&i.Rollover,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCategoryByAccount = `-- name: ListCategoryByAccount :many
+SELECT id, account_id, parent_id, name, created_at, updated_at FROM Categories
 WHERE account_id = $1
-ORDER BY id
+ORDER BY name, id
+`
+
+func (q *Queries) ListCategoryByAccount(ctx context.Context, accountID int32) ([]Category, error) {
+	rows, err := q.db.Query(ctx, listCategoryByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Category
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.ParentID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCategorySpending = `-- name: ListCategorySpending :many
+SELECT p.category_id::int AS category_id, p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = $1::int
+  AND la.kind = 'asset'
+  AND p.category_id IS NOT NULL
+  AND je.entry_date BETWEEN $2::date AND $3::date
+  AND je.deleted_at IS NULL
+GROUP BY p.category_id, p.currency, je.entry_date
+ORDER BY je.entry_date
+`
+
+type ListCategorySpendingParams struct {
+	AccountID int32       `json:"account_id"`
+	FromDate  pgtype.Date `json:"from_date"`
+	ToDate    pgtype.Date `json:"to_date"`
+}
+
+type ListCategorySpendingRow struct {
+	CategoryID int32        `json:"category_id"`
+	Currency   string       `json:"currency"`
+	EntryDate  pgtype.Date  `json:"entry_date"`
+	Amount     money.Amount `json:"amount"`
+}
+
+func (q *Queries) ListCategorySpending(ctx context.Context, arg ListCategorySpendingParams) ([]ListCategorySpendingRow, error) {
+	rows, err := q.db.Query(ctx, listCategorySpending, arg.AccountID, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCategorySpendingRow
+	for rows.Next() {
+		var i ListCategorySpendingRow
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.Currency,
+			&i.EntryDate,
+			&i.Amount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDueRecurringRules = `-- name: ListDueRecurringRules :many
+SELECT r.id, r.account_id, r.user_id, r.transaction_type_id, r.amount, r.currency, r.description, r.category_id, r.frequency, r.interval_count, r.day_of_month, r.start_date, r.end_date, r.occurrence_count, r.created_at, r.updated_at, (
+  SELECT MAX(o.occurrence_date)
+  FROM Recurring_Occurrences AS o
+  WHERE o.rule_id = r.id AND o.status = 'posted' AND o.occurrence_date <= $1
+)::date AS last_occurrence_date
+FROM Recurring_Rules AS r
+JOIN Accounts AS a ON a.id = r.account_id
+WHERE r.start_date <= $1 AND a.archived_at IS NULL
+ORDER BY r.id
+`
+
+type ListDueRecurringRulesRow struct {
+	RecurringRule      RecurringRule `json:"recurring_rule"`
+	LastOccurrenceDate pgtype.Date   `json:"last_occurrence_date"`
+}
+
+func (q *Queries) ListDueRecurringRules(ctx context.Context, startDate pgtype.Date) ([]ListDueRecurringRulesRow, error) {
+	rows, err := q.db.Query(ctx, listDueRecurringRules, startDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDueRecurringRulesRow
+	for rows.Next() {
+		var i ListDueRecurringRulesRow
+		if err := rows.Scan(
+			&i.RecurringRule.ID,
+			&i.RecurringRule.AccountID,
+			&i.RecurringRule.UserID,
+			&i.RecurringRule.TransactionTypeID,
+			&i.RecurringRule.Amount,
+			&i.RecurringRule.Currency,
+			&i.RecurringRule.Description,
+			&i.RecurringRule.CategoryID,
+			&i.RecurringRule.Frequency,
+			&i.RecurringRule.IntervalCount,
+			&i.RecurringRule.DayOfMonth,
+			&i.RecurringRule.StartDate,
+			&i.RecurringRule.EndDate,
+			&i.RecurringRule.OccurrenceCount,
+			&i.RecurringRule.CreatedAt,
+			&i.RecurringRule.UpdatedAt,
+			&i.LastOccurrenceDate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvelopeActivity = `-- name: ListEnvelopeActivity :many
+SELECT COALESCE(p.category_id, 0)::int AS category_id, p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = $1 AND la.kind = 'asset' AND je.deleted_at IS NULL
+GROUP BY p.category_id, p.currency, je.entry_date, p.amount > 0
+ORDER BY je.entry_date
+`
+
+type ListEnvelopeActivityRow struct {
+	CategoryID int32        `json:"category_id"`
+	Currency   string       `json:"currency"`
+	EntryDate  pgtype.Date  `json:"entry_date"`
+	Amount     money.Amount `json:"amount"`
+}
+
+func (q *Queries) ListEnvelopeActivity(ctx context.Context, accountID int32) ([]ListEnvelopeActivityRow, error) {
+	rows, err := q.db.Query(ctx, listEnvelopeActivity, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnvelopeActivityRow
+	for rows.Next() {
+		var i ListEnvelopeActivityRow
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.Currency,
+			&i.EntryDate,
+			&i.Amount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvelopeMoveTotals = `-- name: ListEnvelopeMoveTotals :many
+SELECT COALESCE(category_id, 0)::int AS category_id, SUM(amount)::numeric AS amount
+FROM (
+  SELECT from_category_id AS category_id, -amount AS amount FROM Envelope_Moves WHERE account_id = $1
+  UNION ALL
+  SELECT to_category_id AS category_id, amount FROM Envelope_Moves WHERE account_id = $1
+) AS moves
+GROUP BY category_id
+`
+
+type ListEnvelopeMoveTotalsRow struct {
+	CategoryID int32        `json:"category_id"`
+	Amount     money.Amount `json:"amount"`
+}
+
+func (q *Queries) ListEnvelopeMoveTotals(ctx context.Context, accountID int32) ([]ListEnvelopeMoveTotalsRow, error) {
+	rows, err := q.db.Query(ctx, listEnvelopeMoveTotals, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListEnvelopeMoveTotalsRow
+	for rows.Next() {
+		var i ListEnvelopeMoveTotalsRow
+		if err := rows.Scan(&i.CategoryID, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnvelopeMoves = `-- name: ListEnvelopeMoves :many
+SELECT id, account_id, user_id, from_category_id, to_category_id, amount, note, created_at FROM Envelope_Moves
+WHERE account_id = $1
+ORDER BY id DESC
+`
+
+func (q *Queries) ListEnvelopeMoves(ctx context.Context, accountID int32) ([]EnvelopeMove, error) {
+	rows, err := q.db.Query(ctx, listEnvelopeMoves, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EnvelopeMove
+	for rows.Next() {
+		var i EnvelopeMove
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.FromCategoryID,
+			&i.ToCategoryID,
+			&i.Amount,
+			&i.Note,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExchangeRates = `-- name: ListExchangeRates :many
+SELECT id, rate_date, base_currency, quote_currency, rate, created_at, updated_at FROM Exchange_Rates
+WHERE ($1::text IS NULL OR base_currency = $1)
+  AND ($2::text IS NULL OR quote_currency = $2)
+  AND ($3::date IS NULL OR rate_date = $3)
+ORDER BY rate_date, base_currency, quote_currency
+`
+
+type ListExchangeRatesParams struct {
+	BaseCurrency  pgtype.Text `json:"base_currency"`
+	QuoteCurrency pgtype.Text `json:"quote_currency"`
+	RateDate      pgtype.Date `json:"rate_date"`
+}
+
+func (q *Queries) ListExchangeRates(ctx context.Context, arg ListExchangeRatesParams) ([]ExchangeRate, error) {
+	rows, err := q.db.Query(ctx, listExchangeRates, arg.BaseCurrency, arg.QuoteCurrency, arg.RateDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExchangeRate
+	for rows.Next() {
+		var i ExchangeRate
+		if err := rows.Scan(
+			&i.ID,
+			&i.RateDate,
+			&i.BaseCurrency,
+			&i.QuoteCurrency,
+			&i.Rate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExchangeRatesForCurrencies = `-- name: ListExchangeRatesForCurrencies :many
+SELECT id, rate_date, base_currency, quote_currency, rate, created_at, updated_at FROM Exchange_Rates
+WHERE rate_date <= $1
+  AND (base_currency = ANY($2::text[]) OR quote_currency = ANY($2::text[]))
+ORDER BY rate_date
+`
+
+type ListExchangeRatesForCurrenciesParams struct {
+	RateDate   pgtype.Date `json:"rate_date"`
+	Currencies []string    `json:"currencies"`
+}
+
+func (q *Queries) ListExchangeRatesForCurrencies(ctx context.Context, arg ListExchangeRatesForCurrenciesParams) ([]ExchangeRate, error) {
+	rows, err := q.db.Query(ctx, listExchangeRatesForCurrencies, arg.RateDate, arg.Currencies)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExchangeRate
+	for rows.Next() {
+		var i ExchangeRate
+		if err := rows.Scan(
+			&i.ID,
+			&i.RateDate,
+			&i.BaseCurrency,
+			&i.QuoteCurrency,
+			&i.Rate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGoalContributions = `-- name: ListGoalContributions :many
+SELECT p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = $1::int
+  AND la.kind = 'asset'
+  AND ($2::int IS NULL OR p.category_id IN (
+	WITH RECURSIVE subtree AS (
+		SELECT c.id FROM Categories AS c WHERE c.id = $2::int
+		UNION ALL
+		SELECT c.id FROM Categories AS c JOIN subtree ON c.parent_id = subtree.id
+	)
+	SELECT id FROM subtree
+  ))
+  AND je.entry_date BETWEEN $3::date AND $4::date
+  AND je.deleted_at IS NULL
+GROUP BY p.currency, je.entry_date
+ORDER BY je.entry_date
+`
+
+type ListGoalContributionsParams struct {
+	AccountID  int32       `json:"account_id"`
+	CategoryID pgtype.Int4 `json:"category_id"`
+	FromDate   pgtype.Date `json:"from_date"`
+	ToDate     pgtype.Date `json:"to_date"`
+}
+
+type ListGoalContributionsRow struct {
+	Currency  string       `json:"currency"`
+	EntryDate pgtype.Date  `json:"entry_date"`
+	Amount    money.Amount `json:"amount"`
+}
+
+func (q *Queries) ListGoalContributions(ctx context.Context, arg ListGoalContributionsParams) ([]ListGoalContributionsRow, error) {
+	rows, err := q.db.Query(ctx, listGoalContributions,
+		arg.AccountID,
+		arg.CategoryID,
+		arg.FromDate,
+		arg.ToDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListGoalContributionsRow
+	for rows.Next() {
+		var i ListGoalContributionsRow
+		if err := rows.Scan(&i.Currency, &i.EntryDate, &i.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGoalsByAccount = `-- name: ListGoalsByAccount :many
+SELECT id, account_id, name, target_amount, target_date, category_id, start_date, created_at, updated_at FROM Goals
+WHERE account_id = $1
+ORDER BY target_date, id
+`
+
+func (q *Queries) ListGoalsByAccount(ctx context.Context, accountID int32) ([]Goal, error) {
+	rows, err := q.db.Query(ctx, listGoalsByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Goal
+	for rows.Next() {
+		var i Goal
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Name,
+			&i.TargetAmount,
+			&i.TargetDate,
+			&i.CategoryID,
+			&i.StartDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImportProfiles = `-- name: ListImportProfiles :many
+SELECT id, account_id, name, delimiter, skip_rows, date_column, date_format, description_columns, amount_column, debit_column, credit_column, decimal_separator, negate_amounts, created_at FROM Import_Profiles
+WHERE account_id = $1
+ORDER BY name
+`
+
+func (q *Queries) ListImportProfiles(ctx context.Context, accountID int32) ([]ImportProfile, error) {
+	rows, err := q.db.Query(ctx, listImportProfiles, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ImportProfile
+	for rows.Next() {
+		var i ImportProfile
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Name,
+			&i.Delimiter,
+			&i.SkipRows,
+			&i.DateColumn,
+			&i.DateFormat,
+			&i.DescriptionColumns,
+			&i.AmountColumn,
+			&i.DebitColumn,
+			&i.CreditColumn,
+			&i.DecimalSeparator,
+			&i.NegateAmounts,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listJournalEntryIDsByAccount = `-- name: ListJournalEntryIDsByAccount :many
+SELECT DISTINCT journal_entry_id
+FROM Transaction_Headers
+WHERE account_id = $1
+ORDER BY journal_entry_id
+`
+
+func (q *Queries) ListJournalEntryIDsByAccount(ctx context.Context, accountID int32) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listJournalEntryIDsByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var journal_entry_id int32
+		if err := rows.Scan(&journal_entry_id); err != nil {
+			return nil, err
+		}
+		items = append(items, journal_entry_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMember = `-- name: ListMember :many
+SELECT id, account_id, user_id, member_role_id, created_at, updated_at FROM Members
+ORDER BY id
+`
+
+func (q *Queries) ListMember(ctx context.Context) ([]Member, error) {
+	rows, err := q.db.Query(ctx, listMember)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Member
+	for rows.Next() {
+		var i Member
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.MemberRoleID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMemberByAccount = `-- name: ListMemberByAccount :many
+SELECT id, account_id, user_id, member_role_id, created_at, updated_at FROM Members
+WHERE account_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListMemberByAccount(ctx context.Context, accountID int32) ([]Member, error) {
+	rows, err := q.db.Query(ctx, listMemberByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Member
+	for rows.Next() {
+		var i Member
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.MemberRoleID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMemberByAccountWithUserCheck = `-- name: ListMemberByAccountWithUserCheck :many
+SELECT m1.id, m1.account_id, m1.user_id, m1.member_role_id, m1.created_at, m1.updated_at
+FROM Members as m1
+JOIN Members as m2
+ON m1.account_id = m2.account_id AND m2.user_id = $2
+WHERE m1.account_id = $1
+`
+
+type ListMemberByAccountWithUserCheckParams struct {
+	AccountID int32 `json:"account_id"`
+	UserID    int32 `json:"user_id"`
+}
+
+func (q *Queries) ListMemberByAccountWithUserCheck(ctx context.Context, arg ListMemberByAccountWithUserCheckParams) ([]Member, error) {
+	rows, err := q.db.Query(ctx, listMemberByAccountWithUserCheck, arg.AccountID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Member
+	for rows.Next() {
+		var i Member
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.MemberRoleID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostingsByJournalEntry = `-- name: ListPostingsByJournalEntry :many
+SELECT p.id, p.journal_entry_id, p.transaction_id, p.currency, p.amount, p.created_at, p.ledger_account_id, p.category_id, p.memo, la.account_id, la.kind
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+WHERE p.journal_entry_id = $1
+ORDER BY p.id
+`
+
+type ListPostingsByJournalEntryRow struct {
+	ID              int32            `json:"id"`
+	JournalEntryID  int32            `json:"journal_entry_id"`
+	TransactionID   pgtype.Int4      `json:"transaction_id"`
+	Currency        string           `json:"currency"`
+	Amount          money.Amount     `json:"amount"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	LedgerAccountID int32            `json:"ledger_account_id"`
+	CategoryID      pgtype.Int4      `json:"category_id"`
+	Memo            string           `json:"memo"`
+	AccountID       int32            `json:"account_id"`
+	Kind            string           `json:"kind"`
+}
+
+func (q *Queries) ListPostingsByJournalEntry(ctx context.Context, journalEntryID int32) ([]ListPostingsByJournalEntryRow, error) {
+	rows, err := q.db.Query(ctx, listPostingsByJournalEntry, journalEntryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPostingsByJournalEntryRow
+	for rows.Next() {
+		var i ListPostingsByJournalEntryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.JournalEntryID,
+			&i.TransactionID,
+			&i.Currency,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.LedgerAccountID,
+			&i.CategoryID,
+			&i.Memo,
+			&i.AccountID,
+			&i.Kind,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecurringOccurrencesByAccount = `-- name: ListRecurringOccurrencesByAccount :many
+SELECT o.id, o.rule_id, o.occurrence_date, o.status, o.transaction_id, o.created_at
+FROM Recurring_Occurrences AS o
+JOIN Recurring_Rules AS r ON r.id = o.rule_id
+WHERE r.account_id = $1 AND o.occurrence_date BETWEEN $2 AND $3
+ORDER BY o.occurrence_date, o.rule_id
+`
+
+type ListRecurringOccurrencesByAccountParams struct {
+	AccountID int32       `json:"account_id"`
+	FromDate  pgtype.Date `json:"from_date"`
+	ToDate    pgtype.Date `json:"to_date"`
+}
+
+func (q *Queries) ListRecurringOccurrencesByAccount(ctx context.Context, arg ListRecurringOccurrencesByAccountParams) ([]RecurringOccurrence, error) {
+	rows, err := q.db.Query(ctx, listRecurringOccurrencesByAccount, arg.AccountID, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecurringOccurrence
+	for rows.Next() {
+		var i RecurringOccurrence
+		if err := rows.Scan(
+			&i.ID,
+			&i.RuleID,
+			&i.OccurrenceDate,
+			&i.Status,
+			&i.TransactionID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecurringRulesByAccount = `-- name: ListRecurringRulesByAccount :many
+SELECT id, account_id, user_id, transaction_type_id, amount, currency, description, category_id, frequency, interval_count, day_of_month, start_date, end_date, occurrence_count, created_at, updated_at FROM Recurring_Rules
+WHERE account_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListRecurringRulesByAccount(ctx context.Context, accountID int32) ([]RecurringRule, error) {
+	rows, err := q.db.Query(ctx, listRecurringRulesByAccount, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RecurringRule
+	for rows.Next() {
+		var i RecurringRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.TransactionTypeID,
+			&i.Amount,
+			&i.Currency,
+			&i.Description,
+			&i.CategoryID,
+			&i.Frequency,
+			&i.IntervalCount,
+			&i.DayOfMonth,
+			&i.StartDate,
+			&i.EndDate,
+			&i.OccurrenceCount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsByAccount = `-- name: ListTagsByAccount :many
+SELECT tg.id, tg.account_id, tg.name, tg.created_at, COUNT(tt.transaction_id) AS usage_count
+FROM Tags AS tg
+LEFT JOIN Transaction_Tags AS tt ON tt.tag_id = tg.id
+WHERE tg.account_id = $1 AND starts_with(tg.name, $2::text)
+GROUP BY tg.id
+ORDER BY usage_count DESC, tg.name
+LIMIT 20
+`
+
+type ListTagsByAccountParams struct {
+	AccountID int32  `json:"account_id"`
+	Prefix    string `json:"prefix"`
+}
+
+type ListTagsByAccountRow struct {
+	ID         int32            `json:"id"`
+	AccountID  int32            `json:"account_id"`
+	Name       string           `json:"name"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+	UsageCount int64            `json:"usage_count"`
+}
+
+func (q *Queries) ListTagsByAccount(ctx context.Context, arg ListTagsByAccountParams) ([]ListTagsByAccountRow, error) {
+	rows, err := q.db.Query(ctx, listTagsByAccount, arg.AccountID, arg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTagsByAccountRow
+	for rows.Next() {
+		var i ListTagsByAccountRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UsageCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransaction = `-- name: ListTransaction :many
+SELECT id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description, currency, transfer_id, journal_entry_id, category_id, deleted_at, external_id, metadata FROM Transactions
+ORDER BY id
+`
+
+func (q *Queries) ListTransaction(ctx context.Context) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransaction)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.TransactionDate,
+			&i.TransactionTypeID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.Currency,
+			&i.TransferID,
+			&i.JournalEntryID,
+			&i.CategoryID,
+			&i.DeletedAt,
+			&i.ExternalID,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionByAccount = `-- name: ListTransactionByAccount :many
+SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description, t.currency, t.transfer_id, t.journal_entry_id, t.category_id, t.deleted_at, t.external_id, t.metadata
+FROM transactions AS t
+WHERE t.account_id = $1 AND t.deleted_at IS NULL AND EXISTS (
+	SELECT 1
+	FROM members AS m
+	WHERE m.account_id = t.account_id AND m.user_id = $2
+) AND ($3::text[] IS NULL OR (
+	SELECT COUNT(DISTINCT tg.name)
+	FROM transaction_tags AS tt
+	JOIN tags AS tg ON tg.id = tt.tag_id
+	WHERE tt.transaction_id = t.id AND tg.name = ANY($3::text[])
+) >= CASE WHEN $4::boolean THEN cardinality($3::text[]) ELSE 1 END)
+  AND ($5::date IS NULL OR t.transaction_date >= $5)
+  AND ($6::date IS NULL OR t.transaction_date <= $6)
+  AND ($7::numeric IS NULL OR t.amount >= $7)
+  AND ($8::numeric IS NULL OR t.amount <= $8)
+  AND ($9::int IS NULL OR t.transaction_type_id = $9)
+  AND ($10::int IS NULL OR EXISTS (
+	WITH RECURSIVE subtree AS (
+		SELECT c.id FROM categories AS c WHERE c.id = $10
+		UNION ALL
+		SELECT c.id FROM categories AS c JOIN subtree ON c.parent_id = subtree.id
+	)
+	SELECT 1
+	FROM subtree
+	WHERE subtree.id = t.category_id OR subtree.id IN (
+		SELECT s.category_id FROM transaction_splits AS s WHERE s.transaction_id = t.id
+	)
+  ))
+  AND ($11::int IS NULL OR t.user_id = $11)
+  AND ($12::text IS NULL OR strpos(lower(t.description), lower($12)) > 0)
+  AND ($13::int IS NULL OR CASE
+	WHEN $14::text = 'amount' AND $15::boolean THEN (t.amount, t.id) < ($16::numeric, $13)
+	WHEN $14 = 'amount' THEN (t.amount, t.id) > ($16, $13)
+	WHEN $14 = 'created_at' AND $15 THEN (t.created_at, t.id) < ($17::timestamp, $13)
+	WHEN $14 = 'created_at' THEN (t.created_at, t.id) > ($17, $13)
+	WHEN $15 THEN (t.transaction_date, t.id) < ($18::date, $13)
+	ELSE (t.transaction_date, t.id) > ($18, $13)
+  END)
+ORDER BY
+  CASE WHEN $14 = 'amount' AND NOT $15 THEN t.amount END ASC,
+  CASE WHEN $14 = 'amount' AND $15 THEN t.amount END DESC,
+  CASE WHEN $14 = 'created_at' AND NOT $15 THEN t.created_at END ASC,
+  CASE WHEN $14 = 'created_at' AND $15 THEN t.created_at END DESC,
+  CASE WHEN $14 = 'date' AND NOT $15 THEN t.transaction_date END ASC,
+  CASE WHEN $14 = 'date' AND $15 THEN t.transaction_date END DESC,
+  CASE WHEN NOT $15 THEN t.id END ASC,
+  CASE WHEN $15 THEN t.id END DESC
+LIMIT $19
+`
+
+type ListTransactionByAccountParams struct {
+	AccountID         int32            `json:"account_id"`
+	UserID            int32            `json:"user_id"`
+	Tags              []string         `json:"tags"`
+	MatchAll          bool             `json:"match_all"`
+	FromDate          pgtype.Date      `json:"from_date"`
+	ToDate            pgtype.Date      `json:"to_date"`
+	MinAmount         money.NullAmount `json:"min_amount"`
+	MaxAmount         money.NullAmount `json:"max_amount"`
+	TransactionTypeID pgtype.Int4      `json:"transaction_type_id"`
+	CategoryID        pgtype.Int4      `json:"category_id"`
+	CreatorID         pgtype.Int4      `json:"creator_id"`
+	Search            pgtype.Text      `json:"search"`
+	CursorID          pgtype.Int4      `json:"cursor_id"`
+	SortBy            string           `json:"sort_by"`
+	Descending        bool             `json:"descending"`
+	CursorAmount      money.NullAmount `json:"cursor_amount"`
+	CursorCreatedAt   pgtype.Timestamp `json:"cursor_created_at"`
+	CursorDate        pgtype.Date      `json:"cursor_date"`
+	PageSize          int32            `json:"page_size"`
+}
+
+func (q *Queries) ListTransactionByAccount(ctx context.Context, arg ListTransactionByAccountParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionByAccount,
+		arg.AccountID,
+		arg.UserID,
+		arg.Tags,
+		arg.MatchAll,
+		arg.FromDate,
+		arg.ToDate,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.TransactionTypeID,
+		arg.CategoryID,
+		arg.CreatorID,
+		arg.Search,
+		arg.CursorID,
+		arg.SortBy,
+		arg.Descending,
+		arg.CursorAmount,
+		arg.CursorCreatedAt,
+		arg.CursorDate,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.TransactionDate,
+			&i.TransactionTypeID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.Currency,
+			&i.TransferID,
+			&i.JournalEntryID,
+			&i.CategoryID,
+			&i.DeletedAt,
+			&i.ExternalID,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionByJournalEntry = `-- name: ListTransactionByJournalEntry :many
+SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description, t.currency, t.transfer_id, t.journal_entry_id, t.category_id, t.deleted_at, t.external_id, t.metadata, tt.sign, acc.currency AS account_currency
+FROM Transactions AS t
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Accounts AS acc ON acc.id = t.account_id
+WHERE t.journal_entry_id = $1
+ORDER BY t.id
+`
+
+type ListTransactionByJournalEntryRow struct {
+	Transaction     Transaction `json:"transaction"`
+	Sign            int32       `json:"sign"`
+	AccountCurrency string      `json:"account_currency"`
+}
+
+func (q *Queries) ListTransactionByJournalEntry(ctx context.Context, journalEntryID int32) ([]ListTransactionByJournalEntryRow, error) {
+	rows, err := q.db.Query(ctx, listTransactionByJournalEntry, journalEntryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTransactionByJournalEntryRow
+	for rows.Next() {
+		var i ListTransactionByJournalEntryRow
+		if err := rows.Scan(
+			&i.Transaction.ID,
+			&i.Transaction.AccountID,
+			&i.Transaction.UserID,
+			&i.Transaction.TransactionDate,
+			&i.Transaction.TransactionTypeID,
+			&i.Transaction.Amount,
+			&i.Transaction.CreatedAt,
+			&i.Transaction.UpdatedAt,
+			&i.Transaction.Description,
+			&i.Transaction.Currency,
+			&i.Transaction.TransferID,
+			&i.Transaction.JournalEntryID,
+			&i.Transaction.CategoryID,
+			&i.Transaction.DeletedAt,
+			&i.Transaction.ExternalID,
+			&i.Transaction.Metadata,
+			&i.Sign,
+			&i.AccountCurrency,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionByTransfer = `-- name: ListTransactionByTransfer :many
+SELECT id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description, currency, transfer_id, journal_entry_id, category_id, deleted_at, external_id, metadata FROM Transactions
+WHERE transfer_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListTransactionByTransfer(ctx context.Context, transferID pgtype.Int4) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionByTransfer, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.TransactionDate,
+			&i.TransactionTypeID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.Currency,
+			&i.TransferID,
+			&i.JournalEntryID,
+			&i.CategoryID,
+			&i.DeletedAt,
+			&i.ExternalID,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionDuplicateCandidates = `-- name: ListTransactionDuplicateCandidates :many
+SELECT id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description, currency, transfer_id, journal_entry_id, category_id, deleted_at, external_id, metadata FROM Transactions
+WHERE account_id = $1
+  AND deleted_at IS NULL
+  AND transaction_type_id = $2
+  AND amount = $3
+  AND COALESCE(currency, $4::text) = $5::text
+  AND transaction_date BETWEEN $6::date AND $7::date
+  AND NOT id = ANY($8::int[])
+ORDER BY transaction_date, id;
+`
+
+type ListTransactionDuplicateCandidatesParams struct {
+	AccountID         int32        `json:"account_id"`
+	TransactionTypeID int32        `json:"transaction_type_id"`
+	Amount            money.Amount `json:"amount"`
+	AccountCurrency   string       `json:"account_currency"`
+	Currency          string       `json:"currency"`
+	FromDate          pgtype.Date  `json:"from_date"`
+	ToDate            pgtype.Date  `json:"to_date"`
+	ExcludedIds       []int32      `json:"excluded_ids"`
+}
+
+func (q *Queries) ListTransactionDuplicateCandidates(ctx context.Context, arg ListTransactionDuplicateCandidatesParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionDuplicateCandidates,
+		arg.AccountID,
+		arg.TransactionTypeID,
+		arg.Amount,
+		arg.AccountCurrency,
+		arg.Currency,
+		arg.FromDate,
+		arg.ToDate,
+		arg.ExcludedIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.TransactionDate,
+			&i.TransactionTypeID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Description,
+			&i.Currency,
+			&i.TransferID,
+			&i.JournalEntryID,
+			&i.CategoryID,
+			&i.DeletedAt,
+			&i.ExternalID,
+			&i.Metadata,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionExternalIDs = `-- name: ListTransactionExternalIDs :many
+SELECT external_id::text FROM Transactions
+WHERE account_id = $1 AND external_id = ANY($2::text[])
+`
+
+type ListTransactionExternalIDsParams struct {
+	AccountID   int32    `json:"account_id"`
+	ExternalIds []string `json:"external_ids"`
+}
+
+func (q *Queries) ListTransactionExternalIDs(ctx context.Context, arg ListTransactionExternalIDsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listTransactionExternalIDs, arg.AccountID, arg.ExternalIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var external_id string
+		if err := rows.Scan(&external_id); err != nil {
+			return nil, err
+		}
+		items = append(items, external_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionForExport = `-- name: ListTransactionForExport :many
+SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description, t.currency, t.transfer_id, t.journal_entry_id, t.category_id, t.deleted_at, t.external_id, t.metadata, tt.sign, acc.currency AS account_currency
+FROM Transactions AS t
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Accounts AS acc ON acc.id = t.account_id
+WHERE t.account_id = $1 AND t.deleted_at IS NULL
+ORDER BY t.transaction_date, t.id
 `
 
-func (q *Queries) ListAccountEventByAccount(ctx context.Context, accountID int32) ([]AccountEvent, error) {
-	rows, err := q.db.Query(ctx, listAccountEventByAccount, accountID)
+type ListTransactionForExportRow struct {
+	Transaction     Transaction `json:"transaction"`
+	Sign            int32       `json:"sign"`
+	AccountCurrency string      `json:"account_currency"`
+}
+
+func (q *Queries) ListTransactionForExport(ctx context.Context, accountID int32) ([]ListTransactionForExportRow, error) {
+	rows, err := q.db.Query(ctx, listTransactionForExport, accountID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []AccountEvent
+	var items []ListTransactionForExportRow
 	for rows.Next() {
-		var i AccountEvent
+		var i ListTransactionForExportRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.AccountID,
-			&i.EventTypeID,
-			&i.Description,
-			&i.CreatedAt,
-			&i.UpdatedAt,
+			&i.Transaction.ID,
+			&i.Transaction.AccountID,
+			&i.Transaction.UserID,
+			&i.Transaction.TransactionDate,
+			&i.Transaction.TransactionTypeID,
+			&i.Transaction.Amount,
+			&i.Transaction.CreatedAt,
+			&i.Transaction.UpdatedAt,
+			&i.Transaction.Description,
+			&i.Transaction.Currency,
+			&i.Transaction.TransferID,
+			&i.Transaction.JournalEntryID,
+			&i.Transaction.CategoryID,
+			&i.Transaction.DeletedAt,
+			&i.Transaction.ExternalID,
+			&i.Transaction.Metadata,
+			&i.Sign,
+			&i.AccountCurrency,
 		); err != nil {
 			return nil, err
 		}
@@ -576,27 +3040,29 @@ func (q *Queries) ListAccountEventByAccount(ctx context.Context, accountID int32
 	return items, nil
 }
 
-const listMember = `-- name: ListMember :many
-SELECT id, account_id, user_id, member_role_id, created_at, updated_at FROM Members
+const listTransactionShares = `-- name: ListTransactionShares :many
+SELECT id, transaction_id, user_id, method, value, amount, created_at FROM Transaction_Shares
+WHERE transaction_id = $1
 ORDER BY id
 `
 
-func (q *Queries) ListMember(ctx context.Context) ([]Member, error) {
-	rows, err := q.db.Query(ctx, listMember)
+func (q *Queries) ListTransactionShares(ctx context.Context, transactionID int32) ([]TransactionShare, error) {
+	rows, err := q.db.Query(ctx, listTransactionShares, transactionID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Member
+	var items []TransactionShare
 	for rows.Next() {
-		var i Member
+		var i TransactionShare
 		if err := rows.Scan(
 			&i.ID,
-			&i.AccountID,
+			&i.TransactionID,
 			&i.UserID,
-			&i.MemberRoleID,
+			&i.Method,
+			&i.Value,
+			&i.Amount,
 			&i.CreatedAt,
-			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -608,26 +3074,27 @@ func (q *Queries) ListMember(ctx context.Context) ([]Member, error) {
 	return items, nil
 }
 
-const listMemberByAccount = `-- name: ListMemberByAccount :many
-SELECT id, account_id, user_id, member_role_id, created_at, updated_at FROM Members
-WHERE account_id = $1
+const listTransactionSplits = `-- name: ListTransactionSplits :many
+SELECT id, transaction_id, category_id, amount, memo, created_at, updated_at FROM Transaction_Splits
+WHERE transaction_id = $1
 ORDER BY id
 `
 
-func (q *Queries) ListMemberByAccount(ctx context.Context, accountID int32) ([]Member, error) {
-	rows, err := q.db.Query(ctx, listMemberByAccount, accountID)
+func (q *Queries) ListTransactionSplits(ctx context.Context, transactionID int32) ([]TransactionSplit, error) {
+	rows, err := q.db.Query(ctx, listTransactionSplits, transactionID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Member
+	var items []TransactionSplit
 	for rows.Next() {
-		var i Member
+		var i TransactionSplit
 		if err := rows.Scan(
 			&i.ID,
-			&i.AccountID,
-			&i.UserID,
-			&i.MemberRoleID,
+			&i.TransactionID,
+			&i.CategoryID,
+			&i.Amount,
+			&i.Memo,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -641,33 +3108,29 @@ func (q *Queries) ListMemberByAccount(ctx context.Context, accountID int32) ([]M
 	return items, nil
 }
 
-const listMemberByAccountWithUserCheck = `-- name: ListMemberByAccountWithUserCheck :many
-SELECT m1.id, m1.account_id, m1.user_id, m1.member_role_id, m1.created_at, m1.updated_at
-FROM Members as m1
-JOIN Members as m2
-ON m1.account_id = m2.account_id AND m2.user_id = $2
-WHERE m1.account_id = $1
+const listTransactionSplitsByAccount = `-- name: ListTransactionSplitsByAccount :many
+SELECT s.id, s.transaction_id, s.category_id, s.amount, s.memo, s.created_at, s.updated_at
+FROM Transaction_Splits AS s
+JOIN Transactions AS t ON t.id = s.transaction_id
+WHERE t.account_id = $1 AND t.deleted_at IS NULL
+ORDER BY s.transaction_id, s.id
 `
 
-type ListMemberByAccountWithUserCheckParams struct {
-	AccountID int32 `json:"account_id"`
-	UserID    int32 `json:"user_id"`
-}
-
-func (q *Queries) ListMemberByAccountWithUserCheck(ctx context.Context, arg ListMemberByAccountWithUserCheckParams) ([]Member, error) {
-	rows, err := q.db.Query(ctx, listMemberByAccountWithUserCheck, arg.AccountID, arg.UserID)
+func (q *Queries) ListTransactionSplitsByAccount(ctx context.Context, accountID int32) ([]TransactionSplit, error) {
+	rows, err := q.db.Query(ctx, listTransactionSplitsByAccount, accountID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Member
+	var items []TransactionSplit
 	for rows.Next() {
-		var i Member
+		var i TransactionSplit
 		if err := rows.Scan(
 			&i.ID,
-			&i.AccountID,
-			&i.UserID,
-			&i.MemberRoleID,
+			&i.TransactionID,
+			&i.CategoryID,
+			&i.Amount,
+			&i.Memo,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -681,30 +3144,28 @@ func (q *Queries) ListMemberByAccountWithUserCheck(ctx context.Context, arg List
 	return items, nil
 }
 
-const listTransaction = `-- name: ListTransaction :many
-SELECT id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description FROM Transactions
-ORDER BY id
+const listTransactionTags = `-- name: ListTransactionTags :many
+SELECT tg.id, tg.account_id, tg.name, tg.created_at
+FROM Tags AS tg
+JOIN Transaction_Tags AS tt ON tt.tag_id = tg.id
+WHERE tt.transaction_id = $1
+ORDER BY tg.name
 `
 
-func (q *Queries) ListTransaction(ctx context.Context) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, listTransaction)
+func (q *Queries) ListTransactionTags(ctx context.Context, transactionID int32) ([]Tag, error) {
+	rows, err := q.db.Query(ctx, listTransactionTags, transactionID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Transaction
+	var items []Tag
 	for rows.Next() {
-		var i Transaction
+		var i Tag
 		if err := rows.Scan(
 			&i.ID,
 			&i.AccountID,
-			&i.UserID,
-			&i.TransactionDate,
-			&i.TransactionTypeID,
-			&i.Amount,
+			&i.Name,
 			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Description,
 		); err != nil {
 			return nil, err
 		}
@@ -716,23 +3177,24 @@ func (q *Queries) ListTransaction(ctx context.Context) ([]Transaction, error) {
 	return items, nil
 }
 
-const listTransactionByAccount = `-- name: ListTransactionByAccount :many
-SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description
+const listTrashedTransactionByAccount = `-- name: ListTrashedTransactionByAccount :many
+SELECT t.id, t.account_id, t.user_id, t.transaction_date, t.transaction_type_id, t.amount, t.created_at, t.updated_at, t.description, t.currency, t.transfer_id, t.journal_entry_id, t.category_id, t.deleted_at, t.external_id, t.metadata
 FROM transactions AS t
-WHERE t.account_id = $1 AND EXISTS (
+WHERE t.account_id = $1 AND t.deleted_at IS NOT NULL AND EXISTS (
 	SELECT 1
 	FROM members AS m
 	WHERE m.account_id = t.account_id AND m.user_id = $2
 )
+ORDER BY t.deleted_at DESC, t.id DESC
 `
 
-type ListTransactionByAccountParams struct {
+type ListTrashedTransactionByAccountParams struct {
 	AccountID int32 `json:"account_id"`
 	UserID    int32 `json:"user_id"`
 }
 
-func (q *Queries) ListTransactionByAccount(ctx context.Context, arg ListTransactionByAccountParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, listTransactionByAccount, arg.AccountID, arg.UserID)
+func (q *Queries) ListTrashedTransactionByAccount(ctx context.Context, arg ListTrashedTransactionByAccountParams) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTrashedTransactionByAccount, arg.AccountID, arg.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -750,6 +3212,13 @@ func (q *Queries) ListTransactionByAccount(ctx context.Context, arg ListTransact
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Description,
+			&i.Currency,
+			&i.TransferID,
+			&i.JournalEntryID,
+			&i.CategoryID,
+			&i.DeletedAt,
+			&i.ExternalID,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -762,7 +3231,7 @@ func (q *Queries) ListTransactionByAccount(ctx context.Context, arg ListTransact
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, username, password, created_at, updated_at FROM Users
+SELECT id, username, password, created_at, updated_at, reporting_currency FROM Users
 ORDER BY id
 `
 
@@ -781,6 +3250,7 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 			&i.Password,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ReportingCurrency,
 		); err != nil {
 			return nil, err
 		}
@@ -792,11 +3262,200 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
+const mergeTransaction = `-- name: MergeTransaction :exec
+UPDATE Transaction_Headers
+SET
+  external_id = COALESCE(external_id, $1),
+  metadata = $2::jsonb || metadata,
+  updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $3
+`
+
+type MergeTransactionParams struct {
+	ExternalID pgtype.Text     `json:"external_id"`
+	Metadata   json.RawMessage `json:"metadata"`
+	ID         int32           `json:"id"`
+}
+
+func (q *Queries) MergeTransaction(ctx context.Context, arg MergeTransactionParams) error {
+	_, err := q.db.Exec(ctx, mergeTransaction, arg.ExternalID, arg.Metadata, arg.ID)
+	return err
+}
+
+const purgeJournalEntriesTrashedBefore = `-- name: PurgeJournalEntriesTrashedBefore :execrows
+DELETE FROM Journal_Entries
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeJournalEntriesTrashedBefore(ctx context.Context, deletedAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeJournalEntriesTrashedBefore, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeTransactionsTrashedBefore = `-- name: PurgeTransactionsTrashedBefore :execrows
+DELETE FROM Transaction_Headers
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeTransactionsTrashedBefore(ctx context.Context, deletedAt pgtype.Timestamp) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeTransactionsTrashedBefore, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeOperator = `-- name: RemoveOperator :execrows
+DELETE FROM Operators
+WHERE user_id = $1
+`
+
+func (q *Queries) RemoveOperator(ctx context.Context, userID int32) (int64, error) {
+	result, err := q.db.Exec(ctx, removeOperator, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeTransactionTag = `-- name: RemoveTransactionTag :exec
+DELETE FROM Transaction_Tags AS tt
+USING Tags AS tg
+WHERE tt.tag_id = tg.id AND tt.transaction_id = $1 AND tg.name = $2
+`
+
+type RemoveTransactionTagParams struct {
+	TransactionID int32  `json:"transaction_id"`
+	Name          string `json:"name"`
+}
+
+func (q *Queries) RemoveTransactionTag(ctx context.Context, arg RemoveTransactionTagParams) error {
+	_, err := q.db.Exec(ctx, removeTransactionTag, arg.TransactionID, arg.Name)
+	return err
+}
+
+const restoreJournalEntry = `-- name: RestoreJournalEntry :exec
+UPDATE Journal_Entries
+  SET deleted_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) RestoreJournalEntry(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, restoreJournalEntry, id)
+	return err
+}
+
+const restoreTransactionsByJournalEntry = `-- name: RestoreTransactionsByJournalEntry :exec
+UPDATE Transaction_Headers
+  SET deleted_at = NULL
+WHERE journal_entry_id = $1
+`
+
+func (q *Queries) RestoreTransactionsByJournalEntry(ctx context.Context, journalEntryID int32) error {
+	_, err := q.db.Exec(ctx, restoreTransactionsByJournalEntry, journalEntryID)
+	return err
+}
+
+const setAccountBudgeting = `-- name: SetAccountBudgeting :one
+UPDATE Accounts
+  set budgeting_mode = $2, allow_negative_envelopes = $3, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+RETURNING id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes
+`
+
+type SetAccountBudgetingParams struct {
+	ID                     int32  `json:"id"`
+	BudgetingMode          string `json:"budgeting_mode"`
+	AllowNegativeEnvelopes bool   `json:"allow_negative_envelopes"`
+}
+
+func (q *Queries) SetAccountBudgeting(ctx context.Context, arg SetAccountBudgetingParams) (Account, error) {
+	row := q.db.QueryRow(ctx, setAccountBudgeting, arg.ID, arg.BudgetingMode, arg.AllowNegativeEnvelopes)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.AccountName,
+		&i.AccountType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
+	)
+	return i, err
+}
+
+const setRecurringOccurrenceTransaction = `-- name: SetRecurringOccurrenceTransaction :exec
+UPDATE Recurring_Occurrences
+  set transaction_id = $2
+WHERE id = $1
+`
+
+type SetRecurringOccurrenceTransactionParams struct {
+	ID            int32       `json:"id"`
+	TransactionID pgtype.Int4 `json:"transaction_id"`
+}
+
+func (q *Queries) SetRecurringOccurrenceTransaction(ctx context.Context, arg SetRecurringOccurrenceTransactionParams) error {
+	_, err := q.db.Exec(ctx, setRecurringOccurrenceTransaction, arg.ID, arg.TransactionID)
+	return err
+}
+
+const trashJournalEntry = `-- name: TrashJournalEntry :exec
+UPDATE Journal_Entries
+  SET deleted_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+`
+
+func (q *Queries) TrashJournalEntry(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, trashJournalEntry, id)
+	return err
+}
+
+const trashTransactionsByJournalEntry = `-- name: TrashTransactionsByJournalEntry :exec
+UPDATE Transaction_Headers
+  SET deleted_at = NOW() AT TIME ZONE 'utc'
+WHERE journal_entry_id = $1
+`
+
+func (q *Queries) TrashTransactionsByJournalEntry(ctx context.Context, journalEntryID int32) error {
+	_, err := q.db.Exec(ctx, trashTransactionsByJournalEntry, journalEntryID)
+	return err
+}
+
+const unarchiveAccount = `-- name: UnarchiveAccount :one
+UPDATE Accounts
+  set archived_at = NULL, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+RETURNING id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes
+`
+
+func (q *Queries) UnarchiveAccount(ctx context.Context, id int32) (Account, error) {
+	row := q.db.QueryRow(ctx, unarchiveAccount, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.AccountName,
+		&i.AccountType,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
+	)
+	return i, err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
 UPDATE Accounts
   set account_name = $2, account_type = $3, updated_at = NOW() AT TIME ZONE 'utc'
 WHERE id = $1
-RETURNING id, account_name, account_type, created_at, updated_at
+RETURNING id, account_name, account_type, created_at, updated_at, currency, archived_at, budgeting_mode, allow_negative_envelopes
 `
 
 type UpdateAccountParams struct {
@@ -814,36 +3473,111 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.AccountType,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+		&i.ArchivedAt,
+		&i.BudgetingMode,
+		&i.AllowNegativeEnvelopes,
 	)
 	return i, err
 }
 
-const updateAccountEvent = `-- name: UpdateAccountEvent :one
-UPDATE Account_Events
-  set description = $2, updated_at = NOW() AT TIME ZONE 'utc'
-WHERE id = $1
-RETURNING id, account_id, event_type_id, description, created_at, updated_at
+const updateCategory = `-- name: UpdateCategory :one
+UPDATE Categories
+  SET parent_id = $3, name = $4, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1 AND account_id = $2
+RETURNING id, account_id, parent_id, name, created_at, updated_at
 `
 
-type UpdateAccountEventParams struct {
-	ID          int32  `json:"id"`
-	Description string `json:"description"`
+type UpdateCategoryParams struct {
+	ID        int32       `json:"id"`
+	AccountID int32       `json:"account_id"`
+	ParentID  pgtype.Int4 `json:"parent_id"`
+	Name      string      `json:"name"`
 }
 
-func (q *Queries) UpdateAccountEvent(ctx context.Context, arg UpdateAccountEventParams) (AccountEvent, error) {
-	row := q.db.QueryRow(ctx, updateAccountEvent, arg.ID, arg.Description)
-	var i AccountEvent
+func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, updateCategory,
+		arg.ID,
+		arg.AccountID,
+		arg.ParentID,
+		arg.Name,
+	)
+	var i Category
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
-		&i.EventTypeID,
-		&i.Description,
+		&i.ParentID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateGoal = `-- name: UpdateGoal :one
+UPDATE Goals
+  SET name = COALESCE($1, name),
+      target_amount = COALESCE($2, target_amount),
+      target_date = COALESCE($3, target_date),
+      category_id = COALESCE($4, category_id),
+      start_date = COALESCE($5, start_date),
+      updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $6 AND account_id = $7
+RETURNING id, account_id, name, target_amount, target_date, category_id, start_date, created_at, updated_at
+`
+
+type UpdateGoalParams struct {
+	Name         pgtype.Text      `json:"name"`
+	TargetAmount money.NullAmount `json:"target_amount"`
+	TargetDate   pgtype.Date      `json:"target_date"`
+	CategoryID   pgtype.Int4      `json:"category_id"`
+	StartDate    pgtype.Date      `json:"start_date"`
+	ID           int32            `json:"id"`
+	AccountID    int32            `json:"account_id"`
+}
+
+func (q *Queries) UpdateGoal(ctx context.Context, arg UpdateGoalParams) (Goal, error) {
+	row := q.db.QueryRow(ctx, updateGoal,
+		arg.Name,
+		arg.TargetAmount,
+		arg.TargetDate,
+		arg.CategoryID,
+		arg.StartDate,
+		arg.ID,
+		arg.AccountID,
+	)
+	var i Goal
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.TargetAmount,
+		&i.TargetDate,
+		&i.CategoryID,
+		&i.StartDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const updateJournalEntry = `-- name: UpdateJournalEntry :exec
+UPDATE Journal_Entries
+  SET entry_date = $2, description = $3, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+`
+
+type UpdateJournalEntryParams struct {
+	ID          int32       `json:"id"`
+	EntryDate   pgtype.Date `json:"entry_date"`
+	Description string      `json:"description"`
+}
+
+func (q *Queries) UpdateJournalEntry(ctx context.Context, arg UpdateJournalEntryParams) error {
+	_, err := q.db.Exec(ctx, updateJournalEntry, arg.ID, arg.EntryDate, arg.Description)
+	return err
+}
+
 const updateMember = `-- name: UpdateMember :one
 UPDATE Members
   set member_role_id = $3, updated_at = NOW() AT TIME ZONE 'utc'
@@ -871,57 +3605,167 @@ func (q *Queries) UpdateMember(ctx context.Context, arg UpdateMemberParams) (Mem
 	return i, err
 }
 
-const updateTransaction = `-- name: UpdateTransaction :one
-UPDATE Transactions
-  SET amount = $2, description = $3, updated_at = NOW() AT TIME ZONE 'utc'
-  WHERE id = $1
-  RETURNING id, account_id, user_id, transaction_date, transaction_type_id, amount, created_at, updated_at, description
+const updateTransaction = `-- name: UpdateTransaction :exec
+UPDATE Transaction_Headers
+  SET description = COALESCE($1, description),
+      updated_at = NOW() AT TIME ZONE 'utc'
+  WHERE id = $2
 `
 
 type UpdateTransactionParams struct {
-	ID          int32   `json:"id"`
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
+	Description pgtype.Text `json:"description"`
+	ID          int32       `json:"id"`
 }
 
-func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) (Transaction, error) {
-	row := q.db.QueryRow(ctx, updateTransaction, arg.ID, arg.Amount, arg.Description)
-	var i Transaction
+func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) error {
+	_, err := q.db.Exec(ctx, updateTransaction, arg.Description, arg.ID)
+	return err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE Users
+  set password = COALESCE($1, password),
+      reporting_currency = COALESCE($2, reporting_currency),
+      updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $3
+RETURNING id, username, password, created_at, updated_at, reporting_currency
+`
+
+type UpdateUserParams struct {
+	Password          pgtype.Text `json:"password"`
+	ReportingCurrency pgtype.Text `json:"reporting_currency"`
+	ID                int32       `json:"id"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUser, arg.Password, arg.ReportingCurrency, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ReportingCurrency,
+	)
+	return i, err
+}
+
+const upsertBudget = `-- name: UpsertBudget :one
+INSERT INTO Budgets (
+  account_id, category_id, month, amount, rollover
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (category_id, month) DO UPDATE
+  set amount = EXCLUDED.amount, rollover = EXCLUDED.rollover, updated_at = NOW() AT TIME ZONE 'utc'
+RETURNING id, account_id, category_id, month, amount, rollover, created_at, updated_at
+`
+
+type UpsertBudgetParams struct {
+	AccountID  int32        `json:"account_id"`
+	CategoryID int32        `json:"category_id"`
+	Month      pgtype.Date  `json:"month"`
+	Amount     money.Amount `json:"amount"`
+	Rollover   bool         `json:"rollover"`
+}
+
+func (q *Queries) UpsertBudget(ctx context.Context, arg UpsertBudgetParams) (Budget, error) {
+	row := q.db.QueryRow(ctx, upsertBudget,
+		arg.AccountID,
+		arg.CategoryID,
+		arg.Month,
+		arg.Amount,
+		arg.Rollover,
+	)
+	var i Budget
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
-		&i.UserID,
-		&i.TransactionDate,
-		&i.TransactionTypeID,
+		&i.CategoryID,
+		&i.Month,
 		&i.Amount,
+		&i.Rollover,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.Description,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :one
-UPDATE Users
-  set password = $2, updated_at = NOW() AT TIME ZONE 'utc'
-WHERE id = $1
-RETURNING id, username, password, created_at, updated_at
+const upsertExchangeRate = `-- name: UpsertExchangeRate :one
+INSERT INTO Exchange_Rates (
+  rate_date, base_currency, quote_currency, rate
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (rate_date, base_currency, quote_currency)
+DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE Exchange_Rates.rate <> EXCLUDED.rate
+RETURNING id, rate_date, base_currency, quote_currency, rate, created_at, updated_at, (xmax = 0)::boolean AS inserted
 `
 
-type UpdateUserParams struct {
-	ID       int32  `json:"id"`
-	Password string `json:"password"`
+type UpsertExchangeRateParams struct {
+	RateDate      pgtype.Date `json:"rate_date"`
+	BaseCurrency  string      `json:"base_currency"`
+	QuoteCurrency string      `json:"quote_currency"`
+	Rate          money.Rate  `json:"rate"`
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, updateUser, arg.ID, arg.Password)
-	var i User
+type UpsertExchangeRateRow struct {
+	ID            int32            `json:"id"`
+	RateDate      pgtype.Date      `json:"rate_date"`
+	BaseCurrency  string           `json:"base_currency"`
+	QuoteCurrency string           `json:"quote_currency"`
+	Rate          money.Rate       `json:"rate"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+	Inserted      bool             `json:"inserted"`
+}
+
+func (q *Queries) UpsertExchangeRate(ctx context.Context, arg UpsertExchangeRateParams) (UpsertExchangeRateRow, error) {
+	row := q.db.QueryRow(ctx, upsertExchangeRate,
+		arg.RateDate,
+		arg.BaseCurrency,
+		arg.QuoteCurrency,
+		arg.Rate,
+	)
+	var i UpsertExchangeRateRow
 	err := row.Scan(
 		&i.ID,
-		&i.Username,
-		&i.Password,
+		&i.RateDate,
+		&i.BaseCurrency,
+		&i.QuoteCurrency,
+		&i.Rate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Inserted,
+	)
+	return i, err
+}
+
+const upsertTag = `-- name: UpsertTag :one
+INSERT INTO Tags (
+  account_id, name
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (account_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, account_id, name, created_at
+`
+
+type UpsertTagParams struct {
+	AccountID int32  `json:"account_id"`
+	Name      string `json:"name"`
+}
+
+func (q *Queries) UpsertTag(ctx context.Context, arg UpsertTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, upsertTag, arg.AccountID, arg.Name)
+	var i Tag
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Name,
+		&i.CreatedAt,
 	)
 	return i, err
 }
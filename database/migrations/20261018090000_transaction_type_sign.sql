@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Transaction_Types ADD COLUMN sign INT NOT NULL DEFAULT 1;
+ALTER TABLE Transaction_Types ADD CONSTRAINT chk_transaction_type_sign CHECK (sign IN (-1, 1));
+
+INSERT INTO Transaction_Types (name)
+SELECT 'income'
+WHERE NOT EXISTS (SELECT 1 FROM Transaction_Types WHERE name = 'income');
+
+INSERT INTO Transaction_Types (name)
+SELECT 'expense'
+WHERE NOT EXISTS (SELECT 1 FROM Transaction_Types WHERE name = 'expense');
+
+UPDATE Transaction_Types SET sign = -1 WHERE name = 'expense';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Transaction_Types DROP CONSTRAINT chk_transaction_type_sign;
+ALTER TABLE Transaction_Types DROP COLUMN sign;
+-- +goose StatementEnd
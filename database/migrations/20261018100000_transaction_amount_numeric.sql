@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Transactions
+ALTER COLUMN amount TYPE NUMERIC(19, 4) USING ROUND(amount::numeric, 4);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Transactions
+ALTER COLUMN amount TYPE FLOAT USING amount::float;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Accounts ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+ALTER TABLE Transactions ADD COLUMN currency TEXT;
+ALTER TABLE Users ADD COLUMN reporting_currency TEXT NOT NULL DEFAULT 'USD';
+
+CREATE TABLE Exchange_Rates (
+    id SERIAL PRIMARY KEY,
+    rate_date DATE NOT NULL,
+    base_currency TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT uq_exchange_rate UNIQUE (rate_date, base_currency, quote_currency),
+    CONSTRAINT chk_exchange_rate_positive CHECK (rate > 0)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Exchange_Rates;
+ALTER TABLE Users DROP COLUMN reporting_currency;
+ALTER TABLE Transactions DROP COLUMN currency;
+ALTER TABLE Accounts DROP COLUMN currency;
+-- +goose StatementEnd
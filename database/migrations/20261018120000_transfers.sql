@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Transfers (
+    id SERIAL PRIMARY KEY,
+    from_account_id INT NOT NULL,
+    to_account_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_transfer_from_account FOREIGN KEY (from_account_id) REFERENCES Accounts(id),
+    CONSTRAINT fk_transfer_to_account FOREIGN KEY (to_account_id) REFERENCES Accounts(id),
+    CONSTRAINT fk_transfer_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT chk_transfer_accounts CHECK (from_account_id <> to_account_id)
+);
+
+ALTER TABLE Transactions ADD COLUMN transfer_id INT;
+ALTER TABLE Transactions ADD CONSTRAINT fk_transaction_transfer FOREIGN KEY (transfer_id) REFERENCES Transfers(id);
+
+INSERT INTO Transaction_Types (name, sign)
+SELECT 'transfer_in', 1
+WHERE NOT EXISTS (SELECT 1 FROM Transaction_Types WHERE name = 'transfer_in');
+
+INSERT INTO Transaction_Types (name, sign)
+SELECT 'transfer_out', -1
+WHERE NOT EXISTS (SELECT 1 FROM Transaction_Types WHERE name = 'transfer_out');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Transactions DROP CONSTRAINT fk_transaction_transfer;
+ALTER TABLE Transactions DROP COLUMN transfer_id;
+DROP TABLE Transfers;
+-- +goose StatementEnd
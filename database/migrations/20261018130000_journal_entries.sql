@@ -0,0 +1,209 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Journal_Entries (
+    id SERIAL PRIMARY KEY,
+    entry_date DATE NOT NULL,
+    description TEXT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_journal_entry_user FOREIGN KEY (user_id) REFERENCES Users(id)
+);
+
+-- Every account gets its own chart of accounts: the asset account holding
+-- its money, the income and expense accounts money comes from and goes to,
+-- and an equity account for what is neither, such as currency exchange.
+CREATE TABLE Ledger_Accounts (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    kind TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_ledger_account_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT chk_ledger_account_kind CHECK (kind IN ('asset', 'income', 'expense', 'equity')),
+    CONSTRAINT uq_ledger_account_kind UNIQUE (account_id, kind)
+);
+
+INSERT INTO Ledger_Accounts (account_id, kind)
+SELECT acc.id, kinds.kind
+FROM Accounts AS acc
+CROSS JOIN (VALUES ('asset'), ('income'), ('expense'), ('equity')) AS kinds (kind)
+ORDER BY acc.id;
+
+CREATE FUNCTION create_ledger_accounts() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO Ledger_Accounts (account_id, kind)
+    SELECT NEW.id, kinds.kind
+    FROM (VALUES ('asset'), ('income'), ('expense'), ('equity')) AS kinds (kind);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_accounts_ledger_accounts
+AFTER INSERT ON Accounts
+FOR EACH ROW EXECUTE FUNCTION create_ledger_accounts();
+
+CREATE TABLE Postings (
+    id SERIAL PRIMARY KEY,
+    journal_entry_id INT NOT NULL,
+    transaction_id INT,
+    currency TEXT NOT NULL,
+    amount NUMERIC(19, 4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    ledger_account_id INT NOT NULL,
+    CONSTRAINT fk_posting_journal_entry FOREIGN KEY (journal_entry_id) REFERENCES Journal_Entries(id) ON DELETE CASCADE,
+    CONSTRAINT fk_posting_transaction FOREIGN KEY (transaction_id) REFERENCES Transactions(id) ON DELETE CASCADE,
+    CONSTRAINT fk_posting_ledger_account FOREIGN KEY (ledger_account_id) REFERENCES Ledger_Accounts(id)
+);
+
+CREATE INDEX idx_postings_journal_entry ON Postings (journal_entry_id);
+CREATE INDEX idx_postings_ledger_account ON Postings (ledger_account_id);
+CREATE INDEX idx_postings_transaction ON Postings (transaction_id);
+
+ALTER TABLE Transactions ADD COLUMN journal_entry_id INT;
+
+-- Every existing transaction gets its own entry, except transfer legs which
+-- share the entry of their transfer.
+UPDATE Transactions
+SET journal_entry_id = nextval('journal_entries_id_seq')
+WHERE transfer_id IS NULL;
+
+UPDATE Transactions AS t
+SET journal_entry_id = entries.id
+FROM (
+    SELECT transfer_id, nextval('journal_entries_id_seq') AS id
+    FROM Transactions
+    WHERE transfer_id IS NOT NULL
+    GROUP BY transfer_id
+) AS entries
+WHERE t.transfer_id = entries.transfer_id;
+
+INSERT INTO Journal_Entries (id, entry_date, description, user_id)
+SELECT DISTINCT ON (journal_entry_id) journal_entry_id, transaction_date, description, user_id
+FROM Transactions
+ORDER BY journal_entry_id, id;
+
+-- The asset side: one posting per transaction.
+INSERT INTO Postings (journal_entry_id, ledger_account_id, transaction_id, currency, amount)
+SELECT t.journal_entry_id, la.id, t.id, COALESCE(t.currency, acc.currency), t.amount * tt.sign
+FROM Transactions AS t
+JOIN Accounts AS acc ON acc.id = t.account_id
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Ledger_Accounts AS la ON la.account_id = t.account_id AND la.kind = 'asset'
+WHERE t.amount <> 0
+ORDER BY t.id;
+
+-- Income is booked against the income account and expenses against the
+-- expense account.
+INSERT INTO Postings (journal_entry_id, ledger_account_id, transaction_id, currency, amount)
+SELECT p.journal_entry_id, counter.id, p.transaction_id, p.currency, -p.amount
+FROM Postings AS p
+JOIN Transactions AS t ON t.id = p.transaction_id
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Ledger_Accounts AS counter ON counter.account_id = t.account_id
+    AND counter.kind = CASE WHEN tt.sign > 0 THEN 'income' ELSE 'expense' END
+WHERE tt.name NOT IN ('transfer_in', 'transfer_out')
+ORDER BY p.id;
+
+-- Transfer legs balance each other. What they leave open in a currency,
+-- between accounts of different currencies, goes to the equity of the
+-- account whose leg is in that currency.
+INSERT INTO Postings (journal_entry_id, ledger_account_id, transaction_id, currency, amount)
+SELECT open.journal_entry_id, la.id, t.id, open.currency, -open.amount
+FROM (
+    SELECT journal_entry_id, currency, SUM(amount) AS amount, MIN(transaction_id) AS transaction_id
+    FROM Postings
+    GROUP BY journal_entry_id, currency
+    HAVING SUM(amount) <> 0
+) AS open
+JOIN Transactions AS t ON t.id = open.transaction_id
+JOIN Ledger_Accounts AS la ON la.account_id = t.account_id AND la.kind = 'equity'
+ORDER BY open.journal_entry_id, open.currency;
+
+-- Amounts now only live in the postings; transactions are read from them.
+ALTER TABLE Transactions ALTER COLUMN journal_entry_id SET NOT NULL;
+ALTER TABLE Transactions ADD CONSTRAINT fk_transaction_journal_entry FOREIGN KEY (journal_entry_id) REFERENCES Journal_Entries(id);
+ALTER TABLE Transactions DROP COLUMN amount;
+ALTER TABLE Transactions RENAME TO Transaction_Headers;
+
+-- A transaction moves what its postings on the asset account add up to.
+CREATE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    entry_id INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        entry_id := OLD.journal_entry_id;
+    ELSE
+        entry_id := NEW.journal_entry_id;
+    END IF;
+
+    IF (SELECT COUNT(*) FROM Postings WHERE journal_entry_id = entry_id) = 1 THEN
+        RAISE EXCEPTION 'journal entry % needs at least two postings', entry_id;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM Postings
+        WHERE journal_entry_id = entry_id
+        GROUP BY currency
+        HAVING SUM(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', entry_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trg_postings_balanced
+AFTER INSERT OR UPDATE OR DELETE ON Postings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER trg_postings_balanced ON Postings;
+DROP FUNCTION check_journal_entry_balanced();
+
+ALTER TABLE Transaction_Headers ADD COLUMN amount NUMERIC(19, 4);
+
+UPDATE Transaction_Headers AS h
+SET amount = t.amount
+FROM Transactions AS t
+WHERE t.id = h.id;
+
+DROP VIEW Transactions;
+ALTER TABLE Transaction_Headers RENAME TO Transactions;
+ALTER TABLE Transactions ALTER COLUMN amount SET NOT NULL;
+ALTER TABLE Transactions DROP CONSTRAINT fk_transaction_journal_entry;
+ALTER TABLE Transactions DROP COLUMN journal_entry_id;
+
+DROP TABLE Postings;
+DROP TRIGGER trg_accounts_ledger_accounts ON Accounts;
+DROP FUNCTION create_ledger_accounts();
+DROP TABLE Ledger_Accounts;
+DROP TABLE Journal_Entries;
+-- +goose StatementEnd
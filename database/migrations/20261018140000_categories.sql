@@ -0,0 +1,77 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Categories (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    parent_id INT,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_category_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_category_parent FOREIGN KEY (parent_id) REFERENCES Categories(id),
+    CONSTRAINT chk_category_parent CHECK (parent_id <> id)
+);
+
+CREATE UNIQUE INDEX uq_category_name ON Categories (account_id, COALESCE(parent_id, 0), name);
+
+-- The category of a transaction is kept on its postings, both on the asset
+-- account and on the income or expense account.
+ALTER TABLE Postings ADD COLUMN category_id INT;
+ALTER TABLE Postings ADD CONSTRAINT fk_posting_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE SET NULL;
+
+CREATE OR REPLACE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    lines.category_id
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW Transactions;
+
+CREATE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+
+ALTER TABLE Postings DROP CONSTRAINT fk_posting_category;
+ALTER TABLE Postings DROP COLUMN category_id;
+DROP TABLE Categories;
+-- +goose StatementEnd
@@ -0,0 +1,81 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A split transaction is booked with one line per category on the asset
+-- account and on the income or expense account; each line keeps its memo.
+ALTER TABLE Postings ADD COLUMN memo TEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    CASE WHEN lines.count = 1 THEN lines.category_id END AS category_id
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, COUNT(*) AS count, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+
+CREATE VIEW Transaction_Splits AS
+SELECT
+    p.id,
+    p.transaction_id,
+    p.category_id,
+    (tt.sign * p.amount)::numeric(19, 4) AS amount,
+    p.memo,
+    p.created_at,
+    p.created_at AS updated_at
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id AND la.kind = 'asset'
+JOIN Transaction_Headers AS h ON h.id = p.transaction_id
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+WHERE (
+    SELECT COUNT(*)
+    FROM Postings AS other
+    JOIN Ledger_Accounts AS other_la ON other_la.id = other.ledger_account_id AND other_la.kind = 'asset'
+    WHERE other.transaction_id = p.transaction_id
+) > 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW Transaction_Splits;
+
+CREATE OR REPLACE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    lines.category_id
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+
+ALTER TABLE Postings DROP COLUMN memo;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Tags (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_tag_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT uq_tag_name UNIQUE (account_id, name)
+);
+
+CREATE TABLE Transaction_Tags (
+    transaction_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    PRIMARY KEY (transaction_id, tag_id),
+    CONSTRAINT fk_transaction_tag_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_tag_tag FOREIGN KEY (tag_id) REFERENCES Tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_transaction_tags_tag ON Transaction_Tags (tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Transaction_Tags;
+DROP TABLE Tags;
+-- +goose StatementEnd
@@ -0,0 +1,65 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Transaction_Headers ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE Journal_Entries ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_transactions_deleted_at ON Transaction_Headers (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    CASE WHEN lines.count = 1 THEN lines.category_id END AS category_id,
+    h.deleted_at
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, COUNT(*) AS count, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW Transactions;
+
+CREATE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    CASE WHEN lines.count = 1 THEN lines.category_id END AS category_id
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, COUNT(*) AS count, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+
+DROP INDEX idx_transactions_deleted_at;
+ALTER TABLE Journal_Entries DROP COLUMN deleted_at;
+ALTER TABLE Transaction_Headers DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Accounts ADD COLUMN archived_at TIMESTAMP;
+
+CREATE TABLE Account_Deletions (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    account_name TEXT NOT NULL,
+    deleted_by INT NOT NULL,
+    transaction_count INT NOT NULL,
+    deleted_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_account_deletion_user FOREIGN KEY (deleted_by) REFERENCES Users(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Account_Deletions;
+ALTER TABLE Accounts DROP COLUMN archived_at;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Account_Events ADD COLUMN user_id INT;
+ALTER TABLE Account_Events ADD COLUMN diff JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE Account_Events ADD CONSTRAINT fk_account_event_user FOREIGN KEY (user_id) REFERENCES Users(id);
+
+CREATE INDEX idx_account_events_account ON Account_Events (account_id, id);
+
+ALTER TABLE Event_Types ADD CONSTRAINT uq_event_type_name UNIQUE (name);
+
+INSERT INTO Event_Types (name)
+SELECT name
+FROM (VALUES
+    ('account_created'),
+    ('account_updated'),
+    ('account_archived'),
+    ('account_unarchived'),
+    ('member_added'),
+    ('member_updated'),
+    ('member_removed'),
+    ('transaction_created'),
+    ('transaction_updated'),
+    ('transaction_deleted'),
+    ('transaction_restored')
+) AS seed (name)
+WHERE NOT EXISTS (SELECT 1 FROM Event_Types WHERE Event_Types.name = seed.name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Event_Types DROP CONSTRAINT uq_event_type_name;
+DROP INDEX idx_account_events_account;
+ALTER TABLE Account_Events DROP CONSTRAINT fk_account_event_user;
+ALTER TABLE Account_Events DROP COLUMN diff;
+ALTER TABLE Account_Events DROP COLUMN user_id;
+-- +goose StatementEnd
@@ -0,0 +1,79 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Account_Events ADD COLUMN prev_hash TEXT;
+ALTER TABLE Account_Events ADD COLUMN hash TEXT;
+
+-- The hash covers everything an event says plus the hash of the event before
+-- it, so rewriting or removing an event breaks every link after it.
+CREATE FUNCTION account_event_hash(event Account_Events) RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(jsonb_build_array(
+        event.id,
+        event.account_id,
+        event.event_type_id,
+        event.user_id,
+        event.description,
+        event.diff,
+        event.created_at,
+        event.prev_hash
+    )::text, 'UTF8')), 'hex');
+$$ LANGUAGE sql IMMUTABLE;
+
+DO $$
+DECLARE
+    event Account_Events;
+    previous TEXT;
+    current_account INT;
+BEGIN
+    FOR event IN SELECT * FROM Account_Events ORDER BY account_id, id LOOP
+        IF current_account IS DISTINCT FROM event.account_id THEN
+            previous := NULL;
+            current_account := event.account_id;
+        END IF;
+
+        event.prev_hash := previous;
+        event.hash := account_event_hash(event);
+
+        UPDATE Account_Events
+        SET prev_hash = event.prev_hash, hash = event.hash
+        WHERE id = event.id;
+
+        previous := event.hash;
+    END LOOP;
+END;
+$$;
+
+ALTER TABLE Account_Events ALTER COLUMN hash SET NOT NULL;
+
+CREATE FUNCTION chain_account_event() RETURNS trigger AS $$
+BEGIN
+    -- Writers of the same account wait for each other so the chain cannot
+    -- fork, and the id is only taken once the lock is held so ids follow the
+    -- chain order.
+    PERFORM 1 FROM Accounts WHERE id = NEW.account_id FOR NO KEY UPDATE;
+
+    NEW.id := nextval('account_events_id_seq');
+    NEW.prev_hash := (
+        SELECT hash FROM Account_Events
+        WHERE account_id = NEW.account_id
+        ORDER BY id DESC
+        LIMIT 1
+    );
+    NEW.hash := account_event_hash(NEW);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_account_events_chain
+BEFORE INSERT ON Account_Events
+FOR EACH ROW EXECUTE FUNCTION chain_account_event();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER trg_account_events_chain ON Account_Events;
+DROP FUNCTION chain_account_event();
+DROP FUNCTION account_event_hash(Account_Events);
+ALTER TABLE Account_Events DROP COLUMN hash;
+ALTER TABLE Account_Events DROP COLUMN prev_hash;
+-- +goose StatementEnd
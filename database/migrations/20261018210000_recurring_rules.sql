@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Recurring_Rules (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    user_id INT NOT NULL,
+    transaction_type_id INT NOT NULL,
+    amount NUMERIC(19, 4) NOT NULL,
+    currency TEXT,
+    description TEXT NOT NULL,
+    category_id INT,
+    frequency TEXT NOT NULL,
+    interval_count INT NOT NULL DEFAULT 1,
+    day_of_month INT,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    occurrence_count INT,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_recurring_rule_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_rule_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT fk_recurring_rule_transaction_type FOREIGN KEY (transaction_type_id) REFERENCES Transaction_Types(id),
+    CONSTRAINT fk_recurring_rule_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE SET NULL,
+    CONSTRAINT chk_recurring_rule_frequency CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    CONSTRAINT chk_recurring_rule_interval CHECK (interval_count > 0),
+    CONSTRAINT chk_recurring_rule_day_of_month CHECK (day_of_month BETWEEN 1 AND 31),
+    CONSTRAINT chk_recurring_rule_dates CHECK (end_date >= start_date),
+    CONSTRAINT chk_recurring_rule_count CHECK (occurrence_count > 0)
+);
+
+CREATE INDEX idx_recurring_rules_account ON Recurring_Rules (account_id);
+
+-- One row per rule and date, whether it was posted or skipped. The unique
+-- constraint is what keeps an occurrence from being posted twice.
+CREATE TABLE Recurring_Occurrences (
+    id SERIAL PRIMARY KEY,
+    rule_id INT NOT NULL,
+    occurrence_date DATE NOT NULL,
+    status TEXT NOT NULL,
+    transaction_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_recurring_occurrence_rule FOREIGN KEY (rule_id) REFERENCES Recurring_Rules(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_occurrence_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE SET NULL,
+    CONSTRAINT chk_recurring_occurrence_status CHECK (status IN ('posted', 'skipped')),
+    CONSTRAINT uq_recurring_occurrence UNIQUE (rule_id, occurrence_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Recurring_Occurrences;
+DROP TABLE Recurring_Rules;
+-- +goose StatementEnd
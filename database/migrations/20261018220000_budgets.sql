@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Budgets (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    category_id INT NOT NULL,
+    month DATE NOT NULL,
+    amount NUMERIC(19, 4) NOT NULL,
+    rollover BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_budget_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_budget_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE CASCADE,
+    CONSTRAINT chk_budget_amount CHECK (amount >= 0),
+    CONSTRAINT chk_budget_month CHECK (EXTRACT(DAY FROM month) = 1),
+    CONSTRAINT uq_budget UNIQUE (category_id, month)
+);
+
+CREATE INDEX idx_budgets_account_month ON Budgets (account_id, month);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Budgets;
+-- +goose StatementEnd
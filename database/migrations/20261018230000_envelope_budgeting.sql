@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Accounts ADD COLUMN budgeting_mode TEXT NOT NULL DEFAULT 'monthly';
+ALTER TABLE Accounts ADD COLUMN allow_negative_envelopes BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE Accounts ADD CONSTRAINT chk_account_budgeting_mode CHECK (budgeting_mode IN ('monthly', 'envelope'));
+
+CREATE TABLE Envelope_Moves (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    user_id INT NOT NULL,
+    from_category_id INT,
+    to_category_id INT,
+    amount NUMERIC(19, 4) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_envelope_move_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_envelope_move_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT fk_envelope_move_from FOREIGN KEY (from_category_id) REFERENCES Categories(id) ON DELETE CASCADE,
+    CONSTRAINT fk_envelope_move_to FOREIGN KEY (to_category_id) REFERENCES Categories(id) ON DELETE CASCADE,
+    CONSTRAINT chk_envelope_move_amount CHECK (amount > 0),
+    CONSTRAINT chk_envelope_move_envelopes CHECK (from_category_id IS DISTINCT FROM to_category_id)
+);
+
+CREATE INDEX idx_envelope_moves_account ON Envelope_Moves (account_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Envelope_Moves;
+ALTER TABLE Accounts DROP CONSTRAINT chk_account_budgeting_mode;
+ALTER TABLE Accounts DROP COLUMN allow_negative_envelopes;
+ALTER TABLE Accounts DROP COLUMN budgeting_mode;
+-- +goose StatementEnd
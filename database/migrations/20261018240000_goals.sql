@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Goals (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    target_amount NUMERIC(19, 4) NOT NULL,
+    target_date DATE NOT NULL,
+    category_id INT,
+    start_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_goal_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_goal_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE SET NULL,
+    CONSTRAINT chk_goal_target_amount CHECK (target_amount > 0),
+    CONSTRAINT chk_goal_dates CHECK (target_date >= start_date)
+);
+
+CREATE INDEX idx_goals_account ON Goals (account_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Goals;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Transaction_Shares (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL,
+    user_id INT NOT NULL,
+    method TEXT NOT NULL,
+    value NUMERIC(19, 4),
+    amount NUMERIC(19, 4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_transaction_share_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_share_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT chk_transaction_share_method CHECK (method IN ('equal', 'exact', 'percent', 'weight')),
+    CONSTRAINT chk_transaction_share_amount CHECK (amount >= 0),
+    CONSTRAINT uq_transaction_share UNIQUE (transaction_id, user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Transaction_Shares;
+-- +goose StatementEnd
@@ -0,0 +1,72 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Transaction_Types DROP CONSTRAINT chk_transaction_type_sign;
+ALTER TABLE Transaction_Types ADD CONSTRAINT chk_transaction_type_sign CHECK (sign IN (-1, 0, 1));
+
+INSERT INTO Transaction_Types (name, sign)
+SELECT 'settlement', 0
+WHERE NOT EXISTS (SELECT 1 FROM Transaction_Types WHERE name = 'settlement');
+
+CREATE OR REPLACE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (CASE WHEN tt.sign = 0 THEN COALESCE(shares.amount, 0) ELSE tt.sign * COALESCE(lines.amount, 0) END)::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    CASE WHEN lines.count = 1 THEN lines.category_id END AS category_id,
+    h.deleted_at
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, COUNT(*) AS count, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE
+LEFT JOIN LATERAL (
+    SELECT SUM(s.amount) AS amount
+    FROM Transaction_Shares AS s
+    WHERE s.transaction_id = h.id
+) AS shares ON TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (tt.sign * COALESCE(lines.amount, 0))::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    CASE WHEN lines.count = 1 THEN lines.category_id END AS category_id,
+    h.deleted_at
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, COUNT(*) AS count, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE;
+
+DELETE FROM Transaction_Types WHERE name = 'settlement';
+
+ALTER TABLE Transaction_Types DROP CONSTRAINT chk_transaction_type_sign;
+ALTER TABLE Transaction_Types ADD CONSTRAINT chk_transaction_type_sign CHECK (sign IN (-1, 1));
+-- +goose StatementEnd
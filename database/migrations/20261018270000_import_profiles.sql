@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE Import_Profiles (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    delimiter TEXT NOT NULL DEFAULT ',',
+    skip_rows INT NOT NULL DEFAULT 1,
+    date_column INT NOT NULL,
+    date_format TEXT NOT NULL DEFAULT 'YYYY-MM-DD',
+    description_columns INT[] NOT NULL DEFAULT '{}',
+    amount_column INT,
+    debit_column INT,
+    credit_column INT,
+    decimal_separator TEXT NOT NULL DEFAULT '.',
+    negate_amounts BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_import_profile_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT chk_import_profile_amount CHECK (amount_column IS NOT NULL OR debit_column IS NOT NULL OR credit_column IS NOT NULL),
+    CONSTRAINT chk_import_profile_decimal_separator CHECK (decimal_separator IN ('.', ',')),
+    CONSTRAINT chk_import_profile_skip_rows CHECK (skip_rows >= 0),
+    CONSTRAINT uq_import_profile_name UNIQUE (account_id, name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Import_Profiles;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_transaction_headers_account_date ON Transaction_Headers (account_id, transaction_date) WHERE deleted_at IS NULL;
+
+INSERT INTO Event_Types (name)
+SELECT 'transaction_merged'
+WHERE NOT EXISTS (SELECT 1 FROM Event_Types WHERE name = 'transaction_merged');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM Event_Types WHERE name = 'transaction_merged';
+
+DROP INDEX idx_transaction_headers_account_date;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Deleting a category no longer erases the moves into and out of its
+-- envelope; a category with moves cannot be deleted. The check runs at the end
+-- of the statement, so deleting a whole account still removes both.
+ALTER TABLE Envelope_Moves DROP CONSTRAINT fk_envelope_move_from;
+ALTER TABLE Envelope_Moves DROP CONSTRAINT fk_envelope_move_to;
+ALTER TABLE Envelope_Moves ADD CONSTRAINT fk_envelope_move_from FOREIGN KEY (from_category_id) REFERENCES Categories(id);
+ALTER TABLE Envelope_Moves ADD CONSTRAINT fk_envelope_move_to FOREIGN KEY (to_category_id) REFERENCES Categories(id);
+
+CREATE INDEX idx_envelope_moves_from ON Envelope_Moves (from_category_id);
+CREATE INDEX idx_envelope_moves_to ON Envelope_Moves (to_category_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_envelope_moves_to;
+DROP INDEX idx_envelope_moves_from;
+
+ALTER TABLE Envelope_Moves DROP CONSTRAINT fk_envelope_move_to;
+ALTER TABLE Envelope_Moves DROP CONSTRAINT fk_envelope_move_from;
+ALTER TABLE Envelope_Moves ADD CONSTRAINT fk_envelope_move_from FOREIGN KEY (from_category_id) REFERENCES Categories(id) ON DELETE CASCADE;
+ALTER TABLE Envelope_Moves ADD CONSTRAINT fk_envelope_move_to FOREIGN KEY (to_category_id) REFERENCES Categories(id) ON DELETE CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Operators look after data shared by every account, such as exchange rates.
+CREATE TABLE Operators (
+    user_id INT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_operator_user FOREIGN KEY (user_id) REFERENCES Users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Operators;
+-- +goose StatementEnd
@@ -22,20 +22,44 @@ RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE Users
-  set password = $2, updated_at = NOW() AT TIME ZONE 'utc'
-WHERE id = $1
+  set password = COALESCE(sqlc.narg(password), password),
+      reporting_currency = COALESCE(sqlc.narg(reporting_currency), reporting_currency),
+      updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 -- name: DeleteUser :exec
 DELETE FROM Users
 WHERE id = $1;
 
+-- OPERATORS
+
+-- name: IsOperator :one
+SELECT EXISTS (
+  SELECT 1 FROM Operators
+  WHERE user_id = $1
+);
+
+-- name: AddOperator :exec
+INSERT INTO Operators (user_id)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveOperator :execrows
+DELETE FROM Operators
+WHERE user_id = $1;
+
 -- ACCOUNTS
 
 -- name: GetAccount :one
 SELECT * FROM Accounts
 WHERE id = $1 LIMIT 1;
 
+-- name: GetAccountForUpdate :one
+SELECT * FROM Accounts
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
 -- name: GetAccountWithUserCheck :one
 SELECT 
     acc.*,
@@ -47,6 +71,15 @@ FROM Accounts acc
 JOIN Members mem ON acc.id = mem.account_id AND mem.user_id = $2
 WHERE acc.id = $1;
 
+-- name: ListAccountBalanceByCurrency :many
+SELECT p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = sqlc.arg(account_id)::int AND la.kind = 'asset' AND je.entry_date <= sqlc.arg(as_of)::date AND je.deleted_at IS NULL
+GROUP BY p.currency, je.entry_date
+ORDER BY je.entry_date;
+
 -- name: ListAccount :many
 SELECT * FROM Accounts
 ORDER BY id;
@@ -56,13 +89,13 @@ SELECT
   	acc.*
 FROM Accounts acc
 JOIN Members mem ON acc.id = mem.account_id
-WHERE mem.user_id = $1;
+WHERE mem.user_id = sqlc.arg(user_id) AND (sqlc.arg(include_archived)::boolean OR acc.archived_at IS NULL);
 
 -- name: CreateAccount :one
 INSERT INTO Accounts (
-  account_name, account_type
+  account_name, account_type, currency
 ) VALUES (
-  $1, $2
+  $1, $2, $3
 )
 RETURNING *;
 
@@ -72,10 +105,38 @@ UPDATE Accounts
 WHERE id = $1
 RETURNING *;
 
+-- name: ArchiveAccount :one
+UPDATE Accounts
+  set archived_at = COALESCE(archived_at, NOW() AT TIME ZONE 'utc'), updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+RETURNING *;
+
+-- name: UnarchiveAccount :one
+UPDATE Accounts
+  set archived_at = NULL, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+RETURNING *;
+
+-- name: SetAccountBudgeting :one
+UPDATE Accounts
+  set budgeting_mode = $2, allow_negative_envelopes = $3, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM Accounts
 WHERE id = $1;
 
+-- ACCOUNT_DELETIONS
+
+-- name: CreateAccountDeletion :one
+INSERT INTO Account_Deletions (
+  account_id, account_name, deleted_by, transaction_count
+) VALUES (
+  $1, $2, $3, $4
+)
+RETURNING *;
+
 -- ACCOUNT_EVENTS
 
 -- name: GetAccountEvent :one
@@ -87,27 +148,29 @@ SELECT * FROM Account_Events
 ORDER BY id;
 
 -- name: ListAccountEventByAccount :many
-SELECT * FROM Account_Events
-WHERE account_id = $1
-ORDER BY id;
+SELECT ae.*, et.name AS event_type
+FROM Account_Events AS ae
+JOIN Event_Types AS et ON et.id = ae.event_type_id
+WHERE ae.account_id = $1
+ORDER BY ae.id;
 
 -- name: CreateAccountEvent :one
 INSERT INTO Account_Events (
-  account_id, event_type_id, description
+  account_id, event_type_id, description, user_id, diff
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4, $5
 )
 RETURNING *;
 
--- name: UpdateAccountEvent :one
-UPDATE Account_Events
-  set description = $2, updated_at = NOW() AT TIME ZONE 'utc'
-WHERE id = $1
-RETURNING *;
+-- name: ListAccountEventChain :many
+SELECT ae.id, ae.prev_hash, ae.hash, account_event_hash(ae)::text AS computed_hash
+FROM Account_Events AS ae
+WHERE ae.account_id = $1
+ORDER BY ae.id;
 
--- name: DeleteAccountEvent :exec
+-- name: DeleteAccountEventsByAccount :exec
 DELETE FROM Account_Events
-WHERE id = $1;
+WHERE account_id = $1;
 
 -- MEMBERS
 
@@ -157,6 +220,10 @@ RETURNING *;
 DELETE FROM Members
 WHERE account_id = $1 and user_id = $2;
 
+-- name: DeleteMembersByAccount :exec
+DELETE FROM Members
+WHERE account_id = $1;
+
 -- TRANSACTIONS
 
 -- name: GetTransaction :one
@@ -166,12 +233,80 @@ WHERE id = $1 LIMIT 1;
 -- name: GetTransactionWithCheck :one
 SELECT t.*
 FROM transactions AS t
-WHERE t.account_id = $1 and t.id = $2 AND EXISTS (
+WHERE t.account_id = $1 and t.id = $2 AND t.deleted_at IS NULL AND EXISTS (
 	SELECT 1
 	FROM members AS m
 	WHERE m.account_id = t.account_id AND m.user_id = $3
 );
 
+-- name: GetTrashedTransactionWithCheck :one
+SELECT t.*
+FROM transactions AS t
+WHERE t.account_id = $1 and t.id = $2 AND t.deleted_at IS NOT NULL AND EXISTS (
+	SELECT 1
+	FROM members AS m
+	WHERE m.account_id = t.account_id AND m.user_id = $3
+);
+
+-- name: ListTrashedTransactionByAccount :many
+SELECT t.*
+FROM transactions AS t
+WHERE t.account_id = $1 AND t.deleted_at IS NOT NULL AND EXISTS (
+	SELECT 1
+	FROM members AS m
+	WHERE m.account_id = t.account_id AND m.user_id = $2
+)
+ORDER BY t.deleted_at DESC, t.id DESC;
+
+-- name: ListTransactionByTransfer :many
+SELECT * FROM Transactions
+WHERE transfer_id = $1
+ORDER BY id;
+
+-- name: ListTransactionExternalIDs :many
+SELECT external_id::text FROM Transactions
+WHERE account_id = sqlc.arg(account_id) AND external_id = ANY(sqlc.arg(external_ids)::text[]);
+
+-- name: ListTransactionDuplicateCandidates :many
+SELECT * FROM Transactions
+WHERE account_id = sqlc.arg(account_id)
+  AND deleted_at IS NULL
+  AND transaction_type_id = sqlc.arg(transaction_type_id)
+  AND amount = sqlc.arg(amount)
+  AND COALESCE(currency, sqlc.arg(account_currency)::text) = sqlc.arg(currency)::text
+  AND transaction_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+  AND NOT id = ANY(sqlc.arg(excluded_ids)::int[])
+ORDER BY transaction_date, id;
+
+-- name: MergeTransaction :exec
+UPDATE Transaction_Headers
+SET
+  external_id = COALESCE(external_id, sqlc.narg(external_id)),
+  metadata = sqlc.arg(metadata)::jsonb || metadata,
+  updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = sqlc.arg(id);
+
+-- name: ClearTransactionExternalID :exec
+UPDATE Transaction_Headers
+SET external_id = NULL
+WHERE id = $1;
+
+-- name: ListTransactionByJournalEntry :many
+SELECT sqlc.embed(t), tt.sign, acc.currency AS account_currency
+FROM Transactions AS t
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Accounts AS acc ON acc.id = t.account_id
+WHERE t.journal_entry_id = $1
+ORDER BY t.id;
+
+-- name: ListTransactionForExport :many
+SELECT sqlc.embed(t), tt.sign, acc.currency AS account_currency
+FROM Transactions AS t
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Accounts AS acc ON acc.id = t.account_id
+WHERE t.account_id = $1 AND t.deleted_at IS NULL
+ORDER BY t.transaction_date, t.id;
+
 -- name: ListTransaction :many
 SELECT * FROM Transactions
 ORDER BY id;
@@ -179,27 +314,552 @@ ORDER BY id;
 -- name: ListTransactionByAccount :many
 SELECT t.*
 FROM transactions AS t
-WHERE t.account_id = $1 AND EXISTS (
+WHERE t.account_id = sqlc.arg(account_id) AND t.deleted_at IS NULL AND EXISTS (
 	SELECT 1
 	FROM members AS m
-	WHERE m.account_id = t.account_id AND m.user_id = $2
-);
+	WHERE m.account_id = t.account_id AND m.user_id = sqlc.arg(user_id)
+) AND (sqlc.narg(tags)::text[] IS NULL OR (
+	SELECT COUNT(DISTINCT tg.name)
+	FROM transaction_tags AS tt
+	JOIN tags AS tg ON tg.id = tt.tag_id
+	WHERE tt.transaction_id = t.id AND tg.name = ANY(sqlc.narg(tags)::text[])
+) >= CASE WHEN sqlc.arg(match_all)::boolean THEN cardinality(sqlc.narg(tags)::text[]) ELSE 1 END)
+  AND (sqlc.narg(from_date)::date IS NULL OR t.transaction_date >= sqlc.narg(from_date))
+  AND (sqlc.narg(to_date)::date IS NULL OR t.transaction_date <= sqlc.narg(to_date))
+  AND (sqlc.narg(min_amount)::numeric IS NULL OR t.amount >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::numeric IS NULL OR t.amount <= sqlc.narg(max_amount))
+  AND (sqlc.narg(transaction_type_id)::int IS NULL OR t.transaction_type_id = sqlc.narg(transaction_type_id))
+  AND (sqlc.narg(category_id)::int IS NULL OR EXISTS (
+	WITH RECURSIVE subtree AS (
+		SELECT c.id FROM categories AS c WHERE c.id = sqlc.narg(category_id)
+		UNION ALL
+		SELECT c.id FROM categories AS c JOIN subtree ON c.parent_id = subtree.id
+	)
+	SELECT 1
+	FROM subtree
+	WHERE subtree.id = t.category_id OR subtree.id IN (
+		SELECT s.category_id FROM transaction_splits AS s WHERE s.transaction_id = t.id
+	)
+  ))
+  AND (sqlc.narg(creator_id)::int IS NULL OR t.user_id = sqlc.narg(creator_id))
+  AND (sqlc.narg(search)::text IS NULL OR strpos(lower(t.description), lower(sqlc.narg(search))) > 0)
+  AND (sqlc.narg(cursor_id)::int IS NULL OR CASE
+	WHEN sqlc.arg(sort_by)::text = 'amount' AND sqlc.arg(descending)::boolean THEN (t.amount, t.id) < (sqlc.narg(cursor_amount)::numeric, sqlc.narg(cursor_id))
+	WHEN sqlc.arg(sort_by) = 'amount' THEN (t.amount, t.id) > (sqlc.narg(cursor_amount), sqlc.narg(cursor_id))
+	WHEN sqlc.arg(sort_by) = 'created_at' AND sqlc.arg(descending) THEN (t.created_at, t.id) < (sqlc.narg(cursor_created_at)::timestamp, sqlc.narg(cursor_id))
+	WHEN sqlc.arg(sort_by) = 'created_at' THEN (t.created_at, t.id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id))
+	WHEN sqlc.arg(descending) THEN (t.transaction_date, t.id) < (sqlc.narg(cursor_date)::date, sqlc.narg(cursor_id))
+	ELSE (t.transaction_date, t.id) > (sqlc.narg(cursor_date), sqlc.narg(cursor_id))
+  END)
+ORDER BY
+  CASE WHEN sqlc.arg(sort_by) = 'amount' AND NOT sqlc.arg(descending) THEN t.amount END ASC,
+  CASE WHEN sqlc.arg(sort_by) = 'amount' AND sqlc.arg(descending) THEN t.amount END DESC,
+  CASE WHEN sqlc.arg(sort_by) = 'created_at' AND NOT sqlc.arg(descending) THEN t.created_at END ASC,
+  CASE WHEN sqlc.arg(sort_by) = 'created_at' AND sqlc.arg(descending) THEN t.created_at END DESC,
+  CASE WHEN sqlc.arg(sort_by) = 'date' AND NOT sqlc.arg(descending) THEN t.transaction_date END ASC,
+  CASE WHEN sqlc.arg(sort_by) = 'date' AND sqlc.arg(descending) THEN t.transaction_date END DESC,
+  CASE WHEN NOT sqlc.arg(descending) THEN t.id END ASC,
+  CASE WHEN sqlc.arg(descending) THEN t.id END DESC
+LIMIT sqlc.arg(page_size);
 
 -- SELECT * FROM Transactions
 -- WHERE account_id = $1
 -- ORDER BY id;
 
 -- name: CreateTransaction :one
-INSERT INTO Transactions (
-  account_id, user_id, transaction_date, transaction_type_id, amount, description
+INSERT INTO Transaction_Headers (
+  account_id, user_id, transaction_date, transaction_type_id, description, currency, transfer_id, journal_entry_id, external_id, metadata
 )
 VALUES(
-  $1, $2, $3, $4, $5, $6
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE(sqlc.narg(metadata)::jsonb, '{}')
 )
 returning *;
 
--- name: UpdateTransaction :one
-UPDATE Transactions
-  SET amount = $2, description = $3, updated_at = NOW() AT TIME ZONE 'utc'
-  WHERE id = $1
-  RETURNING *;
\ No newline at end of file
+-- name: UpdateTransaction :exec
+UPDATE Transaction_Headers
+  SET description = COALESCE(sqlc.narg(description), description),
+      updated_at = NOW() AT TIME ZONE 'utc'
+  WHERE id = sqlc.arg(id);
+
+-- name: TrashTransactionsByJournalEntry :exec
+UPDATE Transaction_Headers
+  SET deleted_at = NOW() AT TIME ZONE 'utc'
+WHERE journal_entry_id = $1;
+
+-- name: RestoreTransactionsByJournalEntry :exec
+UPDATE Transaction_Headers
+  SET deleted_at = NULL
+WHERE journal_entry_id = $1;
+
+-- name: DeleteTransactionsByAccount :execrows
+DELETE FROM Transaction_Headers
+WHERE account_id = $1;
+
+-- name: DetachTransfersByAccount :exec
+UPDATE Transaction_Headers
+  SET transfer_id = NULL
+WHERE transfer_id IN (
+	SELECT id
+	FROM Transfers
+	WHERE from_account_id = $1 OR to_account_id = $1
+);
+
+-- name: PurgeTransactionsTrashedBefore :execrows
+DELETE FROM Transaction_Headers
+WHERE deleted_at < $1;
+
+-- TRANSACTION_SPLITS
+
+-- name: ListTransactionSplits :many
+SELECT * FROM Transaction_Splits
+WHERE transaction_id = $1
+ORDER BY id;
+
+-- name: ListTransactionSplitsByAccount :many
+SELECT s.*
+FROM Transaction_Splits AS s
+JOIN Transactions AS t ON t.id = s.transaction_id
+WHERE t.account_id = $1 AND t.deleted_at IS NULL
+ORDER BY s.transaction_id, s.id;
+
+-- TAGS
+
+-- name: ListTagsByAccount :many
+SELECT tg.*, COUNT(tt.transaction_id) AS usage_count
+FROM Tags AS tg
+LEFT JOIN Transaction_Tags AS tt ON tt.tag_id = tg.id
+WHERE tg.account_id = $1 AND starts_with(tg.name, sqlc.arg(prefix)::text)
+GROUP BY tg.id
+ORDER BY usage_count DESC, tg.name
+LIMIT 20;
+
+-- name: ListTransactionTags :many
+SELECT tg.*
+FROM Tags AS tg
+JOIN Transaction_Tags AS tt ON tt.tag_id = tg.id
+WHERE tt.transaction_id = $1
+ORDER BY tg.name;
+
+-- name: UpsertTag :one
+INSERT INTO Tags (
+  account_id, name
+) VALUES (
+  $1, $2
+)
+ON CONFLICT (account_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddTransactionTag :exec
+INSERT INTO Transaction_Tags (
+  transaction_id, tag_id
+) VALUES (
+  $1, $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveTransactionTag :exec
+DELETE FROM Transaction_Tags AS tt
+USING Tags AS tg
+WHERE tt.tag_id = tg.id AND tt.transaction_id = $1 AND tg.name = $2;
+
+-- CATEGORIES
+
+-- name: GetCategory :one
+SELECT * FROM Categories
+WHERE id = $1 AND account_id = $2 LIMIT 1;
+
+-- name: ListCategoryByAccount :many
+SELECT * FROM Categories
+WHERE account_id = $1
+ORDER BY name, id;
+
+-- name: CountCategoryChildren :one
+SELECT COUNT(*) FROM Categories
+WHERE parent_id = $1;
+
+-- name: CountCategoryEnvelopeMoves :one
+SELECT COUNT(*) FROM Envelope_Moves
+WHERE from_category_id = $1 OR to_category_id = $1;
+
+-- name: CreateCategory :one
+INSERT INTO Categories (
+  account_id, parent_id, name
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: UpdateCategory :one
+UPDATE Categories
+  SET parent_id = $3, name = $4, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1 AND account_id = $2
+RETURNING *;
+
+-- name: DeleteCategory :exec
+DELETE FROM Categories
+WHERE id = $1 AND account_id = $2;
+
+-- JOURNAL_ENTRIES
+
+-- name: GetJournalEntry :one
+SELECT * FROM Journal_Entries
+WHERE id = $1 LIMIT 1;
+
+-- name: CreateJournalEntry :one
+INSERT INTO Journal_Entries (
+  entry_date, description, user_id
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- name: ListJournalEntryIDsByAccount :many
+SELECT DISTINCT journal_entry_id
+FROM Transaction_Headers
+WHERE account_id = $1
+ORDER BY journal_entry_id;
+
+-- name: DeleteJournalEntry :exec
+DELETE FROM Journal_Entries
+WHERE id = $1;
+
+-- name: TrashJournalEntry :exec
+UPDATE Journal_Entries
+  SET deleted_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1;
+
+-- name: RestoreJournalEntry :exec
+UPDATE Journal_Entries
+  SET deleted_at = NULL
+WHERE id = $1;
+
+-- name: PurgeJournalEntriesTrashedBefore :execrows
+DELETE FROM Journal_Entries
+WHERE deleted_at < $1;
+
+-- name: UpdateJournalEntry :exec
+UPDATE Journal_Entries
+  SET entry_date = $2, description = $3, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = $1;
+
+-- POSTINGS
+
+-- name: ListPostingsByJournalEntry :many
+SELECT p.*, la.account_id, la.kind
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+WHERE p.journal_entry_id = $1
+ORDER BY p.id;
+
+-- name: CreatePosting :one
+INSERT INTO Postings (
+  journal_entry_id, ledger_account_id, transaction_id, currency, amount, category_id, memo
+)
+SELECT sqlc.arg(journal_entry_id)::int, la.id, sqlc.arg(transaction_id)::int, sqlc.arg(currency)::text, sqlc.arg(amount)::numeric, sqlc.narg(category_id)::int, sqlc.arg(memo)::text
+FROM Ledger_Accounts AS la
+WHERE la.account_id = sqlc.arg(account_id)::int AND la.kind = sqlc.arg(kind)::text
+RETURNING *;
+
+-- name: DeletePostingsByJournalEntry :exec
+DELETE FROM Postings
+WHERE journal_entry_id = $1;
+
+-- TRANSACTION_TYPES
+
+-- name: GetTransactionType :one
+SELECT * FROM Transaction_Types
+WHERE id = $1 LIMIT 1;
+
+-- name: GetTransactionTypeByName :one
+SELECT * FROM Transaction_Types
+WHERE name = $1 LIMIT 1;
+
+-- EVENT_TYPES
+
+-- name: GetEventTypeByName :one
+SELECT * FROM Event_Types
+WHERE name = $1 LIMIT 1;
+
+-- TRANSFERS
+
+-- name: GetTransfer :one
+SELECT * FROM Transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: DeleteTransfersByAccount :exec
+DELETE FROM Transfers
+WHERE from_account_id = $1 OR to_account_id = $1;
+
+-- name: DeleteOrphanTransfers :exec
+DELETE FROM Transfers AS tr
+WHERE NOT EXISTS (
+	SELECT 1
+	FROM Transaction_Headers AS t
+	WHERE t.transfer_id = tr.id
+);
+
+-- name: CreateTransfer :one
+INSERT INTO Transfers (
+  from_account_id, to_account_id, user_id
+) VALUES (
+  $1, $2, $3
+)
+RETURNING *;
+
+-- EXCHANGE_RATES
+
+-- name: ListExchangeRates :many
+SELECT * FROM Exchange_Rates
+WHERE (sqlc.narg(base_currency)::text IS NULL OR base_currency = sqlc.narg(base_currency))
+  AND (sqlc.narg(quote_currency)::text IS NULL OR quote_currency = sqlc.narg(quote_currency))
+  AND (sqlc.narg(rate_date)::date IS NULL OR rate_date = sqlc.narg(rate_date))
+ORDER BY rate_date, base_currency, quote_currency;
+
+-- name: ListExchangeRatesForCurrencies :many
+SELECT * FROM Exchange_Rates
+WHERE rate_date <= sqlc.arg(rate_date)
+  AND (base_currency = ANY(sqlc.arg(currencies)::text[]) OR quote_currency = ANY(sqlc.arg(currencies)::text[]))
+ORDER BY rate_date;
+
+-- name: UpsertExchangeRate :one
+INSERT INTO Exchange_Rates (
+  rate_date, base_currency, quote_currency, rate
+) VALUES (
+  $1, $2, $3, $4
+)
+ON CONFLICT (rate_date, base_currency, quote_currency)
+DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW() AT TIME ZONE 'utc'
+WHERE Exchange_Rates.rate <> EXCLUDED.rate
+RETURNING *, (xmax = 0)::boolean AS inserted;
+
+-- RECURRING_RULES
+
+-- name: GetRecurringRule :one
+SELECT * FROM Recurring_Rules
+WHERE id = $1 AND account_id = $2 LIMIT 1;
+
+-- name: ListRecurringRulesByAccount :many
+SELECT * FROM Recurring_Rules
+WHERE account_id = $1
+ORDER BY id;
+
+-- name: ListDueRecurringRules :many
+SELECT sqlc.embed(r), (
+  SELECT MAX(o.occurrence_date)
+  FROM Recurring_Occurrences AS o
+  WHERE o.rule_id = r.id AND o.status = 'posted' AND o.occurrence_date <= $1
+)::date AS last_occurrence_date
+FROM Recurring_Rules AS r
+JOIN Accounts AS a ON a.id = r.account_id
+WHERE r.start_date <= $1 AND a.archived_at IS NULL
+ORDER BY r.id;
+
+-- name: CreateRecurringRule :one
+INSERT INTO Recurring_Rules (
+  account_id, user_id, transaction_type_id, amount, currency, description, category_id,
+  frequency, interval_count, day_of_month, start_date, end_date, occurrence_count
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+)
+RETURNING *;
+
+-- name: DeleteRecurringRule :exec
+DELETE FROM Recurring_Rules
+WHERE id = $1 AND account_id = $2;
+
+-- RECURRING_OCCURRENCES
+
+-- name: ListRecurringOccurrencesByAccount :many
+SELECT o.*
+FROM Recurring_Occurrences AS o
+JOIN Recurring_Rules AS r ON r.id = o.rule_id
+WHERE r.account_id = $1 AND o.occurrence_date BETWEEN sqlc.arg(from_date) AND sqlc.arg(to_date)
+ORDER BY o.occurrence_date, o.rule_id;
+
+-- name: ClaimRecurringOccurrence :one
+INSERT INTO Recurring_Occurrences (
+  rule_id, occurrence_date, status
+) VALUES (
+  $1, $2, $3
+)
+ON CONFLICT (rule_id, occurrence_date) DO NOTHING
+RETURNING *;
+
+-- name: SetRecurringOccurrenceTransaction :exec
+UPDATE Recurring_Occurrences
+  set transaction_id = $2
+WHERE id = $1;
+
+-- BUDGETS
+
+-- name: ListBudgetsByAccount :many
+SELECT * FROM Budgets
+WHERE account_id = $1 AND month <= sqlc.arg(through)
+ORDER BY month, category_id;
+
+-- name: UpsertBudget :one
+INSERT INTO Budgets (
+  account_id, category_id, month, amount, rollover
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+ON CONFLICT (category_id, month) DO UPDATE
+  set amount = EXCLUDED.amount, rollover = EXCLUDED.rollover, updated_at = NOW() AT TIME ZONE 'utc'
+RETURNING *;
+
+-- name: DeleteBudget :execrows
+DELETE FROM Budgets
+WHERE account_id = $1 AND category_id = $2 AND month = $3;
+
+-- name: ListCategorySpending :many
+SELECT p.category_id::int AS category_id, p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = sqlc.arg(account_id)::int
+  AND la.kind = 'asset'
+  AND p.category_id IS NOT NULL
+  AND je.entry_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+  AND je.deleted_at IS NULL
+GROUP BY p.category_id, p.currency, je.entry_date
+ORDER BY je.entry_date;
+
+-- ENVELOPES
+
+-- name: ListEnvelopeMoves :many
+SELECT * FROM Envelope_Moves
+WHERE account_id = $1
+ORDER BY id DESC;
+
+-- name: CreateEnvelopeMove :one
+INSERT INTO Envelope_Moves (
+  account_id, user_id, from_category_id, to_category_id, amount, note
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: ListEnvelopeMoveTotals :many
+SELECT COALESCE(category_id, 0)::int AS category_id, SUM(amount)::numeric AS amount
+FROM (
+  SELECT from_category_id AS category_id, -amount AS amount FROM Envelope_Moves WHERE account_id = $1
+  UNION ALL
+  SELECT to_category_id AS category_id, amount FROM Envelope_Moves WHERE account_id = $1
+) AS moves
+GROUP BY category_id;
+
+-- name: ListEnvelopeActivity :many
+SELECT COALESCE(p.category_id, 0)::int AS category_id, p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = $1 AND la.kind = 'asset' AND je.deleted_at IS NULL
+GROUP BY p.category_id, p.currency, je.entry_date, p.amount > 0
+ORDER BY je.entry_date;
+
+-- GOALS
+
+-- name: ListGoalsByAccount :many
+SELECT * FROM Goals
+WHERE account_id = $1
+ORDER BY target_date, id;
+
+-- name: GetGoal :one
+SELECT * FROM Goals
+WHERE id = $1 AND account_id = $2 LIMIT 1;
+
+-- name: CreateGoal :one
+INSERT INTO Goals (
+  account_id, name, target_amount, target_date, category_id, start_date
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: UpdateGoal :one
+UPDATE Goals
+  SET name = COALESCE(sqlc.narg(name), name),
+      target_amount = COALESCE(sqlc.narg(target_amount), target_amount),
+      target_date = COALESCE(sqlc.narg(target_date), target_date),
+      category_id = COALESCE(sqlc.narg(category_id), category_id),
+      start_date = COALESCE(sqlc.narg(start_date), start_date),
+      updated_at = NOW() AT TIME ZONE 'utc'
+WHERE id = sqlc.arg(id) AND account_id = sqlc.arg(account_id)
+RETURNING *;
+
+-- name: DeleteGoal :execrows
+DELETE FROM Goals
+WHERE id = $1 AND account_id = $2;
+
+-- name: ListGoalContributions :many
+SELECT p.currency, je.entry_date, SUM(p.amount)::numeric AS amount
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+JOIN Journal_Entries AS je ON je.id = p.journal_entry_id
+WHERE la.account_id = sqlc.arg(account_id)::int
+  AND la.kind = 'asset'
+  AND (sqlc.narg(category_id)::int IS NULL OR p.category_id IN (
+	WITH RECURSIVE subtree AS (
+		SELECT c.id FROM Categories AS c WHERE c.id = sqlc.narg(category_id)::int
+		UNION ALL
+		SELECT c.id FROM Categories AS c JOIN subtree ON c.parent_id = subtree.id
+	)
+	SELECT id FROM subtree
+  ))
+  AND je.entry_date BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date
+  AND je.deleted_at IS NULL
+GROUP BY p.currency, je.entry_date
+ORDER BY je.entry_date;
+
+-- TRANSACTION_SHARES
+
+-- name: ListTransactionShares :many
+SELECT * FROM Transaction_Shares
+WHERE transaction_id = $1
+ORDER BY id;
+
+-- name: CreateTransactionShare :one
+INSERT INTO Transaction_Shares (
+  transaction_id, user_id, method, value, amount
+) VALUES (
+  $1, $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: DeleteTransactionShares :exec
+DELETE FROM Transaction_Shares
+WHERE transaction_id = $1;
+
+-- name: ListAccountShares :many
+SELECT s.transaction_id, s.user_id, s.amount, t.user_id AS payer_id, tt.sign, tt.name AS transaction_type,
+  COALESCE(t.currency, acc.currency)::text AS currency, t.transaction_date, t.description
+FROM Transaction_Shares AS s
+JOIN Transactions AS t ON t.id = s.transaction_id
+JOIN Transaction_Types AS tt ON tt.id = t.transaction_type_id
+JOIN Accounts AS acc ON acc.id = t.account_id
+WHERE t.account_id = $1 AND t.deleted_at IS NULL
+ORDER BY t.transaction_date, s.transaction_id, s.id;
+
+-- IMPORT_PROFILES
+
+-- name: ListImportProfiles :many
+SELECT * FROM Import_Profiles
+WHERE account_id = $1
+ORDER BY name;
+
+-- name: GetImportProfile :one
+SELECT * FROM Import_Profiles
+WHERE id = $1 AND account_id = $2 LIMIT 1;
+
+-- name: CreateImportProfile :one
+INSERT INTO Import_Profiles (
+  account_id, name, delimiter, skip_rows, date_column, date_format, description_columns,
+  amount_column, debit_column, credit_column, decimal_separator, negate_amounts
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: DeleteImportProfile :execrows
+DELETE FROM Import_Profiles
+WHERE id = $1 AND account_id = $2;
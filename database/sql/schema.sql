@@ -5,12 +5,15 @@ CREATE TABLE Member_Roles (
 
 CREATE TABLE Transaction_Types (
     id SERIAL PRIMARY KEY,
-    name text NOT NULL
+    name text NOT NULL,
+    sign INT NOT NULL DEFAULT 1,
+    CONSTRAINT chk_transaction_type_sign CHECK (sign IN (-1, 0, 1))
 );
 
 CREATE TABLE Event_Types (
     id SERIAL PRIMARY KEY,
-    name text NOT NULL
+    name text NOT NULL,
+    CONSTRAINT uq_event_type_name UNIQUE (name)
 );
 
 CREATE TABLE Users (
@@ -18,7 +21,14 @@ CREATE TABLE Users (
     username TEXT UNIQUE NOT NULL,
     password TEXT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
-    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc')
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    reporting_currency TEXT NOT NULL DEFAULT 'USD'
+);
+
+CREATE TABLE Operators (
+    user_id INT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_operator_user FOREIGN KEY (user_id) REFERENCES Users(id) ON DELETE CASCADE
 );
 
 CREATE TABLE Accounts (
@@ -26,9 +36,48 @@ CREATE TABLE Accounts (
     account_name TEXT NOT NULL,
     account_type TEXT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
-    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc')
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    currency TEXT NOT NULL DEFAULT 'USD',
+    archived_at TIMESTAMP,
+    budgeting_mode TEXT NOT NULL DEFAULT 'monthly',
+    allow_negative_envelopes BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT chk_account_budgeting_mode CHECK (budgeting_mode IN ('monthly', 'envelope'))
 );
 
+CREATE TABLE Account_Deletions (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    account_name TEXT NOT NULL,
+    deleted_by INT NOT NULL,
+    transaction_count INT NOT NULL,
+    deleted_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_account_deletion_user FOREIGN KEY (deleted_by) REFERENCES Users(id)
+);
+
+CREATE TABLE Ledger_Accounts (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    kind TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_ledger_account_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT chk_ledger_account_kind CHECK (kind IN ('asset', 'income', 'expense', 'equity')),
+    CONSTRAINT uq_ledger_account_kind UNIQUE (account_id, kind)
+);
+
+CREATE FUNCTION create_ledger_accounts() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO Ledger_Accounts (account_id, kind)
+    SELECT NEW.id, kinds.kind
+    FROM (VALUES ('asset'), ('income'), ('expense'), ('equity')) AS kinds (kind);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_accounts_ledger_accounts
+AFTER INSERT ON Accounts
+FOR EACH ROW EXECUTE FUNCTION create_ledger_accounts();
+
 CREATE TABLE Account_Events (
     id SERIAL PRIMARY KEY,
     account_id INT NOT NULL,
@@ -36,10 +85,56 @@ CREATE TABLE Account_Events (
     description TEXT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
     updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    user_id INT,
+    diff JSONB NOT NULL DEFAULT '{}',
+    prev_hash TEXT,
+    hash TEXT NOT NULL,
     CONSTRAINT fk_account_event_account FOREIGN KEY (account_id) REFERENCES Accounts(id),
-    CONSTRAINT fk_account_event_event_type FOREIGN KEY (event_type_id) REFERENCES Event_Types(id)
+    CONSTRAINT fk_account_event_event_type FOREIGN KEY (event_type_id) REFERENCES Event_Types(id),
+    CONSTRAINT fk_account_event_user FOREIGN KEY (user_id) REFERENCES Users(id)
 );
 
+CREATE INDEX idx_account_events_account ON Account_Events (account_id, id);
+
+-- The hash covers everything an event says plus the hash of the event before
+-- it, so rewriting or removing an event breaks every link after it.
+CREATE FUNCTION account_event_hash(event Account_Events) RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(jsonb_build_array(
+        event.id,
+        event.account_id,
+        event.event_type_id,
+        event.user_id,
+        event.description,
+        event.diff,
+        event.created_at,
+        event.prev_hash
+    )::text, 'UTF8')), 'hex');
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE FUNCTION chain_account_event() RETURNS trigger AS $$
+BEGIN
+    -- Writers of the same account wait for each other so the chain cannot
+    -- fork, and the id is only taken once the lock is held so ids follow the
+    -- chain order.
+    PERFORM 1 FROM Accounts WHERE id = NEW.account_id FOR NO KEY UPDATE;
+
+    NEW.id := nextval('account_events_id_seq');
+    NEW.prev_hash := (
+        SELECT hash FROM Account_Events
+        WHERE account_id = NEW.account_id
+        ORDER BY id DESC
+        LIMIT 1
+    );
+    NEW.hash := account_event_hash(NEW);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_account_events_chain
+BEFORE INSERT ON Account_Events
+FOR EACH ROW EXECUTE FUNCTION chain_account_event();
+
 CREATE TABLE Members (
     id SERIAL PRIMARY KEY,
     account_id int NOT NULL,
@@ -52,17 +147,348 @@ CREATE TABLE Members (
     CONSTRAINT fk_member_member_role FOREIGN KEY (member_role_id) REFERENCES Member_Roles(id)
 );
 
-CREATE TABLE Transactions (
+CREATE TABLE Transfers (
+    id SERIAL PRIMARY KEY,
+    from_account_id INT NOT NULL,
+    to_account_id INT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_transfer_from_account FOREIGN KEY (from_account_id) REFERENCES Accounts(id),
+    CONSTRAINT fk_transfer_to_account FOREIGN KEY (to_account_id) REFERENCES Accounts(id),
+    CONSTRAINT fk_transfer_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT chk_transfer_accounts CHECK (from_account_id <> to_account_id)
+);
+
+CREATE TABLE Categories (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    parent_id INT,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_category_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_category_parent FOREIGN KEY (parent_id) REFERENCES Categories(id),
+    CONSTRAINT chk_category_parent CHECK (parent_id <> id)
+);
+
+CREATE UNIQUE INDEX uq_category_name ON Categories (account_id, COALESCE(parent_id, 0), name);
+
+CREATE TABLE Journal_Entries (
+    id SERIAL PRIMARY KEY,
+    entry_date DATE NOT NULL,
+    description TEXT NOT NULL,
+    user_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    deleted_at TIMESTAMP,
+    CONSTRAINT fk_journal_entry_user FOREIGN KEY (user_id) REFERENCES Users(id)
+);
+
+CREATE TABLE Transaction_Headers (
     id SERIAL PRIMARY KEY,
     account_id int NOT NULL,
     user_id int NOT NULL,
     transaction_date DATE NOT NULL,
     transaction_type_id int NOT NULL,
-    amount FLOAT NOT NULL,
     created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
     updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
     description TEXT NOT NULL,
+    currency TEXT,
+    transfer_id INT,
+    journal_entry_id INT NOT NULL,
+    deleted_at TIMESTAMP,
+    external_id TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}',
     CONSTRAINT fk_transaction_account FOREIGN KEY (account_id) REFERENCES Accounts(id),
     CONSTRAINT fk_transaction_user FOREIGN KEY (user_id) REFERENCES Users(id),
-    CONSTRAINT fk_transaction_transaction_type FOREIGN KEY (transaction_type_id) REFERENCES Transaction_Types(id)
-);
\ No newline at end of file
+    CONSTRAINT fk_transaction_transaction_type FOREIGN KEY (transaction_type_id) REFERENCES Transaction_Types(id),
+    CONSTRAINT fk_transaction_transfer FOREIGN KEY (transfer_id) REFERENCES Transfers(id),
+    CONSTRAINT fk_transaction_journal_entry FOREIGN KEY (journal_entry_id) REFERENCES Journal_Entries(id)
+);
+
+CREATE UNIQUE INDEX uq_transactions_external_id ON Transaction_Headers (account_id, external_id) WHERE external_id IS NOT NULL;
+
+CREATE INDEX idx_transactions_deleted_at ON Transaction_Headers (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX idx_transaction_headers_account_date ON Transaction_Headers (account_id, transaction_date) WHERE deleted_at IS NULL;
+
+CREATE TABLE Tags (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_tag_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT uq_tag_name UNIQUE (account_id, name)
+);
+
+CREATE TABLE Transaction_Tags (
+    transaction_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    PRIMARY KEY (transaction_id, tag_id),
+    CONSTRAINT fk_transaction_tag_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_tag_tag FOREIGN KEY (tag_id) REFERENCES Tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_transaction_tags_tag ON Transaction_Tags (tag_id);
+
+CREATE TABLE Postings (
+    id SERIAL PRIMARY KEY,
+    journal_entry_id INT NOT NULL,
+    transaction_id INT,
+    currency TEXT NOT NULL,
+    amount NUMERIC(19, 4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    ledger_account_id INT NOT NULL,
+    category_id INT,
+    memo TEXT NOT NULL DEFAULT '',
+    CONSTRAINT fk_posting_journal_entry FOREIGN KEY (journal_entry_id) REFERENCES Journal_Entries(id) ON DELETE CASCADE,
+    CONSTRAINT fk_posting_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_posting_ledger_account FOREIGN KEY (ledger_account_id) REFERENCES Ledger_Accounts(id),
+    CONSTRAINT fk_posting_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_postings_journal_entry ON Postings (journal_entry_id);
+CREATE INDEX idx_postings_ledger_account ON Postings (ledger_account_id);
+CREATE INDEX idx_postings_transaction ON Postings (transaction_id);
+
+CREATE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    entry_id INT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        entry_id := OLD.journal_entry_id;
+    ELSE
+        entry_id := NEW.journal_entry_id;
+    END IF;
+
+    IF (SELECT COUNT(*) FROM Postings WHERE journal_entry_id = entry_id) = 1 THEN
+        RAISE EXCEPTION 'journal entry % needs at least two postings', entry_id;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM Postings
+        WHERE journal_entry_id = entry_id
+        GROUP BY currency
+        HAVING SUM(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', entry_id;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trg_postings_balanced
+AFTER INSERT OR UPDATE OR DELETE ON Postings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+CREATE TABLE Exchange_Rates (
+    id SERIAL PRIMARY KEY,
+    rate_date DATE NOT NULL,
+    base_currency TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate NUMERIC(20, 10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT uq_exchange_rate UNIQUE (rate_date, base_currency, quote_currency),
+    CONSTRAINT chk_exchange_rate_positive CHECK (rate > 0)
+);
+
+CREATE TABLE Recurring_Rules (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    user_id INT NOT NULL,
+    transaction_type_id INT NOT NULL,
+    amount NUMERIC(19, 4) NOT NULL,
+    currency TEXT,
+    description TEXT NOT NULL,
+    category_id INT,
+    frequency TEXT NOT NULL,
+    interval_count INT NOT NULL DEFAULT 1,
+    day_of_month INT,
+    start_date DATE NOT NULL,
+    end_date DATE,
+    occurrence_count INT,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_recurring_rule_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_rule_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT fk_recurring_rule_transaction_type FOREIGN KEY (transaction_type_id) REFERENCES Transaction_Types(id),
+    CONSTRAINT fk_recurring_rule_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE SET NULL,
+    CONSTRAINT chk_recurring_rule_frequency CHECK (frequency IN ('daily', 'weekly', 'monthly', 'yearly')),
+    CONSTRAINT chk_recurring_rule_interval CHECK (interval_count > 0),
+    CONSTRAINT chk_recurring_rule_day_of_month CHECK (day_of_month BETWEEN 1 AND 31),
+    CONSTRAINT chk_recurring_rule_dates CHECK (end_date >= start_date),
+    CONSTRAINT chk_recurring_rule_count CHECK (occurrence_count > 0)
+);
+
+CREATE INDEX idx_recurring_rules_account ON Recurring_Rules (account_id);
+
+-- One row per rule and date, whether it was posted or skipped. The unique
+-- constraint is what keeps an occurrence from being posted twice.
+CREATE TABLE Recurring_Occurrences (
+    id SERIAL PRIMARY KEY,
+    rule_id INT NOT NULL,
+    occurrence_date DATE NOT NULL,
+    status TEXT NOT NULL,
+    transaction_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_recurring_occurrence_rule FOREIGN KEY (rule_id) REFERENCES Recurring_Rules(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recurring_occurrence_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE SET NULL,
+    CONSTRAINT chk_recurring_occurrence_status CHECK (status IN ('posted', 'skipped')),
+    CONSTRAINT uq_recurring_occurrence UNIQUE (rule_id, occurrence_date)
+);
+
+CREATE TABLE Budgets (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    category_id INT NOT NULL,
+    month DATE NOT NULL,
+    amount NUMERIC(19, 4) NOT NULL,
+    rollover BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_budget_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_budget_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE CASCADE,
+    CONSTRAINT chk_budget_amount CHECK (amount >= 0),
+    CONSTRAINT chk_budget_month CHECK (EXTRACT(DAY FROM month) = 1),
+    CONSTRAINT uq_budget UNIQUE (category_id, month)
+);
+
+CREATE INDEX idx_budgets_account_month ON Budgets (account_id, month);
+
+CREATE TABLE Envelope_Moves (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    user_id INT NOT NULL,
+    from_category_id INT,
+    to_category_id INT,
+    amount NUMERIC(19, 4) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_envelope_move_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_envelope_move_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT fk_envelope_move_from FOREIGN KEY (from_category_id) REFERENCES Categories(id),
+    CONSTRAINT fk_envelope_move_to FOREIGN KEY (to_category_id) REFERENCES Categories(id),
+    CONSTRAINT chk_envelope_move_amount CHECK (amount > 0),
+    CONSTRAINT chk_envelope_move_envelopes CHECK (from_category_id IS DISTINCT FROM to_category_id)
+);
+
+CREATE INDEX idx_envelope_moves_account ON Envelope_Moves (account_id);
+CREATE INDEX idx_envelope_moves_from ON Envelope_Moves (from_category_id);
+CREATE INDEX idx_envelope_moves_to ON Envelope_Moves (to_category_id);
+
+CREATE TABLE Goals (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    target_amount NUMERIC(19, 4) NOT NULL,
+    target_date DATE NOT NULL,
+    category_id INT,
+    start_date DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    updated_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_goal_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_goal_category FOREIGN KEY (category_id) REFERENCES Categories(id) ON DELETE SET NULL,
+    CONSTRAINT chk_goal_target_amount CHECK (target_amount > 0),
+    CONSTRAINT chk_goal_dates CHECK (target_date >= start_date)
+);
+
+CREATE INDEX idx_goals_account ON Goals (account_id);
+
+CREATE TABLE Transaction_Shares (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL,
+    user_id INT NOT NULL,
+    method TEXT NOT NULL,
+    value NUMERIC(19, 4),
+    amount NUMERIC(19, 4) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_transaction_share_transaction FOREIGN KEY (transaction_id) REFERENCES Transaction_Headers(id) ON DELETE CASCADE,
+    CONSTRAINT fk_transaction_share_user FOREIGN KEY (user_id) REFERENCES Users(id),
+    CONSTRAINT chk_transaction_share_method CHECK (method IN ('equal', 'exact', 'percent', 'weight')),
+    CONSTRAINT chk_transaction_share_amount CHECK (amount >= 0),
+    CONSTRAINT uq_transaction_share UNIQUE (transaction_id, user_id)
+);
+
+CREATE TABLE Import_Profiles (
+    id SERIAL PRIMARY KEY,
+    account_id INT NOT NULL,
+    name TEXT NOT NULL,
+    delimiter TEXT NOT NULL DEFAULT ',',
+    skip_rows INT NOT NULL DEFAULT 1,
+    date_column INT NOT NULL,
+    date_format TEXT NOT NULL DEFAULT 'YYYY-MM-DD',
+    description_columns INT[] NOT NULL DEFAULT '{}',
+    amount_column INT,
+    debit_column INT,
+    credit_column INT,
+    decimal_separator TEXT NOT NULL DEFAULT '.',
+    negate_amounts BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+    CONSTRAINT fk_import_profile_account FOREIGN KEY (account_id) REFERENCES Accounts(id) ON DELETE CASCADE,
+    CONSTRAINT chk_import_profile_amount CHECK (amount_column IS NOT NULL OR debit_column IS NOT NULL OR credit_column IS NOT NULL),
+    CONSTRAINT chk_import_profile_decimal_separator CHECK (decimal_separator IN ('.', ',')),
+    CONSTRAINT chk_import_profile_skip_rows CHECK (skip_rows >= 0),
+    CONSTRAINT uq_import_profile_name UNIQUE (account_id, name)
+);
+
+-- A transaction moves what its postings on the asset account add up to; a
+-- settle-up payment has no postings and moves what its shares add up to. It
+-- has the category of its only line, none when it is split.
+CREATE VIEW Transactions AS
+SELECT
+    h.id,
+    h.account_id,
+    h.user_id,
+    h.transaction_date,
+    h.transaction_type_id,
+    (CASE WHEN tt.sign = 0 THEN COALESCE(shares.amount, 0) ELSE tt.sign * COALESCE(lines.amount, 0) END)::numeric(19, 4) AS amount,
+    h.created_at,
+    h.updated_at,
+    h.description,
+    h.currency,
+    h.transfer_id,
+    h.journal_entry_id,
+    CASE WHEN lines.count = 1 THEN lines.category_id END AS category_id,
+    h.deleted_at,
+    h.external_id,
+    h.metadata
+FROM Transaction_Headers AS h
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+LEFT JOIN LATERAL (
+    SELECT SUM(p.amount) AS amount, COUNT(*) AS count, MIN(p.category_id) AS category_id
+    FROM Postings AS p
+    JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id
+    WHERE p.transaction_id = h.id AND la.kind = 'asset'
+) AS lines ON TRUE
+LEFT JOIN LATERAL (
+    SELECT SUM(s.amount) AS amount
+    FROM Transaction_Shares AS s
+    WHERE s.transaction_id = h.id
+) AS shares ON TRUE;
+
+-- The splits of a transaction are its lines on the asset account, when it
+-- has more than one.
+CREATE VIEW Transaction_Splits AS
+SELECT
+    p.id,
+    p.transaction_id,
+    p.category_id,
+    (tt.sign * p.amount)::numeric(19, 4) AS amount,
+    p.memo,
+    p.created_at,
+    p.created_at AS updated_at
+FROM Postings AS p
+JOIN Ledger_Accounts AS la ON la.id = p.ledger_account_id AND la.kind = 'asset'
+JOIN Transaction_Headers AS h ON h.id = p.transaction_id
+JOIN Transaction_Types AS tt ON tt.id = h.transaction_type_id
+WHERE (
+    SELECT COUNT(*)
+    FROM Postings AS other
+    JOIN Ledger_Accounts AS other_la ON other_la.id = other.ledger_account_id AND other_la.kind = 'asset'
+    WHERE other.transaction_id = p.transaction_id
+) > 1;
@@ -0,0 +1,96 @@
+// Package duplicates tells whether two transactions of an account are likely
+// the same payment recorded twice, such as an expense entered by hand and
+// imported again from a statement.
+//
+// Candidates are found in the database by account, direction, amount and
+// currency within a window of days around the transaction date; this package
+// only decides whether their descriptions are close enough.
+package duplicates
+
+import (
+	"strings"
+	"unicode"
+)
+
+// threshold is the share of characters two descriptions must have in common
+// to be taken as the same.
+const threshold = 0.8
+
+// minContained is the length below which a description is too short to be
+// recognized inside a longer one.
+const minContained = 4
+
+// normalize lowercases a description and reduces everything that is not a
+// letter or a digit to single spaces, so that punctuation and spacing do not
+// tell two descriptions apart.
+func normalize(description string) string {
+	fields := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return strings.Join(fields, " ")
+}
+
+// distance is the Levenshtein distance between two strings, counted in
+// runes.
+func distance(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// Similarity returns how alike two descriptions are, from 0 for nothing in
+// common to 1 for descriptions that only differ in case, punctuation or
+// spacing.
+func Similarity(a string, b string) float64 {
+	first, second := []rune(normalize(a)), []rune(normalize(b))
+	longest := max(len(first), len(second))
+
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(distance(first, second))/float64(longest)
+}
+
+// Similar reports whether two descriptions are near-identical. A description
+// found whole inside the other also counts: banks tend to add references to
+// the name a person would type, as in "ACME" and "ACME GmbH Invoice 42".
+func Similar(a string, b string) bool {
+	first, second := normalize(a), normalize(b)
+
+	if first == second {
+		return true
+	}
+
+	if len(first) > len(second) {
+		first, second = second, first
+	}
+
+	if len(first) >= minContained && strings.Contains(" "+second+" ", " "+first+" ") {
+		return true
+	}
+
+	return Similarity(first, second) >= threshold
+}
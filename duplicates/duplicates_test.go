@@ -0,0 +1,46 @@
+package duplicates
+
+import "testing"
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want float64
+	}{
+		{"", "", 1},
+		{"Rent", "rent", 1},
+		{"Coffee  shop!", "coffee shop", 1},
+		{"abcd", "abce", 0.75},
+		{"abc", "xyz", 0},
+	}
+
+	for _, test := range tests {
+		if got := Similarity(test.a, test.b); got != test.want {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want bool
+	}{
+		{"Netflix", "NETFLIX.", true},
+		{"Supermarket", "Supermarkt", true},
+		{"ACME", "ACME GmbH Invoice 42", true},
+		{"ACME GmbH Invoice 42", "acme", true},
+		{"Bus", "Bus ticket", false},
+		{"ACM", "ACME GmbH", false},
+		{"Rent", "Gym", false},
+		{"Salary March", "Salary April", false},
+	}
+
+	for _, test := range tests {
+		if got := Similar(test.a, test.b); got != test.want {
+			t.Errorf("Similar(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
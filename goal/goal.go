@@ -0,0 +1,81 @@
+// Package goal tracks savings goals: how much of a target amount was saved
+// so far and how much has to be put aside every month to reach it in time.
+package goal
+
+import (
+	"cashpal/money"
+	"math"
+	"time"
+)
+
+// Progress is the state of a goal on a given day.
+type Progress struct {
+	Current         money.Amount `json:"current"`
+	Remaining       money.Amount `json:"remaining"`
+	Percent         float64      `json:"percent"`
+	MonthsLeft      int          `json:"months_left"`
+	RequiredMonthly money.Amount `json:"required_monthly"`
+	Achieved        bool         `json:"achieved"`
+	Overdue         bool         `json:"overdue"`
+}
+
+// MonthsLeft returns how many monthly contributions still fit between today
+// and deadline. A deadline later in its month than today counts as one more
+// month. It is zero once the deadline is reached.
+func MonthsLeft(today time.Time, deadline time.Time) int {
+	months := (deadline.Year()-today.Year())*12 + int(deadline.Month()) - int(today.Month())
+
+	if deadline.Day() > today.Day() {
+		months++
+	}
+
+	if months < 0 {
+		return 0
+	}
+
+	return months
+}
+
+// Compute reports the progress towards target of a goal that has current
+// saved by today. The required monthly contribution is rounded up to the
+// currency so that paying it every month reaches the target; once the
+// deadline has passed, the whole remaining amount is required.
+func Compute(target money.Amount, current money.Amount, deadline time.Time, today time.Time, currency string) Progress {
+	progress := Progress{
+		Current:    current,
+		MonthsLeft: MonthsLeft(today, deadline),
+		Achieved:   current >= target,
+	}
+
+	if !progress.Achieved {
+		progress.Remaining = target - current
+	}
+
+	if current > 0 {
+		progress.Percent = math.Round(float64(current)/float64(target)*10000) / 100
+	}
+
+	if progress.Achieved {
+		return progress
+	}
+
+	// Past the deadline, everything left is due at once.
+	months := int64(progress.MonthsLeft)
+
+	if months == 0 {
+		progress.Overdue = today.After(deadline)
+		months = 1
+	}
+
+	step := int64(1)
+
+	if exponent, ok := money.Exponent(currency); ok && exponent < money.Scale {
+		step = int64(math.Pow10(money.Scale - exponent))
+	}
+
+	// Round up to a whole number of the currency's smallest unit.
+	share := months * step
+	progress.RequiredMonthly = money.Amount((int64(progress.Remaining) + share - 1) / share * step)
+
+	return progress
+}
@@ -0,0 +1,151 @@
+package goal
+
+import (
+	"cashpal/money"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMonthsLeft(t *testing.T) {
+	today := day(2026, 1, 15)
+
+	tests := []struct {
+		deadline time.Time
+		want     int
+	}{
+		{day(2026, 6, 15), 5},
+		{day(2026, 6, 20), 6},
+		{day(2026, 6, 10), 5},
+		{day(2027, 1, 15), 12},
+		{day(2026, 1, 31), 1},
+		{day(2026, 1, 15), 0},
+		{day(2026, 1, 1), 0},
+		{day(2025, 11, 30), 0},
+	}
+
+	for _, test := range tests {
+		if got := MonthsLeft(today, test.deadline); got != test.want {
+			t.Errorf("MonthsLeft(%s) = %d, want %d", test.deadline.Format(time.DateOnly), got, test.want)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	today := day(2026, 1, 15)
+
+	tests := []struct {
+		name     string
+		target   string
+		current  string
+		deadline time.Time
+		currency string
+		want     Progress
+	}{
+		{
+			name:     "half way",
+			target:   "1000",
+			current:  "500",
+			deadline: day(2026, 6, 15),
+			currency: "USD",
+			want: Progress{
+				Current:         money.MustParse("500"),
+				Remaining:       money.MustParse("500"),
+				Percent:         50,
+				MonthsLeft:      5,
+				RequiredMonthly: money.MustParse("100"),
+			},
+		},
+		{
+			name:     "monthly amount rounded up",
+			target:   "100",
+			current:  "0",
+			deadline: day(2026, 4, 15),
+			currency: "USD",
+			want: Progress{
+				Remaining:       money.MustParse("100"),
+				MonthsLeft:      3,
+				RequiredMonthly: money.MustParse("33.34"),
+			},
+		},
+		{
+			name:     "currency without minor units",
+			target:   "10000",
+			current:  "1",
+			deadline: day(2026, 4, 15),
+			currency: "JPY",
+			want: Progress{
+				Current:         money.MustParse("1"),
+				Remaining:       money.MustParse("9999"),
+				Percent:         0.01,
+				MonthsLeft:      3,
+				RequiredMonthly: money.MustParse("3333"),
+			},
+		},
+		{
+			name:     "achieved",
+			target:   "1000",
+			current:  "1200",
+			deadline: day(2026, 6, 15),
+			currency: "USD",
+			want: Progress{
+				Current:    money.MustParse("1200"),
+				Percent:    120,
+				MonthsLeft: 5,
+				Achieved:   true,
+			},
+		},
+		{
+			name:     "achieved after the deadline",
+			target:   "1000",
+			current:  "1000",
+			deadline: day(2025, 12, 31),
+			currency: "USD",
+			want: Progress{
+				Current:  money.MustParse("1000"),
+				Percent:  100,
+				Achieved: true,
+			},
+		},
+		{
+			name:     "deadline today",
+			target:   "300",
+			current:  "100",
+			deadline: day(2026, 1, 15),
+			currency: "USD",
+			want: Progress{
+				Current:         money.MustParse("100"),
+				Remaining:       money.MustParse("200"),
+				Percent:         33.33,
+				RequiredMonthly: money.MustParse("200"),
+			},
+		},
+		{
+			name:     "overdue",
+			target:   "300",
+			current:  "-50",
+			deadline: day(2026, 1, 10),
+			currency: "EUR",
+			want: Progress{
+				Current:         money.MustParse("-50"),
+				Remaining:       money.MustParse("350"),
+				RequiredMonthly: money.MustParse("350"),
+				Overdue:         true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Compute(money.MustParse(test.target), money.MustParse(test.current), test.deadline, today, test.currency)
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Compute = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package ledger
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeDB keeps journal entries, transaction headers, postings and transfers
+// in memory and answers the queries the ledger runs, told apart by their
+// "-- name:" comment. Transactions are read the way the Transactions view
+// computes them from the asset postings.
+type fakeDB struct {
+	nextID    int32
+	entries   map[int32]*db.JournalEntry
+	headers   map[int32]*db.TransactionHeader
+	postings  []db.ListPostingsByJournalEntryRow
+	transfers map[int32]db.Transfer
+	// signs maps transaction type ids to the sign of their type.
+	signs map[int32]int32
+}
+
+const (
+	typeExpense     int32 = 1
+	typeIncome      int32 = 2
+	typeTransferOut int32 = 3
+	typeTransferIn  int32 = 4
+)
+
+var ledgerKinds = map[string]int32{Asset: 1, Income: 2, Expense: 3, Equity: 4}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		entries:   make(map[int32]*db.JournalEntry),
+		headers:   make(map[int32]*db.TransactionHeader),
+		transfers: make(map[int32]db.Transfer),
+		signs: map[int32]int32{
+			typeExpense:     -1,
+			typeIncome:      1,
+			typeTransferOut: -1,
+			typeTransferIn:  1,
+		},
+	}
+}
+
+func (f *fakeDB) id() int32 {
+	f.nextID++
+	return f.nextID
+}
+
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+func now() pgtype.Timestamp {
+	return pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+}
+
+// transaction reads a header the way the Transactions view does.
+func (f *fakeDB) transaction(header *db.TransactionHeader) db.Transaction {
+	var amount money.Amount
+	var categories []pgtype.Int4
+
+	for _, posting := range f.postings {
+		if posting.TransactionID.Int32 == header.ID && posting.Kind == Asset {
+			amount += posting.Amount
+			categories = append(categories, posting.CategoryID)
+		}
+	}
+
+	transaction := db.Transaction{
+		ID:                header.ID,
+		AccountID:         header.AccountID,
+		UserID:            header.UserID,
+		TransactionDate:   header.TransactionDate,
+		TransactionTypeID: header.TransactionTypeID,
+		Amount:            amount * money.Amount(f.signs[header.TransactionTypeID]),
+		CreatedAt:         header.CreatedAt,
+		UpdatedAt:         header.UpdatedAt,
+		Description:       header.Description,
+		Currency:          header.Currency,
+		TransferID:        header.TransferID,
+		JournalEntryID:    header.JournalEntryID,
+		DeletedAt:         header.DeletedAt,
+		ExternalID:        header.ExternalID,
+		Metadata:          header.Metadata,
+	}
+
+	if len(categories) == 1 {
+		transaction.CategoryID = categories[0]
+	}
+
+	return transaction
+}
+
+// entryHeaders returns the headers of an entry in id order.
+func (f *fakeDB) entryHeaders(entryID int32) []*db.TransactionHeader {
+	var headers []*db.TransactionHeader
+
+	for _, header := range f.headers {
+		if header.JournalEntryID == entryID {
+			headers = append(headers, header)
+		}
+	}
+
+	sort.Slice(headers, func(i, j int) bool { return headers[i].ID < headers[j].ID })
+
+	return headers
+}
+
+// entryPostings returns the postings of an entry in id order.
+func (f *fakeDB) entryPostings(entryID int32) []db.ListPostingsByJournalEntryRow {
+	var postings []db.ListPostingsByJournalEntryRow
+
+	for _, posting := range f.postings {
+		if posting.JournalEntryID == entryID {
+			postings = append(postings, posting)
+		}
+	}
+
+	return postings
+}
+
+func (f *fakeDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	var affected int
+
+	switch queryName(sql) {
+	case "UpdateJournalEntry":
+		entry := f.entries[args[0].(int32)]
+		entry.EntryDate = args[1].(pgtype.Date)
+		entry.Description = args[2].(string)
+	case "DeletePostingsByJournalEntry":
+		entryID := args[0].(int32)
+		kept := f.postings[:0]
+
+		for _, posting := range f.postings {
+			if posting.JournalEntryID != entryID {
+				kept = append(kept, posting)
+			}
+		}
+
+		f.postings = kept
+	case "TrashTransactionsByJournalEntry":
+		for _, header := range f.entryHeaders(args[0].(int32)) {
+			header.DeletedAt = now()
+		}
+	case "TrashJournalEntry":
+		f.entries[args[0].(int32)].DeletedAt = now()
+	case "RestoreTransactionsByJournalEntry":
+		for _, header := range f.entryHeaders(args[0].(int32)) {
+			header.DeletedAt = pgtype.Timestamp{}
+		}
+	case "RestoreJournalEntry":
+		f.entries[args[0].(int32)].DeletedAt = pgtype.Timestamp{}
+	case "DetachTransfersByAccount":
+		accountID := args[0].(int32)
+
+		for _, header := range f.headers {
+			transfer, ok := f.transfers[header.TransferID.Int32]
+
+			if header.TransferID.Valid && ok && (transfer.FromAccountID == accountID || transfer.ToAccountID == accountID) {
+				header.TransferID = pgtype.Int4{}
+			}
+		}
+	case "DeleteTransactionsByAccount":
+		accountID := args[0].(int32)
+
+		for id, header := range f.headers {
+			if header.AccountID == accountID {
+				delete(f.headers, id)
+				affected++
+			}
+		}
+
+		// Postings of a deleted transaction go with it.
+		kept := f.postings[:0]
+
+		for _, posting := range f.postings {
+			if _, ok := f.headers[posting.TransactionID.Int32]; ok {
+				kept = append(kept, posting)
+			}
+		}
+
+		f.postings = kept
+	case "DeleteTransfersByAccount":
+		accountID := args[0].(int32)
+
+		for id, transfer := range f.transfers {
+			if transfer.FromAccountID == accountID || transfer.ToAccountID == accountID {
+				delete(f.transfers, id)
+			}
+		}
+	case "DeleteJournalEntry":
+		delete(f.entries, args[0].(int32))
+	default:
+		return pgconn.CommandTag{}, fmt.Errorf("fakeDB cannot exec %s", queryName(sql))
+	}
+
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", affected)), nil
+}
+
+func (f *fakeDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	var rows [][]any
+
+	switch queryName(sql) {
+	case "ListTransactionByJournalEntry":
+		for _, header := range f.entryHeaders(args[0].(int32)) {
+			row := columns(f.transaction(header))
+			rows = append(rows, append(row, f.signs[header.TransactionTypeID], "EUR"))
+		}
+	case "ListJournalEntryIDsByAccount":
+		seen := make(map[int32]bool)
+		var entryIDs []int32
+
+		for _, header := range f.headers {
+			if header.AccountID == args[0].(int32) && !seen[header.JournalEntryID] {
+				seen[header.JournalEntryID] = true
+				entryIDs = append(entryIDs, header.JournalEntryID)
+			}
+		}
+
+		sort.Slice(entryIDs, func(i, j int) bool { return entryIDs[i] < entryIDs[j] })
+
+		for _, entryID := range entryIDs {
+			rows = append(rows, []any{entryID})
+		}
+	case "ListPostingsByJournalEntry":
+		for _, posting := range f.entryPostings(args[0].(int32)) {
+			rows = append(rows, columns(posting))
+		}
+	default:
+		return nil, fmt.Errorf("fakeDB cannot query %s", queryName(sql))
+	}
+
+	return &fakeRows{rows: rows, index: -1}, nil
+}
+
+func (f *fakeDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	switch queryName(sql) {
+	case "CreateJournalEntry":
+		entry := &db.JournalEntry{
+			ID:          f.id(),
+			EntryDate:   args[0].(pgtype.Date),
+			Description: args[1].(string),
+			UserID:      args[2].(int32),
+			CreatedAt:   now(),
+			UpdatedAt:   now(),
+		}
+
+		f.entries[entry.ID] = entry
+
+		return fakeRow{values: columns(*entry)}
+	case "CreateTransaction":
+		if _, ok := f.entries[args[7].(int32)]; !ok {
+			return fakeRow{err: fmt.Errorf("journal entry %d does not exist", args[7])}
+		}
+
+		header := &db.TransactionHeader{
+			ID:                f.id(),
+			AccountID:         args[0].(int32),
+			UserID:            args[1].(int32),
+			TransactionDate:   args[2].(pgtype.Date),
+			TransactionTypeID: args[3].(int32),
+			CreatedAt:         now(),
+			UpdatedAt:         now(),
+			Description:       args[4].(string),
+			Currency:          args[5].(pgtype.Text),
+			TransferID:        args[6].(pgtype.Int4),
+			JournalEntryID:    args[7].(int32),
+			ExternalID:        args[8].(pgtype.Text),
+			Metadata:          args[9].(json.RawMessage),
+		}
+
+		f.headers[header.ID] = header
+
+		return fakeRow{values: columns(*header)}
+	case "CreatePosting":
+		posting := db.ListPostingsByJournalEntryRow{
+			ID:             f.id(),
+			JournalEntryID: args[0].(int32),
+			TransactionID:  pgtype.Int4{Int32: args[1].(int32), Valid: true},
+			Currency:       args[2].(string),
+			Amount:         args[3].(money.Amount),
+			CreatedAt:      now(),
+			CategoryID:     args[4].(pgtype.Int4),
+			Memo:           args[5].(string),
+			AccountID:      args[6].(int32),
+			Kind:           args[7].(string),
+		}
+
+		// Every account has one ledger account of each kind.
+		posting.LedgerAccountID = posting.AccountID*10 + ledgerKinds[posting.Kind]
+
+		f.postings = append(f.postings, posting)
+
+		return fakeRow{values: columns(posting)[:9]}
+	case "GetTransaction":
+		header, ok := f.headers[args[0].(int32)]
+
+		if !ok {
+			return fakeRow{err: pgx.ErrNoRows}
+		}
+
+		return fakeRow{values: columns(f.transaction(header))}
+	default:
+		return fakeRow{err: fmt.Errorf("fakeDB cannot query %s", queryName(sql))}
+	}
+}
+
+// columns lists the fields of a row struct in order, which is the order the
+// generated queries scan them in.
+func columns(row any) []any {
+	value := reflect.ValueOf(row)
+	values := make([]any, value.NumField())
+
+	for i := range values {
+		values[i] = value.Field(i).Interface()
+	}
+
+	return values
+}
+
+func scan(values []any, dest []any) error {
+	if len(values) != len(dest) {
+		return fmt.Errorf("fakeDB has %d columns, scanning %d", len(values), len(dest))
+	}
+
+	for i, value := range values {
+		target := reflect.ValueOf(dest[i]).Elem()
+		source := reflect.ValueOf(value)
+
+		if !source.Type().AssignableTo(target.Type()) {
+			source = source.Convert(target.Type())
+		}
+
+		target.Set(source)
+	}
+
+	return nil
+}
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	return scan(r.values, dest)
+}
+
+type fakeRows struct {
+	rows  [][]any
+	index int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.index++
+	return r.index < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	return scan(r.rows[r.index], dest)
+}
+
+func (r *fakeRows) Values() ([]any, error) {
+	return r.rows[r.index], nil
+}
@@ -0,0 +1,530 @@
+// Package ledger keeps the double-entry journal behind the Transactions API.
+//
+// Postings are the record of what happened to money; transactions and their
+// splits are read from them. Every Cashpal account has four ledger accounts:
+// its asset account, holding the money in it, the income and expense
+// accounts money comes from and goes to, and an equity account for what is
+// neither, such as a change of currency. Callers book every posting of an
+// entry, the counter-account side included, and an entry whose postings do
+// not sum to zero in every currency is rejected rather than balanced.
+//
+// Income and expenses book each line of a transaction on the asset account
+// and the opposite amount on the income or expense account, with the same
+// category. A transfer is a single entry whose legs balance each other;
+// between currencies each leg is balanced by the equity of its own account.
+package ledger
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// The kinds of ledger accounts every Cashpal account has.
+const (
+	Asset   = "asset"
+	Income  = "income"
+	Expense = "expense"
+	Equity  = "equity"
+)
+
+var (
+	ErrUnbalanced    = errors.New("journal entry is not balanced")
+	ErrSplitMismatch = errors.New("splits do not add up to the transaction amount")
+)
+
+// Posting is an amount booked on one of the ledger accounts of a Cashpal
+// account for a transaction.
+type Posting struct {
+	TransactionID int32
+	AccountID     int32
+	Kind          string
+	Currency      string
+	Amount        money.Amount
+	CategoryID    pgtype.Int4
+	Memo          string
+}
+
+// Line is the part of a transaction that goes to one category. A transaction
+// that is not split has a single line for its whole amount.
+type Line struct {
+	CategoryID pgtype.Int4
+	Amount     money.Amount
+	Memo       string
+}
+
+// Leg is a transaction to record along with its postings. The postings get
+// the id of the transaction once it is created.
+type Leg struct {
+	Transaction db.CreateTransactionParams
+	Postings    []Posting
+}
+
+// Side is one account's side of a transfer. Amounts are positive on both
+// sides; the transaction id is 0 for a leg that is yet to be recorded.
+type Side struct {
+	TransactionID int32
+	AccountID     int32
+	Currency      string
+	Amount        money.Amount
+	CategoryID    pgtype.Int4
+}
+
+// Validate checks that postings go to known kinds of ledger accounts, sum to
+// zero in every currency and that an entry with postings has at least two of
+// them.
+func Validate(postings []Posting) error {
+	if len(postings) == 1 {
+		return fmt.Errorf("%w: an entry needs at least two postings", ErrUnbalanced)
+	}
+
+	totals := make(map[string]money.Amount)
+
+	for _, posting := range postings {
+		switch posting.Kind {
+		case Asset, Income, Expense, Equity:
+		default:
+			return fmt.Errorf("unknown kind of ledger account %q", posting.Kind)
+		}
+
+		totals[posting.Currency] += posting.Amount
+	}
+
+	for currency, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("%w: %s postings sum to %s", ErrUnbalanced, currency, total)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSplits checks that the lines of a split transaction add up to its
+// amount. A transaction without splits is always valid. A split has at least
+// two lines and none of them is zero, since only those are read back as
+// splits.
+func ValidateSplits(amount money.Amount, lines []Line) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if len(lines) < 2 {
+		return errors.New("a split transaction needs at least two splits")
+	}
+
+	var total money.Amount
+
+	for _, line := range lines {
+		if line.Amount == 0 {
+			return errors.New("split amounts cannot be zero")
+		}
+
+		total += line.Amount
+	}
+
+	if total != amount {
+		return fmt.Errorf("%w: splits sum to %s, transaction is %s", ErrSplitMismatch, total, amount)
+	}
+
+	return nil
+}
+
+// IncomeExpense books the lines of a transaction of the given sign: each
+// line on the asset account, and the opposite amount on the income account
+// for income or on the expense account for expenses.
+func IncomeExpense(transactionID int32, accountID int32, currency string, sign int32, lines []Line) []Posting {
+	if sign < 0 {
+		return Book(transactionID, accountID, currency, sign, Expense, lines)
+	}
+
+	return Book(transactionID, accountID, currency, sign, Income, lines)
+}
+
+// Book books the lines of a transaction of the given sign on the asset
+// account, and the opposite amount on the ledger account of the given kind
+// with the same category.
+func Book(transactionID int32, accountID int32, currency string, sign int32, counter string, lines []Line) []Posting {
+	var postings []Posting
+
+	for _, line := range lines {
+		amount := line.Amount * money.Amount(sign)
+
+		if amount == 0 {
+			continue
+		}
+
+		postings = append(postings,
+			Posting{
+				TransactionID: transactionID,
+				AccountID:     accountID,
+				Kind:          Asset,
+				Currency:      currency,
+				Amount:        amount,
+				CategoryID:    line.CategoryID,
+				Memo:          line.Memo,
+			},
+			Posting{
+				TransactionID: transactionID,
+				AccountID:     accountID,
+				Kind:          counter,
+				Currency:      currency,
+				Amount:        -amount,
+				CategoryID:    line.CategoryID,
+				Memo:          line.Memo,
+			},
+		)
+	}
+
+	return postings
+}
+
+// Transfer books a transfer and returns the postings of its outgoing and
+// incoming legs. The money leaves the asset account of one side and arrives
+// on the asset account of the other. Between currencies each side is
+// balanced by the equity of its own account, where the exchange shows up.
+func Transfer(from Side, to Side) ([]Posting, []Posting) {
+	out := []Posting{{
+		TransactionID: from.TransactionID,
+		AccountID:     from.AccountID,
+		Kind:          Asset,
+		Currency:      from.Currency,
+		Amount:        -from.Amount,
+		CategoryID:    from.CategoryID,
+	}}
+
+	in := []Posting{{
+		TransactionID: to.TransactionID,
+		AccountID:     to.AccountID,
+		Kind:          Asset,
+		Currency:      to.Currency,
+		Amount:        to.Amount,
+		CategoryID:    to.CategoryID,
+	}}
+
+	if from.Currency != to.Currency {
+		out = append(out, Posting{
+			TransactionID: from.TransactionID,
+			AccountID:     from.AccountID,
+			Kind:          Equity,
+			Currency:      from.Currency,
+			Amount:        from.Amount,
+		})
+
+		in = append(in, Posting{
+			TransactionID: to.TransactionID,
+			AccountID:     to.AccountID,
+			Kind:          Equity,
+			Currency:      to.Currency,
+			Amount:        -to.Amount,
+		})
+	}
+
+	return out, in
+}
+
+// Record creates one journal entry holding all the given transactions and
+// books their postings, which must balance. The queries should be bound to a
+// database transaction.
+func Record(ctx context.Context, query *db.Queries, legs []Leg) ([]db.Transaction, error) {
+	if len(legs) == 0 {
+		return nil, errors.New("a journal entry needs at least one transaction")
+	}
+
+	var postings []Posting
+
+	for _, leg := range legs {
+		postings = append(postings, leg.Postings...)
+	}
+
+	if err := Validate(postings); err != nil {
+		return nil, err
+	}
+
+	newEntry := db.CreateJournalEntryParams{
+		EntryDate:   legs[0].Transaction.TransactionDate,
+		Description: legs[0].Transaction.Description,
+		UserID:      legs[0].Transaction.UserID,
+	}
+
+	entry, err := query.CreateJournalEntry(ctx, newEntry)
+
+	if err != nil {
+		return nil, err
+	}
+
+	created := make([]db.Transaction, 0, len(legs))
+
+	for _, leg := range legs {
+		leg.Transaction.JournalEntryID = entry.ID
+
+		header, err := query.CreateTransaction(ctx, leg.Transaction)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, posting := range leg.Postings {
+			posting.TransactionID = header.ID
+
+			if err := book(ctx, query, entry.ID, posting); err != nil {
+				return nil, err
+			}
+		}
+
+		transaction, err := query.GetTransaction(ctx, header.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		created = append(created, transaction)
+	}
+
+	return created, nil
+}
+
+// Rebook replaces all postings of an entry with the given ones, which must
+// balance, and brings the date and description of the entry in line with its
+// first transaction. It must run after any change to the amounts or
+// categories of those transactions.
+func Rebook(ctx context.Context, query *db.Queries, entryID int32, postings []Posting) error {
+	if err := Validate(postings); err != nil {
+		return err
+	}
+
+	transactions, err := query.ListTransactionByJournalEntry(ctx, entryID)
+
+	if err != nil {
+		return err
+	}
+
+	if len(transactions) > 0 {
+		first := transactions[0].Transaction
+
+		entryParams := db.UpdateJournalEntryParams{
+			ID:          entryID,
+			EntryDate:   first.TransactionDate,
+			Description: first.Description,
+		}
+
+		if err := query.UpdateJournalEntry(ctx, entryParams); err != nil {
+			return err
+		}
+	}
+
+	if err := query.DeletePostingsByJournalEntry(ctx, entryID); err != nil {
+		return err
+	}
+
+	for _, posting := range postings {
+		if err := book(ctx, query, entryID, posting); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// book stores a single posting of an entry.
+func book(ctx context.Context, query *db.Queries, entryID int32, posting Posting) error {
+	postingParams := db.CreatePostingParams{
+		JournalEntryID: entryID,
+		TransactionID:  posting.TransactionID,
+		Currency:       posting.Currency,
+		Amount:         posting.Amount,
+		CategoryID:     posting.CategoryID,
+		Memo:           posting.Memo,
+		AccountID:      posting.AccountID,
+		Kind:           posting.Kind,
+	}
+
+	_, err := query.CreatePosting(ctx, postingParams)
+
+	return err
+}
+
+// Trash moves an entry and all of its transactions to the trash. Trashed
+// entries keep their postings but no longer count towards any balance.
+func Trash(ctx context.Context, query *db.Queries, entryID int32) error {
+	if err := query.TrashTransactionsByJournalEntry(ctx, entryID); err != nil {
+		return err
+	}
+
+	return query.TrashJournalEntry(ctx, entryID)
+}
+
+// Restore takes an entry and all of its transactions out of the trash.
+func Restore(ctx context.Context, query *db.Queries, entryID int32) error {
+	if err := query.RestoreTransactionsByJournalEntry(ctx, entryID); err != nil {
+		return err
+	}
+
+	return query.RestoreJournalEntry(ctx, entryID)
+}
+
+// Purge permanently removes everything that was trashed before the given
+// time and returns how many transactions were removed. Transfers left without
+// any legs are removed as well.
+func Purge(ctx context.Context, query *db.Queries, before time.Time) (int64, error) {
+	cutoff := pgtype.Timestamp{Time: before.UTC(), Valid: true}
+
+	purged, err := query.PurgeTransactionsTrashedBefore(ctx, cutoff)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := query.PurgeJournalEntriesTrashedBefore(ctx, cutoff); err != nil {
+		return 0, err
+	}
+
+	if err := query.DeleteOrphanTransfers(ctx); err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// Removal reports what RemoveAccount did.
+type Removal struct {
+	// Removed is how many transactions of the account were deleted.
+	Removed int64
+	// Rewritten holds the transactions of other accounts left in entries
+	// shared with the account, such as the other leg of a transfer, as
+	// they were before and after the removal.
+	Rewritten []Rewrite
+}
+
+// Rewrite is a transaction before and after RemoveAccount changed it.
+type Rewrite struct {
+	Before db.Transaction
+	After  db.Transaction
+}
+
+// RemoveAccount deletes every transaction of an account along with its
+// postings. Entries left empty are deleted. Entries shared with other
+// accounts, such as transfers between accounts of the same currency, are
+// left with legs that no longer balance; what each leaves open in a
+// currency is booked on the equity of the account whose leg remains.
+func RemoveAccount(ctx context.Context, query *db.Queries, accountID int32) (Removal, error) {
+	var removal Removal
+
+	entryIDs, err := query.ListJournalEntryIDsByAccount(ctx, accountID)
+
+	if err != nil {
+		return removal, err
+	}
+
+	before := make(map[int32]db.Transaction)
+
+	for _, entryID := range entryIDs {
+		transactions, err := query.ListTransactionByJournalEntry(ctx, entryID)
+
+		if err != nil {
+			return removal, err
+		}
+
+		for _, transaction := range transactions {
+			if transaction.Transaction.AccountID != accountID {
+				before[transaction.Transaction.ID] = transaction.Transaction
+			}
+		}
+	}
+
+	if err := query.DetachTransfersByAccount(ctx, accountID); err != nil {
+		return removal, err
+	}
+
+	removal.Removed, err = query.DeleteTransactionsByAccount(ctx, accountID)
+
+	if err != nil {
+		return removal, err
+	}
+
+	if err := query.DeleteTransfersByAccount(ctx, accountID); err != nil {
+		return removal, err
+	}
+
+	for _, entryID := range entryIDs {
+		remaining, err := query.ListTransactionByJournalEntry(ctx, entryID)
+
+		if err != nil {
+			return removal, err
+		}
+
+		if len(remaining) == 0 {
+			if err := query.DeleteJournalEntry(ctx, entryID); err != nil {
+				return removal, err
+			}
+
+			continue
+		}
+
+		postings, err := query.ListPostingsByJournalEntry(ctx, entryID)
+
+		if err != nil {
+			return removal, err
+		}
+
+		for _, posting := range balanceWithEquity(postings) {
+			if err := book(ctx, query, entryID, posting); err != nil {
+				return removal, err
+			}
+		}
+
+		for _, transaction := range remaining {
+			rewrite := Rewrite{Before: before[transaction.Transaction.ID], After: transaction.Transaction}
+			removal.Rewritten = append(removal.Rewritten, rewrite)
+		}
+	}
+
+	return removal, nil
+}
+
+// balanceWithEquity returns the postings that balance what the given
+// postings leave open in each currency, booked on the equity of the account
+// of the first posting in that currency.
+func balanceWithEquity(postings []db.ListPostingsByJournalEntryRow) []Posting {
+	open := make(map[string]money.Amount)
+	first := make(map[string]db.ListPostingsByJournalEntryRow)
+
+	for _, posting := range postings {
+		if _, ok := first[posting.Currency]; !ok {
+			first[posting.Currency] = posting
+		}
+
+		open[posting.Currency] += posting.Amount
+	}
+
+	currencies := make([]string, 0, len(open))
+
+	for currency := range open {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Strings(currencies)
+
+	var balancing []Posting
+
+	for _, currency := range currencies {
+		if open[currency] == 0 {
+			continue
+		}
+
+		balancing = append(balancing, Posting{
+			TransactionID: first[currency].TransactionID.Int32,
+			AccountID:     first[currency].AccountID,
+			Kind:          Equity,
+			Currency:      currency,
+			Amount:        -open[currency],
+		})
+	}
+
+	return balancing
+}
@@ -0,0 +1,352 @@
+package ledger
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestValidateSplits(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		lines   []string
+		wantErr bool
+	}{
+		{"not split", "10", nil, false},
+		{"adds up", "10", []string{"4", "6"}, false},
+		{"refund line", "10", []string{"12", "-2"}, false},
+		{"does not add up", "10", []string{"4", "5"}, true},
+		{"single split", "10", []string{"10"}, true},
+		{"zero split", "10", []string{"10", "0"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lines []Line
+
+			for _, amount := range test.lines {
+				lines = append(lines, Line{Amount: money.MustParse(amount)})
+			}
+
+			err := ValidateSplits(money.MustParse(test.amount), lines)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+
+	err := ValidateSplits(money.MustParse("10"), []Line{{Amount: money.MustParse("4")}, {Amount: money.MustParse("5")}})
+
+	if !errors.Is(err, ErrSplitMismatch) {
+		t.Errorf("err = %v, want %v", err, ErrSplitMismatch)
+	}
+}
+
+func category(id int32) pgtype.Int4 {
+	return pgtype.Int4{Int32: id, Valid: true}
+}
+
+func date(value string) pgtype.Date {
+	day, _ := time.Parse(time.DateOnly, value)
+	return pgtype.Date{Time: day, Valid: true}
+}
+
+func TestBook(t *testing.T) {
+	lines := []Line{
+		{CategoryID: category(1), Amount: money.MustParse("30"), Memo: "groceries"},
+		{CategoryID: category(2), Amount: money.MustParse("-5"), Memo: "deposit back"},
+	}
+
+	got := IncomeExpense(7, 1, "EUR", -1, lines)
+
+	want := []Posting{
+		{TransactionID: 7, AccountID: 1, Kind: Asset, Currency: "EUR", Amount: money.MustParse("-30"), CategoryID: category(1), Memo: "groceries"},
+		{TransactionID: 7, AccountID: 1, Kind: Expense, Currency: "EUR", Amount: money.MustParse("30"), CategoryID: category(1), Memo: "groceries"},
+		{TransactionID: 7, AccountID: 1, Kind: Asset, Currency: "EUR", Amount: money.MustParse("5"), CategoryID: category(2), Memo: "deposit back"},
+		{TransactionID: 7, AccountID: 1, Kind: Expense, Currency: "EUR", Amount: money.MustParse("-5"), CategoryID: category(2), Memo: "deposit back"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("IncomeExpense = %+v, want %+v", got, want)
+	}
+
+	if err := Validate(got); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+
+	income := IncomeExpense(7, 1, "EUR", 1, lines[:1])
+
+	if income[0].Amount != money.MustParse("30") || income[1].Kind != Income {
+		t.Errorf("income postings = %+v", income)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	t.Run("same currency", func(t *testing.T) {
+		out, in := Transfer(
+			Side{AccountID: 1, Currency: "EUR", Amount: money.MustParse("100")},
+			Side{AccountID: 2, Currency: "EUR", Amount: money.MustParse("100")},
+		)
+
+		if len(out) != 1 || len(in) != 1 {
+			t.Fatalf("out = %+v, in = %+v, want one asset posting each", out, in)
+		}
+
+		if out[0].Amount != money.MustParse("-100") || in[0].Amount != money.MustParse("100") {
+			t.Errorf("out = %+v, in = %+v", out, in)
+		}
+
+		if err := Validate(append(out, in...)); err != nil {
+			t.Errorf("Validate = %v", err)
+		}
+	})
+
+	t.Run("between currencies", func(t *testing.T) {
+		out, in := Transfer(
+			Side{AccountID: 1, Currency: "EUR", Amount: money.MustParse("100")},
+			Side{AccountID: 2, Currency: "USD", Amount: money.MustParse("108.5")},
+		)
+
+		if len(out) != 2 || out[1].Kind != Equity || out[1].Amount != money.MustParse("100") {
+			t.Errorf("out = %+v, want the asset balanced by equity", out)
+		}
+
+		if len(in) != 2 || in[1].Kind != Equity || in[1].Amount != money.MustParse("-108.5") {
+			t.Errorf("in = %+v, want the asset balanced by equity", in)
+		}
+
+		if err := Validate(append(out, in...)); err != nil {
+			t.Errorf("Validate = %v", err)
+		}
+	})
+}
+
+func expense(accountID int32, day string, lines []Line) Leg {
+	return Leg{
+		Transaction: db.CreateTransactionParams{
+			AccountID:         accountID,
+			UserID:            1,
+			TransactionDate:   date(day),
+			TransactionTypeID: typeExpense,
+			Description:       "shopping",
+		},
+		Postings: IncomeExpense(0, accountID, "EUR", -1, lines),
+	}
+}
+
+func TestRecordAndRebook(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeDB()
+	query := db.New(fake)
+
+	lines := []Line{
+		{CategoryID: category(1), Amount: money.MustParse("30")},
+		{CategoryID: category(2), Amount: money.MustParse("12.5")},
+	}
+
+	transactions, err := Record(ctx, query, []Leg{expense(1, "2024-03-01", lines)})
+
+	if err != nil {
+		t.Fatalf("Record = %v", err)
+	}
+
+	recorded := transactions[0]
+
+	if recorded.Amount != money.MustParse("42.5") || recorded.CategoryID.Valid {
+		t.Errorf("recorded = %+v, want 42.5 without a single category", recorded)
+	}
+
+	if got := len(fake.entryPostings(recorded.JournalEntryID)); got != 4 {
+		t.Errorf("%d postings booked, want 4", got)
+	}
+
+	fake.headers[recorded.ID].TransactionDate = date("2024-03-05")
+	fake.headers[recorded.ID].Description = "market"
+
+	postings := IncomeExpense(recorded.ID, 1, "EUR", -1, []Line{{CategoryID: category(3), Amount: money.MustParse("20")}})
+
+	if err := Rebook(ctx, query, recorded.JournalEntryID, postings); err != nil {
+		t.Fatalf("Rebook = %v", err)
+	}
+
+	rebooked, err := query.GetTransaction(ctx, recorded.ID)
+
+	if err != nil {
+		t.Fatalf("GetTransaction = %v", err)
+	}
+
+	if rebooked.Amount != money.MustParse("20") || rebooked.CategoryID != category(3) {
+		t.Errorf("rebooked = %+v, want 20 in category 3", rebooked)
+	}
+
+	if got := len(fake.entryPostings(recorded.JournalEntryID)); got != 2 {
+		t.Errorf("%d postings after rebooking, want 2", got)
+	}
+
+	entry := fake.entries[recorded.JournalEntryID]
+
+	if entry.EntryDate != date("2024-03-05") || entry.Description != "market" {
+		t.Errorf("entry = %+v, want the date and description of its transaction", entry)
+	}
+}
+
+func TestRecordRejectsUnbalancedPostings(t *testing.T) {
+	fake := newFakeDB()
+	leg := expense(1, "2024-03-01", []Line{{Amount: money.MustParse("10")}})
+	leg.Postings = leg.Postings[:1]
+
+	if _, err := Record(context.Background(), db.New(fake), []Leg{leg}); !errors.Is(err, ErrUnbalanced) {
+		t.Fatalf("Record = %v, want %v", err, ErrUnbalanced)
+	}
+
+	if len(fake.entries) != 0 || len(fake.headers) != 0 {
+		t.Errorf("an unbalanced entry was written: %d entries, %d transactions", len(fake.entries), len(fake.headers))
+	}
+
+	if err := Rebook(context.Background(), db.New(fake), 1, leg.Postings); !errors.Is(err, ErrUnbalanced) {
+		t.Errorf("Rebook = %v, want %v", err, ErrUnbalanced)
+	}
+}
+
+// recordTransfer records a transfer of amount from account 1 to account 2.
+func recordTransfer(t *testing.T, fake *fakeDB, amount string) []db.Transaction {
+	t.Helper()
+
+	transfer := db.Transfer{ID: fake.id(), FromAccountID: 1, ToAccountID: 2}
+	fake.transfers[transfer.ID] = transfer
+
+	out, in := Transfer(
+		Side{AccountID: 1, Currency: "EUR", Amount: money.MustParse(amount)},
+		Side{AccountID: 2, Currency: "EUR", Amount: money.MustParse(amount)},
+	)
+
+	leg := func(accountID int32, typeID int32, postings []Posting) Leg {
+		return Leg{
+			Transaction: db.CreateTransactionParams{
+				AccountID:         accountID,
+				UserID:            1,
+				TransactionDate:   date("2024-03-01"),
+				TransactionTypeID: typeID,
+				Description:       "savings",
+				TransferID:        pgtype.Int4{Int32: transfer.ID, Valid: true},
+			},
+			Postings: postings,
+		}
+	}
+
+	transactions, err := Record(context.Background(), db.New(fake), []Leg{leg(1, typeTransferOut, out), leg(2, typeTransferIn, in)})
+
+	if err != nil {
+		t.Fatalf("Record = %v", err)
+	}
+
+	return transactions
+}
+
+func TestTrashAndRestore(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeDB()
+	query := db.New(fake)
+
+	legs := recordTransfer(t, fake, "100")
+	entryID := legs[0].JournalEntryID
+
+	if legs[1].JournalEntryID != entryID {
+		t.Fatalf("transfer legs are in entries %d and %d, want one", entryID, legs[1].JournalEntryID)
+	}
+
+	if err := Trash(ctx, query, entryID); err != nil {
+		t.Fatalf("Trash = %v", err)
+	}
+
+	for _, leg := range legs {
+		if !fake.headers[leg.ID].DeletedAt.Valid {
+			t.Errorf("transaction %d was not trashed with its entry", leg.ID)
+		}
+	}
+
+	if !fake.entries[entryID].DeletedAt.Valid {
+		t.Errorf("entry %d was not trashed", entryID)
+	}
+
+	if got := len(fake.entryPostings(entryID)); got != 2 {
+		t.Errorf("%d postings kept in the trash, want 2", got)
+	}
+
+	if err := Restore(ctx, query, entryID); err != nil {
+		t.Fatalf("Restore = %v", err)
+	}
+
+	for _, leg := range legs {
+		restored, err := query.GetTransaction(ctx, leg.ID)
+
+		if err != nil {
+			t.Fatalf("GetTransaction = %v", err)
+		}
+
+		if restored.DeletedAt.Valid || restored.Amount != money.MustParse("100") {
+			t.Errorf("restored = %+v, want it out of the trash with its amount", restored)
+		}
+	}
+
+	if fake.entries[entryID].DeletedAt.Valid {
+		t.Errorf("entry %d is still in the trash", entryID)
+	}
+}
+
+func TestRemoveAccount(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeDB()
+	query := db.New(fake)
+
+	legs := recordTransfer(t, fake, "100")
+	transferEntryID := legs[0].JournalEntryID
+
+	spent, err := Record(ctx, query, []Leg{expense(1, "2024-03-02", []Line{{Amount: money.MustParse("25")}})})
+
+	if err != nil {
+		t.Fatalf("Record = %v", err)
+	}
+
+	removal, err := RemoveAccount(ctx, query, 1)
+
+	if err != nil {
+		t.Fatalf("RemoveAccount = %v", err)
+	}
+
+	if removal.Removed != 2 {
+		t.Errorf("Removed = %d, want 2", removal.Removed)
+	}
+
+	if _, ok := fake.entries[spent[0].JournalEntryID]; ok {
+		t.Errorf("entry %d was left without transactions", spent[0].JournalEntryID)
+	}
+
+	if len(removal.Rewritten) != 1 {
+		t.Fatalf("Rewritten = %+v, want the incoming leg", removal.Rewritten)
+	}
+
+	rewrite := removal.Rewritten[0]
+
+	if rewrite.Before.ID != legs[1].ID || !rewrite.Before.TransferID.Valid {
+		t.Errorf("Before = %+v, want the leg as part of the transfer", rewrite.Before)
+	}
+
+	if rewrite.After.TransferID.Valid || rewrite.After.Amount != money.MustParse("100") {
+		t.Errorf("After = %+v, want the leg detached with its amount", rewrite.After)
+	}
+
+	postings := fake.entryPostings(transferEntryID)
+
+	if len(postings) != 2 || postings[1].AccountID != 2 || postings[1].Kind != Equity || postings[1].Amount != money.MustParse("-100") {
+		t.Fatalf("postings = %+v, want the remaining leg balanced by its equity", postings)
+	}
+}
@@ -0,0 +1,341 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scale is the number of decimal places kept for every stored amount. It is
+// large enough for any currency in the currency table.
+const Scale = 4
+
+var scaleFactor = pow10(Scale)
+
+var ErrInvalidAmount = errors.New("amount is invalid or malformed")
+
+// Amount is an exact monetary value stored as an integer number of
+// 1/10^Scale units. It is persisted as NUMERIC and serialized as a JSON
+// string so that clients never see a binary floating point value.
+type Amount int64
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// Parse reads a plain decimal string such as "-12.50".
+func Parse(value string) (Amount, error) {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+
+	switch value[0] {
+	case '-':
+		negative = true
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+
+	if whole == "" && fraction == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	if len(fraction) > Scale {
+		return 0, fmt.Errorf("amount supports at most %d decimal places", Scale)
+	}
+
+	for _, digits := range []string{whole, fraction} {
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return 0, ErrInvalidAmount
+			}
+		}
+	}
+
+	var units int64
+
+	if whole != "" {
+		parsed, err := strconv.ParseInt(whole, 10, 64)
+
+		if err != nil || parsed > (1<<63-1)/scaleFactor {
+			return 0, ErrInvalidAmount
+		}
+
+		units = parsed * scaleFactor
+	}
+
+	if fraction != "" {
+		parsed, _ := strconv.ParseInt(fraction, 10, 64)
+		fractionUnits := parsed * pow10(Scale-len(fraction))
+
+		if units > math.MaxInt64-fractionUnits {
+			return 0, ErrInvalidAmount
+		}
+
+		units += fractionUnits
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return Amount(units), nil
+}
+
+// MustParse is like Parse but panics on malformed input. It is meant for
+// constants in code.
+func MustParse(value string) Amount {
+	amount, err := Parse(value)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return amount
+}
+
+// DecimalPlaces reports how many fractional digits are needed to represent
+// the amount without losing precision.
+func (a Amount) DecimalPlaces() int {
+	units := int64(a)
+	places := Scale
+
+	for places > 0 && units%10 == 0 {
+		units /= 10
+		places--
+	}
+
+	return places
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// String formats the amount with at least two decimal places.
+func (a Amount) String() string {
+	places := a.DecimalPlaces()
+
+	if places < 2 {
+		places = 2
+	}
+
+	return a.Format(places)
+}
+
+// Format renders the amount with exactly the given number of decimal places,
+// truncating any extra precision.
+func (a Amount) Format(places int) string {
+	units := int64(a)
+	sign := ""
+
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	whole := units / scaleFactor
+	fraction := units % scaleFactor
+
+	if places <= 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+
+	digits := fmt.Sprintf("%0*d", Scale, fraction)
+
+	if places < Scale {
+		digits = digits[:places]
+	} else {
+		digits += strings.Repeat("0", places-Scale)
+	}
+
+	return sign + strconv.FormatInt(whole, 10) + "." + digits
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts either a JSON string or a JSON number. Numbers are
+// read from their literal text, never through float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	value := string(data)
+
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := Parse(value)
+
+	if err != nil {
+		return err
+	}
+
+	*a = parsed
+	return nil
+}
+
+func (a Amount) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(a)), Exp: -Scale, Valid: true}, nil
+}
+
+func (a *Amount) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return errors.New("cannot scan NULL into money.Amount")
+	}
+
+	units, err := numericToUnits(v)
+
+	if err != nil {
+		return err
+	}
+
+	*a = units
+	return nil
+}
+
+func numericToUnits(v pgtype.Numeric) (Amount, error) {
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return 0, ErrInvalidAmount
+	}
+
+	units := new(big.Int)
+
+	if v.Int != nil {
+		units.Set(v.Int)
+	}
+
+	exp := int(v.Exp) + Scale
+
+	if exp > 0 {
+		units.Mul(units, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	} else if exp < 0 {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil)
+		remainder := new(big.Int)
+		units.QuoRem(units, divisor, remainder)
+
+		if remainder.Sign() != 0 {
+			return 0, fmt.Errorf("numeric value has more than %d decimal places", Scale)
+		}
+	}
+
+	if !units.IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+
+	return Amount(units.Int64()), nil
+}
+
+// NullAmount is an Amount that may be absent, used for optional columns and
+// for partial updates where a missing amount must not be read as zero.
+type NullAmount struct {
+	Amount Amount
+	Valid  bool
+}
+
+func (n NullAmount) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Amount.MarshalJSON()
+}
+
+func (n *NullAmount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*n = NullAmount{}
+		return nil
+	}
+
+	if err := n.Amount.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	n.Valid = true
+	return nil
+}
+
+func (n NullAmount) NumericValue() (pgtype.Numeric, error) {
+	if !n.Valid {
+		return pgtype.Numeric{}, nil
+	}
+	return n.Amount.NumericValue()
+}
+
+func (n *NullAmount) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*n = NullAmount{}
+		return nil
+	}
+
+	units, err := numericToUnits(v)
+
+	if err != nil {
+		return err
+	}
+
+	*n = NullAmount{Amount: units, Valid: true}
+	return nil
+}
+
+// Mul multiplies the amount by an exact factor, rounding half away from zero
+// to the stored scale. A product too large for an Amount is an
+// ErrInvalidAmount.
+func (a Amount) Mul(factor *big.Rat) (Amount, error) {
+	product := roundRat(new(big.Rat).Mul(new(big.Rat).SetInt64(int64(a)), factor))
+
+	if !product.IsInt64() {
+		return 0, ErrInvalidAmount
+	}
+
+	return Amount(product.Int64()), nil
+}
+
+// Round rounds the amount half away from zero to the given number of decimal
+// places, typically a currency exponent.
+func (a Amount) Round(places int) Amount {
+	if places >= Scale {
+		return a
+	}
+
+	step := pow10(Scale - places)
+	value := new(big.Rat).SetFrac(big.NewInt(int64(a)), big.NewInt(step))
+
+	return Amount(roundRat(value).Int64() * step)
+}
+
+func roundRat(value *big.Rat) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	doubled := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+
+	if doubled.Cmp(value.Denom()) >= 0 {
+		if value.Sign() < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	return quotient
+}
@@ -0,0 +1,215 @@
+package money
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Amount
+		wantErr bool
+	}{
+		{"12.50", 125000, false},
+		{"-12.5", -125000, false},
+		{"+3", 30000, false},
+		{" 0.0001 ", 1, false},
+		{".5", 5000, false},
+		{"7.", 70000, false},
+		{"0", 0, false},
+		{"922337203685477", 9223372036854770000, false},
+		{"922337203685478", 0, true},
+		{"922337203685477.5807", 9223372036854775807, false},
+		{"922337203685477.9999", 0, true},
+		{"-922337203685477.9999", 0, true},
+		{"1.00001", 0, true},
+		{"1,50", 0, true},
+		{"1e3", 0, true},
+		{"--1", 0, true},
+		{".", 0, true},
+		{"", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.value)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("Parse(%q) err = %v, want error %v", test.value, err, test.wantErr)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("Parse(%q) = %d, want %d", test.value, got, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		places int
+		want   string
+	}{
+		{MustParse("12.5"), 2, "12.50"},
+		{MustParse("12.5"), 0, "12"},
+		{MustParse("-0.1234"), 2, "-0.12"},
+		{MustParse("-0.1234"), 6, "-0.123400"},
+		{MustParse("1000"), 3, "1000.000"},
+	}
+
+	for _, test := range tests {
+		if got := test.amount.Format(test.places); got != test.want {
+			t.Errorf("%d.Format(%d) = %q, want %q", test.amount, test.places, got, test.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"0", "0.00"},
+		{"12", "12.00"},
+		{"-12.5", "-12.50"},
+		{"0.125", "0.125"},
+		{"-0.0001", "-0.0001"},
+	}
+
+	for _, test := range tests {
+		if got := MustParse(test.value).String(); got != test.want {
+			t.Errorf("MustParse(%q).String() = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value  string
+		places int
+		want   string
+	}{
+		{"1.005", 2, "1.01"},
+		{"1.0049", 2, "1"},
+		{"-1.005", 2, "-1.01"},
+		{"2.5", 0, "3"},
+		{"-2.5", 0, "-3"},
+		{"1.2345", 4, "1.2345"},
+		{"1.2345", 6, "1.2345"},
+	}
+
+	for _, test := range tests {
+		if got := MustParse(test.value).Round(test.places); got != MustParse(test.want) {
+			t.Errorf("Round(%s, %d) = %s, want %s", test.value, test.places, got, test.want)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		value  string
+		factor *big.Rat
+		want   string
+	}{
+		{"100", big.NewRat(1, 3), "33.3333"},
+		{"200", big.NewRat(1, 3), "66.6667"},
+		{"-200", big.NewRat(1, 3), "-66.6667"},
+		{"10", big.NewRat(11, 10), "11"},
+	}
+
+	for _, test := range tests {
+		got, err := MustParse(test.value).Mul(test.factor)
+
+		if err != nil {
+			t.Errorf("%s * %s err = %v", test.value, test.factor, err)
+			continue
+		}
+
+		if got != MustParse(test.want) {
+			t.Errorf("%s * %s = %s, want %s", test.value, test.factor, got, test.want)
+		}
+	}
+}
+
+func TestMulOverflow(t *testing.T) {
+	if _, err := MustParse("922337203685477").Mul(big.NewRat(2, 1)); err != ErrInvalidAmount {
+		t.Errorf("err = %v, want %v", err, ErrInvalidAmount)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		wantErr  bool
+	}{
+		{"12.34", "USD", false},
+		{"12.34", "eur", false},
+		{"12.345", "USD", true},
+		{"12.345", "KWD", false},
+		{"12", "JPY", false},
+		{"12.5", "JPY", true},
+		{"12", "XXX", true},
+	}
+
+	for _, test := range tests {
+		if err := MustParse(test.value).Validate(test.currency); (err != nil) != test.wantErr {
+			t.Errorf("Validate(%s, %s) = %v, want error %v", test.value, test.currency, err, test.wantErr)
+		}
+	}
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     string
+		wantErr  bool
+	}{
+		{"usd", "USD", false},
+		{" Eur ", "EUR", false},
+		{"BTC", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := NormalizeCurrency(test.currency)
+
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("NormalizeCurrency(%q) = %q, %v, want %q, error %v", test.currency, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Amount
+		wantErr bool
+	}{
+		{`"12.50"`, MustParse("12.5"), false},
+		{`12.50`, MustParse("12.5"), false},
+		{`-0.1`, MustParse("-0.1"), false},
+		{`"abc"`, 0, true},
+		{`1e2`, 0, true},
+	}
+
+	for _, test := range tests {
+		var got Amount
+		err := json.Unmarshal([]byte(test.input), &got)
+
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("Unmarshal(%s) = %s, %v, want %s, error %v", test.input, got, err, test.want, test.wantErr)
+		}
+	}
+
+	data, err := json.Marshal(struct {
+		Amount   Amount     `json:"amount"`
+		Optional NullAmount `json:"optional"`
+	}{Amount: MustParse("1.5")})
+
+	if err != nil || string(data) != `{"amount":"1.50","optional":null}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+}
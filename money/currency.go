@@ -0,0 +1,65 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultCurrency is used wherever no currency has been chosen explicitly.
+const DefaultCurrency = "USD"
+
+// exponents holds the number of minor unit digits of the ISO 4217 currencies
+// Cashpal accepts.
+var exponents = map[string]int{
+	"ARS": 2, "AUD": 2, "BGN": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2,
+	"CLP": 0, "CNY": 2, "COP": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3,
+	"JPY": 0, "KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2,
+	"OMR": 3, "PEN": 2, "PHP": 2, "PLN": 2, "RON": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TND": 3, "TRY": 2, "USD": 2, "UYU": 2, "ZAR": 2,
+}
+
+// Exponent returns the number of decimal places allowed for a currency.
+func Exponent(currency string) (int, bool) {
+	exponent, ok := exponents[strings.ToUpper(currency)]
+	return exponent, ok
+}
+
+// Validate checks that the amount does not carry more decimal places than
+// the currency allows.
+func (a Amount) Validate(currency string) error {
+	exponent, ok := Exponent(currency)
+
+	if !ok {
+		return fmt.Errorf("currency %q is not supported", currency)
+	}
+
+	if a.DecimalPlaces() > exponent {
+		return fmt.Errorf("%s amounts allow at most %d decimal places", strings.ToUpper(currency), exponent)
+	}
+
+	return nil
+}
+
+// NormalizeCurrency upper-cases a currency code and checks that it is
+// supported.
+func NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if _, ok := exponents[currency]; !ok {
+		return "", fmt.Errorf("currency %q is not supported", currency)
+	}
+
+	return currency, nil
+}
+
+// RoundTo rounds the amount to the number of decimal places of a currency.
+func (a Amount) RoundTo(currency string) Amount {
+	exponent, ok := Exponent(currency)
+
+	if !ok {
+		return a
+	}
+
+	return a.Round(exponent)
+}
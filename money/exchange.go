@@ -0,0 +1,137 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrRateNotFound = errors.New("no exchange rate available")
+
+// ExchangeRate is a rate valid from Date until the next known rate of the
+// same currency pair.
+type ExchangeRate struct {
+	Date  time.Time
+	Base  string
+	Quote string
+	Rate  Rate
+}
+
+type pair struct {
+	base  string
+	quote string
+}
+
+// RateTable answers conversion questions from a set of dated rates. A pair
+// can be converted directly, through the inverse of the opposite pair, or
+// through one intermediate currency, which is how ECB rates (all quoted
+// against EUR) are used for non-EUR pairs.
+type RateTable struct {
+	rates      map[pair][]ExchangeRate
+	currencies []string
+}
+
+func NewRateTable(rates []ExchangeRate) *RateTable {
+	table := &RateTable{rates: make(map[pair][]ExchangeRate)}
+	seen := make(map[string]bool)
+
+	for _, rate := range rates {
+		rate.Base = strings.ToUpper(rate.Base)
+		rate.Quote = strings.ToUpper(rate.Quote)
+
+		key := pair{rate.Base, rate.Quote}
+		table.rates[key] = append(table.rates[key], rate)
+
+		for _, currency := range []string{rate.Base, rate.Quote} {
+			if !seen[currency] {
+				seen[currency] = true
+				table.currencies = append(table.currencies, currency)
+			}
+		}
+	}
+
+	for key := range table.rates {
+		sort.Slice(table.rates[key], func(i, j int) bool {
+			return table.rates[key][i].Date.Before(table.rates[key][j].Date)
+		})
+	}
+
+	sort.Strings(table.currencies)
+
+	return table
+}
+
+// latest returns the most recent rate of a pair published on or before the
+// given date.
+func (t *RateTable) latest(key pair, on time.Time) (Rate, bool) {
+	rates := t.rates[key]
+	index := sort.Search(len(rates), func(i int) bool { return rates[i].Date.After(on) })
+
+	if index == 0 {
+		return 0, false
+	}
+
+	return rates[index-1].Rate, true
+}
+
+func (t *RateTable) direct(from string, to string, on time.Time) (*big.Rat, bool) {
+	if rate, ok := t.latest(pair{from, to}, on); ok {
+		return rate.Rat(), true
+	}
+
+	if rate, ok := t.latest(pair{to, from}, on); ok {
+		return new(big.Rat).Inv(rate.Rat()), true
+	}
+
+	return nil, false
+}
+
+// Factor returns the multiplier that converts an amount in one currency into
+// another using the rates valid on the given date.
+func (t *RateTable) Factor(from string, to string, on time.Time) (*big.Rat, error) {
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	if factor, ok := t.direct(from, to, on); ok {
+		return factor, nil
+	}
+
+	for _, via := range t.currencies {
+		if via == from || via == to {
+			continue
+		}
+
+		first, ok := t.direct(from, via, on)
+
+		if !ok {
+			continue
+		}
+
+		second, ok := t.direct(via, to, on)
+
+		if !ok {
+			continue
+		}
+
+		return new(big.Rat).Mul(first, second), nil
+	}
+
+	return nil, fmt.Errorf("%w for %s/%s on %s", ErrRateNotFound, from, to, on.Format(time.DateOnly))
+}
+
+func (t *RateTable) Convert(amount Amount, from string, to string, on time.Time) (Amount, error) {
+	factor, err := t.Factor(from, to, on)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return amount.Mul(factor)
+}
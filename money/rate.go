@@ -0,0 +1,136 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RateScale is the number of decimal places kept for exchange rates.
+const RateScale = 10
+
+var ErrInvalidRate = errors.New("exchange rate is invalid or malformed")
+
+// Rate is an exact exchange rate stored as an integer number of
+// 1/10^RateScale units. A rate between a base and a quote currency says how
+// many units of the quote currency one unit of the base currency buys.
+type Rate int64
+
+func ParseRate(value string) (Rate, error) {
+	value = strings.TrimSpace(value)
+	whole, fraction, _ := strings.Cut(value, ".")
+
+	if whole == "" && fraction == "" {
+		return 0, ErrInvalidRate
+	}
+
+	if len(fraction) > RateScale {
+		return 0, fmt.Errorf("exchange rate supports at most %d decimal places", RateScale)
+	}
+
+	for _, digits := range []string{whole, fraction} {
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return 0, ErrInvalidRate
+			}
+		}
+	}
+
+	var units int64
+
+	if whole != "" {
+		parsed, err := strconv.ParseInt(whole, 10, 64)
+
+		if err != nil || parsed > (1<<63-1)/pow10(RateScale) {
+			return 0, ErrInvalidRate
+		}
+
+		units = parsed * pow10(RateScale)
+	}
+
+	if fraction != "" {
+		parsed, _ := strconv.ParseInt(fraction, 10, 64)
+		units += parsed * pow10(RateScale-len(fraction))
+	}
+
+	if units <= 0 {
+		return 0, ErrInvalidRate
+	}
+
+	return Rate(units), nil
+}
+
+// Rat returns the rate as an exact fraction.
+func (r Rate) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(int64(r)), big.NewInt(pow10(RateScale)))
+}
+
+func (r Rate) String() string {
+	digits := fmt.Sprintf("%0*d", RateScale, int64(r)%pow10(RateScale))
+	digits = strings.TrimRight(digits, "0")
+
+	if digits == "" {
+		digits = "0"
+	}
+
+	return strconv.FormatInt(int64(r)/pow10(RateScale), 10) + "." + digits
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	value := string(data)
+
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseRate(value)
+
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+	return nil
+}
+
+func (r Rate) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(r)), Exp: -RateScale, Valid: true}, nil
+}
+
+func (r *Rate) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid || v.NaN || v.InfinityModifier != pgtype.Finite {
+		return ErrInvalidRate
+	}
+
+	units := new(big.Int)
+
+	if v.Int != nil {
+		units.Set(v.Int)
+	}
+
+	exp := int64(v.Exp) + RateScale
+
+	if exp > 0 {
+		units.Mul(units, new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil))
+	} else if exp < 0 {
+		units.Quo(units, new(big.Int).Exp(big.NewInt(10), big.NewInt(-exp), nil))
+	}
+
+	if !units.IsInt64() {
+		return ErrInvalidRate
+	}
+
+	*r = Rate(units.Int64())
+	return nil
+}
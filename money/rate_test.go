@@ -0,0 +1,93 @@
+package money
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"1.1", "1.1", false},
+		{"0.0000000001", "0.0000000001", false},
+		{"149.25", "149.25", false},
+		{"1", "1.0", false},
+		{"0.00000000001", "", true},
+		{"1.00000000001", "", true},
+		{"0", "", true},
+		{"-1.1", "", true},
+		{"1,1", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseRate(test.value)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseRate(%q) err = %v, want error %v", test.value, err, test.wantErr)
+			continue
+		}
+
+		if !test.wantErr && got.String() != test.want {
+			t.Errorf("ParseRate(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func mustParseRate(value string) Rate {
+	rate, err := ParseRate(value)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return rate
+}
+
+func day(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRateTableConvert(t *testing.T) {
+	table := NewRateTable([]ExchangeRate{
+		{Date: day(2026, 2, 1), Base: "EUR", Quote: "USD", Rate: mustParseRate("1.2")},
+		{Date: day(2026, 1, 1), Base: "eur", Quote: "usd", Rate: mustParseRate("1.1")},
+		{Date: day(2026, 1, 1), Base: "EUR", Quote: "GBP", Rate: mustParseRate("0.85")},
+	})
+
+	tests := []struct {
+		name    string
+		amount  string
+		from    string
+		to      string
+		on      time.Time
+		want    string
+		wantErr error
+	}{
+		{"same currency", "10", "JPY", "jpy", day(2020, 1, 1), "10", nil},
+		{"direct", "10", "EUR", "USD", day(2026, 1, 15), "11", nil},
+		{"latest rate", "10", "EUR", "USD", day(2026, 2, 1), "12", nil},
+		{"inverse", "100", "USD", "EUR", day(2026, 1, 15), "90.9091", nil},
+		{"through another currency", "10", "GBP", "USD", day(2026, 1, 15), "12.9412", nil},
+		{"before the first rate", "10", "EUR", "USD", day(2025, 12, 31), "", ErrRateNotFound},
+		{"unknown currency", "10", "EUR", "CHF", day(2026, 1, 15), "", ErrRateNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := table.Convert(MustParse(test.amount), test.from, test.to, test.on)
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("err = %v, want %v", err, test.wantErr)
+			}
+
+			if test.wantErr == nil && got != MustParse(test.want) {
+				t.Errorf("Convert = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package rates
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Summary reports what an import did to the stored rates.
+type Summary struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+	Skipped   int `json:"skipped"`
+}
+
+// Import upserts the rates using the given queries, which should be bound to
+// a database transaction. Importing the same file twice leaves every rate
+// unchanged. Rates for unsupported currencies are skipped.
+func Import(ctx context.Context, query *db.Queries, rates []money.ExchangeRate) (Summary, error) {
+	var summary Summary
+
+	for _, rate := range rates {
+		base, baseErr := money.NormalizeCurrency(rate.Base)
+		quote, quoteErr := money.NormalizeCurrency(rate.Quote)
+
+		if baseErr != nil || quoteErr != nil || base == quote {
+			summary.Skipped++
+			continue
+		}
+
+		upsertParams := db.UpsertExchangeRateParams{
+			RateDate:      pgtype.Date{Time: rate.Date, Valid: true},
+			BaseCurrency:  base,
+			QuoteCurrency: quote,
+			Rate:          rate.Rate,
+		}
+
+		upserted, err := query.UpsertExchangeRate(ctx, upsertParams)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			summary.Unchanged++
+			continue
+		}
+
+		if err != nil {
+			return summary, err
+		}
+
+		if upserted.Inserted {
+			summary.Added++
+		} else {
+			summary.Changed++
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,143 @@
+package rates
+
+import (
+	"cashpal/money"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	FormatECB = "ecb"
+	FormatCSV = "csv"
+)
+
+// ecbBase is the base currency of every rate published by the European
+// Central Bank.
+const ecbBase = "EUR"
+
+var ErrUnknownFormat = errors.New("unknown exchange rate file format, expected ecb or csv")
+
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ParseECB reads the European Central Bank euro foreign exchange reference
+// rate XML, either the daily file or the 90 day and historical ones.
+func ParseECB(r io.Reader) ([]money.ExchangeRate, error) {
+	var envelope ecbEnvelope
+
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("invalid ECB XML: %w", err)
+	}
+
+	var rates []money.ExchangeRate
+
+	for _, day := range envelope.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid ECB rate date %q", day.Time)
+		}
+
+		for _, quote := range day.Rates {
+			rate, err := money.ParseRate(quote.Rate)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid ECB rate %q for %s on %s", quote.Rate, quote.Currency, day.Time)
+			}
+
+			rates = append(rates, money.ExchangeRate{
+				Date:  date,
+				Base:  ecbBase,
+				Quote: strings.ToUpper(quote.Currency),
+				Rate:  rate,
+			})
+		}
+	}
+
+	return rates, nil
+}
+
+// ParseCSV reads rates in the form date,base,quote,rate. A header row whose
+// first column is "date" is skipped.
+func ParseCSV(r io.Reader) ([]money.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	var rates []money.ExchangeRate
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		date, err := time.Parse(time.DateOnly, strings.TrimSpace(record[0]))
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+
+		rate, err := money.ParseRate(record[3])
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+		}
+
+		rates = append(rates, money.ExchangeRate{
+			Date:  date,
+			Base:  strings.ToUpper(strings.TrimSpace(record[1])),
+			Quote: strings.ToUpper(strings.TrimSpace(record[2])),
+			Rate:  rate,
+		})
+	}
+
+	return rates, nil
+}
+
+func Parse(format string, r io.Reader) ([]money.ExchangeRate, error) {
+	switch format {
+	case FormatECB:
+		return ParseECB(r)
+	case FormatCSV:
+		return ParseCSV(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// DetectFormat guesses the file format from a file name or a content type.
+func DetectFormat(nameOrContentType string) string {
+	value := strings.ToLower(nameOrContentType)
+
+	switch {
+	case filepath.Ext(value) == ".xml", strings.Contains(value, "xml"):
+		return FormatECB
+	case filepath.Ext(value) == ".csv", strings.Contains(value, "csv"):
+		return FormatCSV
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,38 @@
+package rates
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// LoadTable fetches every rate up to asOf that involves one of the given
+// currencies, which is enough to convert between them directly, inversely or
+// through a shared base currency.
+func LoadTable(ctx context.Context, query *db.Queries, currencies []string, asOf pgtype.Date) (*money.RateTable, error) {
+	rateParams := db.ListExchangeRatesForCurrenciesParams{
+		RateDate:   asOf,
+		Currencies: currencies,
+	}
+
+	exchangeRates, err := query.ListExchangeRatesForCurrencies(ctx, rateParams)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tableRates := make([]money.ExchangeRate, 0, len(exchangeRates))
+
+	for _, exchangeRate := range exchangeRates {
+		tableRates = append(tableRates, money.ExchangeRate{
+			Date:  exchangeRate.RateDate.Time,
+			Base:  exchangeRate.BaseCurrency,
+			Quote: exchangeRate.QuoteCurrency,
+			Rate:  exchangeRate.Rate,
+		})
+	}
+
+	return money.NewRateTable(tableRates), nil
+}
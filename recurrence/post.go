@@ -0,0 +1,156 @@
+package recurrence
+
+import (
+	"cashpal/audit"
+	"cashpal/budget"
+	db "cashpal/database/generated"
+	"cashpal/ledger"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	StatusPosted  = "posted"
+	StatusSkipped = "skipped"
+)
+
+// NewRule reads the schedule of a stored rule.
+func NewRule(rule db.RecurringRule) Rule {
+	schedule := Rule{
+		Frequency:  rule.Frequency,
+		Interval:   int(rule.IntervalCount),
+		DayOfMonth: int(rule.DayOfMonth.Int32),
+		Start:      rule.StartDate.Time,
+		Count:      int(rule.OccurrenceCount.Int32),
+	}
+
+	if rule.EndDate.Valid {
+		schedule.End = rule.EndDate.Time
+	}
+
+	return schedule
+}
+
+// Due returns the dates of the rule up to today that come after its last
+// posted occurrence. Skipped dates are still returned; Post leaves them alone.
+func Due(rule db.RecurringRule, lastOccurrence pgtype.Date, today time.Time) []time.Time {
+	from := rule.StartDate.Time
+
+	if lastOccurrence.Valid {
+		from = lastOccurrence.Time.AddDate(0, 0, 1)
+	}
+
+	return NewRule(rule).Between(from, today)
+}
+
+// Post creates the transactions of the rule for the given dates and returns
+// how many it created. Every date is claimed in Recurring_Occurrences before
+// its transaction is recorded, so a date that was already posted or skipped,
+// even by another process, is left alone. Nothing is posted when it would
+// overdraw an envelope of the account. The queries should be bound to a
+// database transaction.
+func Post(ctx context.Context, query *db.Queries, rule db.RecurringRule, dates []time.Time) (int, error) {
+	posted := 0
+
+	if len(dates) == 0 {
+		return posted, nil
+	}
+
+	transactionType, err := query.GetTransactionType(ctx, rule.TransactionTypeID)
+
+	if err != nil {
+		return posted, err
+	}
+
+	currency := rule.Currency.String
+
+	if !rule.Currency.Valid {
+		account, err := query.GetAccount(ctx, rule.AccountID)
+
+		if err != nil {
+			return posted, err
+		}
+
+		currency = account.Currency
+	}
+
+	lines := []ledger.Line{{CategoryID: rule.CategoryID, Amount: rule.Amount}}
+
+	envelopes, err := budget.Lock(ctx, query, rule.AccountID)
+
+	if err != nil {
+		return posted, err
+	}
+
+	for _, date := range dates {
+		claim := db.ClaimRecurringOccurrenceParams{
+			RuleID:         rule.ID,
+			OccurrenceDate: pgtype.Date{Time: date, Valid: true},
+			Status:         StatusPosted,
+		}
+
+		occurrence, err := query.ClaimRecurringOccurrence(ctx, claim)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+
+		if err != nil {
+			return posted, err
+		}
+
+		newTransaction := db.CreateTransactionParams{
+			AccountID:         rule.AccountID,
+			UserID:            rule.UserID,
+			TransactionDate:   occurrence.OccurrenceDate,
+			TransactionTypeID: rule.TransactionTypeID,
+			Description:       rule.Description,
+			Currency:          rule.Currency,
+		}
+
+		leg := ledger.Leg{
+			Transaction: newTransaction,
+			Postings:    ledger.IncomeExpense(0, rule.AccountID, currency, transactionType.Sign, lines),
+		}
+
+		transactions, err := ledger.Record(ctx, query, []ledger.Leg{leg})
+
+		if err != nil {
+			return posted, err
+		}
+
+		link := db.SetRecurringOccurrenceTransactionParams{
+			ID:            occurrence.ID,
+			TransactionID: pgtype.Int4{Int32: transactions[0].ID, Valid: true},
+		}
+
+		if err := query.SetRecurringOccurrenceTransaction(ctx, link); err != nil {
+			return posted, err
+		}
+
+		event := audit.Event{
+			AccountID:   rule.AccountID,
+			UserID:      rule.UserID,
+			Type:        audit.TransactionCreated,
+			Description: fmt.Sprintf("transaction %d created by recurring rule %d", transactions[0].ID, rule.ID),
+			After:       transactions[0],
+		}
+
+		if _, err := audit.Record(ctx, query, event); err != nil {
+			return posted, err
+		}
+
+		posted++
+	}
+
+	if err := envelopes.Verify(ctx, query); err != nil {
+		return posted, err
+	}
+
+	return posted, nil
+}
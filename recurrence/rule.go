@@ -0,0 +1,141 @@
+// Package recurrence expands recurring transaction rules into the dates they
+// fall on and posts the ones that are due.
+package recurrence
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+)
+
+// Rule describes when a recurring transaction happens: every Interval days,
+// weeks, months or years from Start, until End or until Count occurrences.
+// Monthly and yearly rules fall on DayOfMonth, or on the day of Start when it
+// is zero; months shorter than that day use their last day instead.
+type Rule struct {
+	Frequency  string
+	Interval   int
+	DayOfMonth int
+	Start      time.Time
+	End        time.Time
+	Count      int
+}
+
+// Validate reports the first problem that would make the rule unusable.
+func (rule Rule) Validate() error {
+	switch rule.Frequency {
+	case Daily, Weekly, Monthly, Yearly:
+	default:
+		return errors.New("frequency must be daily, weekly, monthly or yearly")
+	}
+
+	if rule.Interval < 1 {
+		return errors.New("interval must be at least 1")
+	}
+
+	if rule.DayOfMonth != 0 {
+		if rule.Frequency != Monthly && rule.Frequency != Yearly {
+			return errors.New("day_of_month only applies to monthly and yearly rules")
+		}
+
+		if rule.DayOfMonth < 1 || rule.DayOfMonth > 31 {
+			return errors.New("day_of_month must be between 1 and 31")
+		}
+	}
+
+	if rule.Start.IsZero() {
+		return errors.New("start_date was not provided")
+	}
+
+	if !rule.End.IsZero() && rule.End.Before(rule.Start) {
+		return errors.New("end_date cannot be before start_date")
+	}
+
+	if rule.Count < 0 {
+		return errors.New("count cannot be negative")
+	}
+
+	return nil
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// candidate returns the n-th date of the rule's pattern, which can fall
+// before Start when DayOfMonth is earlier in the month than Start.
+func (rule Rule) candidate(n int) time.Time {
+	start := rule.Start
+
+	switch rule.Frequency {
+	case Daily:
+		return start.AddDate(0, 0, n*rule.Interval)
+	case Weekly:
+		return start.AddDate(0, 0, 7*n*rule.Interval)
+	}
+
+	months := n * rule.Interval
+
+	if rule.Frequency == Yearly {
+		months *= 12
+	}
+
+	// Step from the first day of the month so AddDate never overflows into
+	// the next month.
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+
+	day := rule.DayOfMonth
+
+	if day == 0 {
+		day = start.Day()
+	}
+
+	if last := daysIn(first.Year(), first.Month()); day > last {
+		day = last
+	}
+
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Between returns the dates of the rule from from to through, both included,
+// in order.
+func (rule Rule) Between(from time.Time, through time.Time) []time.Time {
+	var dates []time.Time
+
+	occurrences := 0
+
+	for n := 0; ; n++ {
+		date := rule.candidate(n)
+
+		if date.After(through) || (!rule.End.IsZero() && date.After(rule.End)) {
+			return dates
+		}
+
+		if date.Before(rule.Start) {
+			continue
+		}
+
+		occurrences++
+
+		if rule.Count > 0 && occurrences > rule.Count {
+			return dates
+		}
+
+		if !date.Before(from) {
+			dates = append(dates, date)
+		}
+	}
+}
+
+// Includes reports whether the rule falls on date.
+func (rule Rule) Includes(date time.Time) bool {
+	dates := rule.Between(date, date)
+
+	return len(dates) == 1
+}
@@ -0,0 +1,172 @@
+package recurrence
+
+import (
+	db "cashpal/database/generated"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func day(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := Rule{Frequency: Monthly, Interval: 1, Start: day(2026, 1, 1)}
+
+	tests := []struct {
+		name    string
+		change  func(*Rule)
+		wantErr bool
+	}{
+		{"valid", func(*Rule) {}, false},
+		{"day of month", func(r *Rule) { r.DayOfMonth = 31 }, false},
+		{"end on start", func(r *Rule) { r.End = r.Start }, false},
+		{"unknown frequency", func(r *Rule) { r.Frequency = "hourly" }, true},
+		{"zero interval", func(r *Rule) { r.Interval = 0 }, true},
+		{"day of month on a weekly rule", func(r *Rule) { r.Frequency = Weekly; r.DayOfMonth = 3 }, true},
+		{"day of month out of range", func(r *Rule) { r.DayOfMonth = 32 }, true},
+		{"no start", func(r *Rule) { r.Start = time.Time{} }, true},
+		{"end before start", func(r *Rule) { r.End = day(2025, 12, 31) }, true},
+		{"negative count", func(r *Rule) { r.Count = -1 }, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule := valid
+			test.change(&rule)
+
+			if err := rule.Validate(); (err != nil) != test.wantErr {
+				t.Errorf("err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		from    time.Time
+		through time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "daily",
+			rule:    Rule{Frequency: Daily, Interval: 3, Start: day(2026, 1, 30)},
+			from:    day(2026, 1, 1),
+			through: day(2026, 2, 5),
+			want:    []time.Time{day(2026, 1, 30), day(2026, 2, 2), day(2026, 2, 5)},
+		},
+		{
+			name:    "every other week",
+			rule:    Rule{Frequency: Weekly, Interval: 2, Start: day(2026, 1, 1)},
+			from:    day(2026, 1, 1),
+			through: day(2026, 2, 1),
+			want:    []time.Time{day(2026, 1, 1), day(2026, 1, 15), day(2026, 1, 29)},
+		},
+		{
+			name:    "end of the month",
+			rule:    Rule{Frequency: Monthly, Interval: 1, Start: day(2026, 1, 31)},
+			from:    day(2026, 1, 1),
+			through: day(2026, 5, 31),
+			want:    []time.Time{day(2026, 1, 31), day(2026, 2, 28), day(2026, 3, 31), day(2026, 4, 30), day(2026, 5, 31)},
+		},
+		{
+			name:    "day of month before the start",
+			rule:    Rule{Frequency: Monthly, Interval: 1, DayOfMonth: 15, Start: day(2026, 1, 20)},
+			from:    day(2026, 1, 1),
+			through: day(2026, 3, 31),
+			want:    []time.Time{day(2026, 2, 15), day(2026, 3, 15)},
+		},
+		{
+			name:    "quarterly until the end date",
+			rule:    Rule{Frequency: Monthly, Interval: 3, Start: day(2026, 1, 10), End: day(2026, 7, 9)},
+			from:    day(2026, 1, 1),
+			through: day(2026, 12, 31),
+			want:    []time.Time{day(2026, 1, 10), day(2026, 4, 10)},
+		},
+		{
+			name:    "leap day",
+			rule:    Rule{Frequency: Yearly, Interval: 1, Start: day(2024, 2, 29)},
+			from:    day(2025, 1, 1),
+			through: day(2028, 12, 31),
+			want:    []time.Time{day(2025, 2, 28), day(2026, 2, 28), day(2027, 2, 28), day(2028, 2, 29)},
+		},
+		{
+			name:    "count includes dates before from",
+			rule:    Rule{Frequency: Daily, Interval: 1, Start: day(2026, 1, 1), Count: 3},
+			from:    day(2026, 1, 2),
+			through: day(2026, 1, 10),
+			want:    []time.Time{day(2026, 1, 2), day(2026, 1, 3)},
+		},
+		{
+			name:    "through before the start",
+			rule:    Rule{Frequency: Weekly, Interval: 1, Start: day(2026, 3, 1)},
+			from:    day(2026, 1, 1),
+			through: day(2026, 2, 28),
+			want:    nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.rule.Between(test.from, test.through); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Between = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRuleIncludes(t *testing.T) {
+	rule := Rule{Frequency: Monthly, Interval: 2, DayOfMonth: 31, Start: day(2026, 1, 1), Count: 3}
+
+	tests := []struct {
+		date time.Time
+		want bool
+	}{
+		{day(2026, 1, 31), true},
+		{day(2026, 2, 28), false},
+		{day(2026, 3, 31), true},
+		{day(2026, 5, 31), true},
+		{day(2026, 7, 31), false},
+		{day(2025, 12, 31), false},
+	}
+
+	for _, test := range tests {
+		if got := rule.Includes(test.date); got != test.want {
+			t.Errorf("Includes(%s) = %v, want %v", test.date.Format(time.DateOnly), got, test.want)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	rule := db.RecurringRule{
+		Frequency:     Weekly,
+		IntervalCount: 1,
+		StartDate:     pgtype.Date{Time: day(2026, 1, 5), Valid: true},
+		EndDate:       pgtype.Date{Time: day(2026, 2, 2), Valid: true},
+	}
+
+	tests := []struct {
+		name           string
+		lastOccurrence pgtype.Date
+		today          time.Time
+		want           []time.Time
+	}{
+		{"never posted", pgtype.Date{}, day(2026, 1, 19), []time.Time{day(2026, 1, 5), day(2026, 1, 12), day(2026, 1, 19)}},
+		{"posted before", pgtype.Date{Time: day(2026, 1, 12), Valid: true}, day(2026, 1, 20), []time.Time{day(2026, 1, 19)}},
+		{"after the end date", pgtype.Date{Time: day(2026, 1, 26), Valid: true}, day(2026, 3, 1), []time.Time{day(2026, 2, 2)}},
+		{"nothing due", pgtype.Date{Time: day(2026, 1, 19), Valid: true}, day(2026, 1, 25), nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Due(rule, test.lastOccurrence, test.today); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Due = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package settle
+
+import (
+	"cashpal/money"
+	"sort"
+)
+
+// exactLimit is the largest number of members with an open balance for which
+// Simplify searches for the fewest payments. The search is exponential, so
+// larger groups fall back to the greedy matching alone.
+const exactLimit = 16
+
+// Payment is a repayment of Amount from one member to another.
+type Payment struct {
+	From   int32        `json:"from_user_id"`
+	To     int32        `json:"to_user_id"`
+	Amount money.Amount `json:"amount"`
+}
+
+type balance struct {
+	userID int32
+	amount money.Amount
+}
+
+// Simplify returns payments that bring every balance to zero. Positive
+// balances are owed money, negative ones owe it, and all of them add up to
+// zero.
+//
+// A group of n members whose balances add up to zero never needs more than
+// n-1 payments, so the fewest payments come from splitting the members into
+// as many zero-sum groups as possible. Simplify finds that split exactly for
+// up to exactLimit members and settles each group by repeatedly matching its
+// largest debtor with its largest creditor.
+func Simplify(balances map[int32]money.Amount) []Payment {
+	var open []balance
+
+	for userID, amount := range balances {
+		if amount != 0 {
+			open = append(open, balance{userID: userID, amount: amount})
+		}
+	}
+
+	sort.Slice(open, func(i, j int) bool { return open[i].userID < open[j].userID })
+
+	payments := []Payment{}
+
+	if len(open) > exactLimit {
+		return append(payments, greedy(open)...)
+	}
+
+	for _, group := range zeroSumGroups(open) {
+		payments = append(payments, greedy(group)...)
+	}
+
+	return payments
+}
+
+// zeroSumGroups splits balances into the largest number of groups that each
+// add up to zero.
+//
+// best[mask] is the most zero-sum groups the members in mask can be split
+// into when they are taken in some order and a group is closed every time
+// the members taken so far add up to zero.
+func zeroSumGroups(balances []balance) [][]balance {
+	n := len(balances)
+	size := 1 << n
+
+	sums := make([]money.Amount, size)
+	best := make([]int, size)
+
+	for mask := 1; mask < size; mask++ {
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				sums[mask] = sums[mask&^(1<<i)] + balances[i].amount
+				break
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 && best[mask&^(1<<i)] > best[mask] {
+				best[mask] = best[mask&^(1<<i)]
+			}
+		}
+
+		if sums[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	// Walk back from all members, removing one member at a time along an
+	// optimal order. Members removed between two zero-sum masks form a group.
+	var groups [][]balance
+	var group []balance
+
+	for mask := size - 1; mask != 0; {
+		closed := 0
+
+		if sums[mask] == 0 {
+			closed = 1
+
+			if len(group) > 0 {
+				groups = append(groups, group)
+				group = nil
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 && best[mask&^(1<<i)]+closed == best[mask] {
+				group = append(group, balances[i])
+				mask &^= 1 << i
+				break
+			}
+		}
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// greedy settles balances by paying the largest debt to the largest credit
+// until nothing is left. Each payment clears at least one member, so a group
+// of n members that adds up to zero needs at most n-1 payments.
+func greedy(balances []balance) []Payment {
+	var creditors, debtors []balance
+
+	for _, item := range balances {
+		if item.amount > 0 {
+			creditors = append(creditors, item)
+		} else if item.amount < 0 {
+			debtors = append(debtors, balance{userID: item.userID, amount: -item.amount})
+		}
+	}
+
+	var payments []Payment
+
+	for len(creditors) > 0 && len(debtors) > 0 {
+		sortLargestFirst(creditors)
+		sortLargestFirst(debtors)
+
+		amount := creditors[0].amount
+
+		if debtors[0].amount < amount {
+			amount = debtors[0].amount
+		}
+
+		payments = append(payments, Payment{From: debtors[0].userID, To: creditors[0].userID, Amount: amount})
+
+		creditors[0].amount -= amount
+		debtors[0].amount -= amount
+
+		if creditors[0].amount == 0 {
+			creditors = creditors[1:]
+		}
+
+		if debtors[0].amount == 0 {
+			debtors = debtors[1:]
+		}
+	}
+
+	return payments
+}
+
+func sortLargestFirst(balances []balance) {
+	sort.SliceStable(balances, func(i, j int) bool {
+		if balances[i].amount != balances[j].amount {
+			return balances[i].amount > balances[j].amount
+		}
+
+		return balances[i].userID < balances[j].userID
+	})
+}
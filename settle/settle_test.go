@@ -0,0 +1,97 @@
+package settle
+
+import (
+	"cashpal/money"
+	"reflect"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances map[int32]money.Amount
+		want     []Payment
+		count    int
+	}{
+		{
+			name:     "nothing owed",
+			balances: map[int32]money.Amount{1: 0, 2: 0},
+			want:     []Payment{},
+		},
+		{
+			name:     "one debt",
+			balances: map[int32]money.Amount{1: money.MustParse("25"), 2: money.MustParse("-25")},
+			want:     []Payment{{From: 2, To: 1, Amount: money.MustParse("25")}},
+		},
+		{
+			name: "largest debtor pays first",
+			balances: map[int32]money.Amount{
+				1: money.MustParse("30"),
+				2: money.MustParse("-10"),
+				3: money.MustParse("-20"),
+			},
+			want: []Payment{
+				{From: 3, To: 1, Amount: money.MustParse("20")},
+				{From: 2, To: 1, Amount: money.MustParse("10")},
+			},
+		},
+		{
+			name: "zero-sum groups",
+			balances: map[int32]money.Amount{
+				1: money.MustParse("10"),
+				2: money.MustParse("5"),
+				3: money.MustParse("-6"),
+				4: money.MustParse("-4"),
+				5: money.MustParse("-5"),
+			},
+			count: 3,
+		},
+		{
+			name: "pairs inside a larger group",
+			balances: map[int32]money.Amount{
+				1: money.MustParse("6"),
+				2: money.MustParse("4"),
+				3: money.MustParse("3"),
+				4: money.MustParse("-5"),
+				5: money.MustParse("-5"),
+				6: money.MustParse("-3"),
+			},
+			count: 4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payments := Simplify(test.balances)
+
+			if test.want != nil && !reflect.DeepEqual(payments, test.want) {
+				t.Fatalf("payments = %+v, want %+v", payments, test.want)
+			}
+
+			if test.want == nil && len(payments) != test.count {
+				t.Errorf("payments = %+v, want %d of them", payments, test.count)
+			}
+
+			left := make(map[int32]money.Amount)
+
+			for userID, amount := range test.balances {
+				left[userID] = amount
+			}
+
+			for _, payment := range payments {
+				if payment.Amount <= 0 {
+					t.Errorf("payment %+v is not positive", payment)
+				}
+
+				left[payment.From] += payment.Amount
+				left[payment.To] -= payment.Amount
+			}
+
+			for userID, amount := range left {
+				if amount != 0 {
+					t.Errorf("user %d is left with %s", userID, amount)
+				}
+			}
+		})
+	}
+}
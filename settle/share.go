@@ -0,0 +1,124 @@
+// Package settle splits shared expenses between the members of an account
+// and works out the fewest repayments that settle everyone's balance.
+package settle
+
+import (
+	"cashpal/money"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+const (
+	Equal   = "equal"
+	Exact   = "exact"
+	Percent = "percent"
+	Weight  = "weight"
+)
+
+var hundredPercent = money.MustParse("100")
+
+// unit returns the smallest amount of a currency, in stored units.
+func unit(currency string) int64 {
+	exponent, ok := money.Exponent(currency)
+
+	if !ok || exponent >= money.Scale {
+		return 1
+	}
+
+	step := int64(1)
+
+	for i := exponent; i < money.Scale; i++ {
+		step *= 10
+	}
+
+	return step
+}
+
+// Allocate divides total between participants. values holds one entry per
+// participant: the exact amount, the percentage or the weight of each one,
+// and is ignored for equal shares. Shares are rounded to the currency and
+// always add up to total; the units left over by rounding go to the shares
+// that lost the most to it, first participants first on ties.
+func Allocate(total money.Amount, method string, values []money.Amount, currency string) ([]money.Amount, error) {
+	if len(values) == 0 {
+		return nil, errors.New("at least one participant is needed")
+	}
+
+	if method == Exact {
+		var sum money.Amount
+
+		for _, value := range values {
+			if value < 0 {
+				return nil, errors.New("shares cannot be negative")
+			}
+
+			if err := value.Validate(currency); err != nil {
+				return nil, err
+			}
+
+			sum += value
+		}
+
+		if sum != total {
+			return nil, errors.New("exact shares must add up to the transaction amount")
+		}
+
+		return values, nil
+	}
+
+	weights := make([]int64, len(values))
+	var sum int64
+
+	for i, value := range values {
+		switch method {
+		case Equal:
+			weights[i] = 1
+		case Percent, Weight:
+			if value <= 0 {
+				return nil, errors.New("percentages and weights must be positive")
+			}
+
+			weights[i] = int64(value)
+		default:
+			return nil, errors.New("method must be equal, exact, percent or weight")
+		}
+
+		sum += weights[i]
+	}
+
+	if method == Percent && sum != int64(hundredPercent) {
+		return nil, errors.New("percentages must add up to 100")
+	}
+
+	step := unit(currency)
+	units := big.NewInt(int64(total) / step)
+
+	shares := make([]money.Amount, len(values))
+	remainders := make([]*big.Int, len(values))
+	allocated := new(big.Int)
+
+	for i, weight := range weights {
+		quotient, remainder := new(big.Int).QuoRem(new(big.Int).Mul(units, big.NewInt(weight)), big.NewInt(sum), new(big.Int))
+
+		shares[i] = money.Amount(quotient.Int64() * step)
+		remainders[i] = remainder
+		allocated.Add(allocated, quotient)
+	}
+
+	order := make([]int, len(values))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return remainders[order[i]].Cmp(remainders[order[j]]) > 0 })
+
+	left := new(big.Int).Sub(units, allocated).Int64()
+
+	for i := int64(0); i < left; i++ {
+		shares[order[i]] += money.Amount(step)
+	}
+
+	return shares, nil
+}
@@ -0,0 +1,59 @@
+package settle
+
+import (
+	"cashpal/money"
+	"reflect"
+	"testing"
+)
+
+func amounts(values ...string) []money.Amount {
+	result := make([]money.Amount, len(values))
+
+	for i, value := range values {
+		result[i] = money.MustParse(value)
+	}
+
+	return result
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    string
+		method   string
+		values   []money.Amount
+		currency string
+		want     []money.Amount
+		wantErr  bool
+	}{
+		{"equal", "100", Equal, make([]money.Amount, 3), "USD", amounts("33.34", "33.33", "33.33"), false},
+		{"equal without minor units", "1000", Equal, make([]money.Amount, 3), "JPY", amounts("334", "333", "333"), false},
+		{"equal with three decimals", "1", Equal, make([]money.Amount, 3), "KWD", amounts("0.334", "0.333", "0.333"), false},
+		{"exact", "10", Exact, amounts("7.5", "2.5"), "USD", amounts("7.5", "2.5"), false},
+		{"percent", "10.01", Percent, amounts("50", "30", "20"), "USD", amounts("5.01", "3", "2"), false},
+		{"weight", "10", Weight, amounts("1", "2"), "USD", amounts("3.33", "6.67"), false},
+		{"fractional weight", "9", Weight, amounts("0.5", "1"), "EUR", amounts("3", "6"), false},
+		{"negative total", "-10", Equal, make([]money.Amount, 2), "USD", amounts("-5", "-5"), false},
+		{"no participants", "10", Equal, nil, "USD", nil, true},
+		{"exact shares do not add up", "10", Exact, amounts("7", "2"), "USD", nil, true},
+		{"negative exact share", "10", Exact, amounts("12", "-2"), "USD", nil, true},
+		{"exact share below the currency unit", "10", Exact, amounts("9.5", "0.5"), "JPY", nil, true},
+		{"percentages do not add up", "10", Percent, amounts("50", "40"), "USD", nil, true},
+		{"zero weight", "10", Weight, amounts("1", "0"), "USD", nil, true},
+		{"unknown method", "10", "shares", amounts("1"), "USD", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Allocate(money.MustParse(test.total), test.method, test.values, test.currency)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("shares = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
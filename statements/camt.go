@@ -0,0 +1,443 @@
+package statements
+
+import (
+	"bytes"
+	"cashpal/money"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// camtAmount is an amount with its currency in the Ccy attribute.
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtDate is a date, or a date time of which only the date is used.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtAccount struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+func (a camtAccount) id() string {
+	if a.IBAN != "" {
+		return a.IBAN
+	}
+
+	return a.Other
+}
+
+// camtParty is a debtor or creditor. Newer versions of the format wrap the
+// name in a Pty element.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	return firstOf(p.Name, p.PartyName)
+}
+
+// camtStatus is the status of an entry, a code of its own in older versions
+// of the format and wrapped in a Cd element in newer ones.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+func (s camtStatus) code() string {
+	return firstOf(s.Code, s.Text)
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtTransactionDetails struct {
+	EndToEndID       string      `xml:"Refs>EndToEndId"`
+	Debtor           camtParty   `xml:"RltdPties>Dbtr"`
+	DebtorAccount    camtAccount `xml:"RltdPties>DbtrAcct"`
+	Creditor         camtParty   `xml:"RltdPties>Cdtr"`
+	CreditorAccount  camtAccount `xml:"RltdPties>CdtrAcct"`
+	Unstructured     []string    `xml:"RmtInf>Ustrd"`
+	AdditionalDetail string      `xml:"AddtlTxInf"`
+}
+
+// counterparty returns the other side of a payment: the creditor of money
+// leaving the account and the debtor of money coming in.
+func (d camtTransactionDetails) counterparty(debit bool) (string, string) {
+	if debit {
+		return d.Creditor.name(), d.CreditorAccount.id()
+	}
+
+	return d.Debtor.name(), d.DebtorAccount.id()
+}
+
+type camtEntry struct {
+	Reference        string                   `xml:"NtryRef"`
+	Amount           camtAmount               `xml:"Amt"`
+	Indicator        string                   `xml:"CdtDbtInd"`
+	Reversal         bool                     `xml:"RvslInd"`
+	Status           camtStatus               `xml:"Sts"`
+	BookingDate      camtDate                 `xml:"BookgDt"`
+	ValueDate        camtDate                 `xml:"ValDt"`
+	BankReference    string                   `xml:"AcctSvcrRef"`
+	Details          []camtTransactionDetails `xml:"NtryDtls>TxDtls"`
+	AdditionalDetail string                   `xml:"AddtlNtryInf"`
+}
+
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func (d camtDate) parse() (pgtype.Date, error) {
+	value := firstOf(d.Date, d.DateTime)
+
+	if len(value) < 10 {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	date, err := time.Parse("2006-01-02", value[:10])
+
+	if err != nil {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return pgtype.Date{Time: date, Valid: true}, nil
+}
+
+// signed applies a credit or debit indicator to an amount, which the format
+// always writes without a sign.
+func (a camtAmount) signed(indicator string) (money.Amount, error) {
+	amount, err := ParseAmount(a.Value, ".")
+
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("invalid amount %q", a.Value)
+	}
+
+	switch strings.TrimSpace(indicator) {
+	case "CRDT":
+		return amount, nil
+	case "DBIT":
+		return -amount, nil
+	default:
+		return 0, fmt.Errorf("invalid credit or debit indicator %q", indicator)
+	}
+}
+
+func (a camtAmount) currency() string {
+	return strings.ToUpper(strings.TrimSpace(a.Currency))
+}
+
+// latin1Reader lets the XML decoder read files that declare a Latin-1 or
+// Windows-1252 encoding.
+func latin1Reader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "iso-8859-1", "iso-8859-15", "latin1", "windows-1252", "cp1252":
+	default:
+		return nil, fmt.Errorf("unsupported character set %q", label)
+	}
+
+	data, err := io.ReadAll(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.NewReader(latin1(data)), nil
+}
+
+// camtStatement collects the account, balances and entries of one Stmt, Rpt
+// or Ntfctn element.
+type camtStatement struct {
+	id          string
+	account     camtAccount
+	entries     []camtEntry
+	lines       []int
+	balance     *camtBalance
+	balanceLine int
+}
+
+func isCAMTStatement(name string) bool {
+	return name == "Stmt" || name == "Rpt" || name == "Ntfctn"
+}
+
+// ParseCAMT reads ISO 20022 bank to customer statements: camt.053 end of
+// day statements, and the camt.052 reports and camt.054 notifications that
+// share their layout. A file may hold several statements, for one or more
+// accounts. Only booked entries are imported, and the closing booked balance
+// of every statement is reported in the statement balances.
+//
+// The external id of a row is the bank's reference for the entry, prefixed
+// with the account, or else the position of the entry in its statement.
+// End-to-end references and counterparties are kept in the row metadata.
+func ParseCAMT(r io.Reader) (Statement, error) {
+	statement := newStatement()
+
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return statement, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = latin1Reader
+
+	var current *camtStatement
+	var parents []string
+	found := false
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			line, _ := decoder.InputPos()
+			return statement, fmt.Errorf("invalid camt XML: line %d: %w", line, err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			line, _ := decoder.InputPos()
+			name := element.Name.Local
+
+			if current == nil {
+				if isCAMTStatement(name) {
+					current = &camtStatement{}
+					found = true
+				}
+
+				parents = append(parents, name)
+				continue
+			}
+
+			// Only the direct children of a statement are read; anything
+			// deeper belongs to its summary or group header.
+			if !isCAMTStatement(parents[len(parents)-1]) {
+				parents = append(parents, name)
+				continue
+			}
+
+			switch name {
+			case "Id":
+				if err := decoder.DecodeElement(&current.id, &element); err != nil {
+					return statement, fmt.Errorf("invalid camt XML: line %d: %w", line, err)
+				}
+			case "Acct":
+				if err := decoder.DecodeElement(&current.account, &element); err != nil {
+					return statement, fmt.Errorf("invalid camt XML: line %d: %w", line, err)
+				}
+			case "Bal":
+				var balance camtBalance
+
+				if err := decoder.DecodeElement(&balance, &element); err != nil {
+					return statement, fmt.Errorf("invalid camt XML: line %d: %w", line, err)
+				}
+
+				// The closing booked balance is the one the bank stands
+				// behind; an interim balance is kept only when there is no
+				// closing one.
+				if balance.Type == "CLBD" || (balance.Type == "ITBD" && (current.balance == nil || current.balance.Type != "CLBD")) {
+					current.balance = &balance
+					current.balanceLine = line
+				}
+			case "Ntry":
+				var entry camtEntry
+
+				if err := decoder.DecodeElement(&entry, &element); err != nil {
+					return statement, fmt.Errorf("invalid camt XML: line %d: %w", line, err)
+				}
+
+				current.entries = append(current.entries, entry)
+				current.lines = append(current.lines, line)
+			default:
+				parents = append(parents, name)
+			}
+		case xml.EndElement:
+			if len(parents) == 0 {
+				continue
+			}
+
+			name := parents[len(parents)-1]
+			parents = parents[:len(parents)-1]
+
+			if current != nil && isCAMTStatement(name) {
+				readCAMTStatement(&statement, current)
+				current = nil
+			}
+		}
+	}
+
+	if !found {
+		return statement, errors.New("the camt file holds no statement")
+	}
+
+	return statement, nil
+}
+
+func readCAMTStatement(statement *Statement, current *camtStatement) {
+	account := current.account.id()
+
+	for i, entry := range current.entries {
+		if status := entry.Status.code(); status != "" && status != "BOOK" {
+			statement.Skipped++
+			continue
+		}
+
+		row, err := camtRow(entry, current, i)
+
+		if err != nil {
+			statement.Fail(current.lines[i], "%s", err)
+			continue
+		}
+
+		row.Line = current.lines[i]
+		statement.add(row)
+	}
+
+	if current.balance == nil {
+		return
+	}
+
+	amount, err := current.balance.Amount.signed(current.balance.Indicator)
+
+	if err != nil {
+		statement.Fail(current.balanceLine, "closing balance has an %s", err)
+		return
+	}
+
+	date, err := current.balance.Date.parse()
+
+	if err != nil {
+		statement.Fail(current.balanceLine, "closing balance has an %s", err)
+		return
+	}
+
+	statement.Balances = append(statement.Balances, Balance{
+		Account:  account,
+		Currency: firstOf(current.balance.Amount.currency(), strings.ToUpper(current.account.Currency)),
+		Date:     date,
+		Amount:   amount,
+	})
+}
+
+func camtRow(entry camtEntry, current *camtStatement, index int) (Row, error) {
+	row := Row{Currency: firstOf(entry.Amount.currency(), strings.ToUpper(current.account.Currency))}
+
+	var err error
+
+	row.Amount, err = entry.Amount.signed(entry.Indicator)
+
+	if err != nil {
+		return row, err
+	}
+
+	dateValue := entry.BookingDate
+
+	if firstOf(dateValue.Date, dateValue.DateTime) == "" {
+		dateValue = entry.ValueDate
+	}
+
+	row.Date, err = dateValue.parse()
+
+	if err != nil {
+		return row, err
+	}
+
+	account := current.account.id()
+	metadata := map[string]string{}
+
+	if current.id != "" {
+		metadata[MetadataStatement] = current.id
+	}
+
+	// The indicator of a reversal already gives the direction the money moves
+	// back in, so the flag is only kept for reference.
+	if entry.Reversal {
+		metadata[MetadataReversal] = "true"
+	}
+
+	if reference := strings.TrimSpace(entry.BankReference); reference != "" {
+		metadata[MetadataBankReference] = reference
+		row.ExternalID = reference
+	} else {
+		row.ExternalID = current.id + "/" + strconv.Itoa(index+1)
+	}
+
+	if account != "" {
+		row.ExternalID = account + ":" + row.ExternalID
+	}
+
+	var remittance []string
+
+	// A batch entry has one transaction detail per payment; the first one
+	// names the counterparty of the entry.
+	for _, details := range entry.Details {
+		if endToEndID := strings.TrimSpace(details.EndToEndID); endToEndID != "" && endToEndID != "NOTPROVIDED" && metadata[MetadataEndToEndID] == "" {
+			metadata[MetadataEndToEndID] = endToEndID
+		}
+
+		name, counterpartyAccount := details.counterparty(row.Amount < 0)
+
+		if name != "" && metadata[MetadataCounterpartyName] == "" {
+			metadata[MetadataCounterpartyName] = name
+		}
+
+		if counterpartyAccount != "" && metadata[MetadataCounterpartyAccount] == "" {
+			metadata[MetadataCounterpartyAccount] = counterpartyAccount
+		}
+
+		for _, line := range details.Unstructured {
+			if line = strings.TrimSpace(line); line != "" {
+				remittance = append(remittance, line)
+			}
+		}
+
+		if len(details.Unstructured) == 0 && strings.TrimSpace(details.AdditionalDetail) != "" {
+			remittance = append(remittance, strings.TrimSpace(details.AdditionalDetail))
+		}
+	}
+
+	description := strings.Join(remittance, " ")
+
+	if description == "" {
+		description = strings.TrimSpace(entry.AdditionalDetail)
+	}
+
+	if name := metadata[MetadataCounterpartyName]; name != "" && !strings.Contains(description, name) {
+		description = strings.TrimSpace(name + " " + description)
+	}
+
+	row.Description = strings.Join(strings.Fields(description), " ")
+
+	if len(metadata) > 0 {
+		row.Metadata = metadata
+	}
+
+	return row, nil
+}
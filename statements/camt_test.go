@@ -0,0 +1,172 @@
+package statements
+
+import (
+	"cashpal/money"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// camtStatements holds two statements for two accounts: a debit with its
+// details, a reversed debit coming back as a credit and a pending entry in
+// the first, and a reversed credit in the second.
+const camtStatements = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+<BkToCstmrStmt>
+<GrpHdr><MsgId>MSG1</MsgId></GrpHdr>
+<Stmt>
+<Id>S1</Id>
+<Acct><Id><IBAN>DE01</IBAN></Id><Ccy>EUR</Ccy></Acct>
+<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2026-01-01</Dt></Dt></Bal>
+<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">70.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2026-01-31</Dt></Dt></Bal>
+<Ntry>
+<Amt Ccy="EUR">50.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+<BookgDt><Dt>2026-01-05</Dt></BookgDt>
+<AcctSvcrRef>REF1</AcctSvcrRef>
+<NtryDtls><TxDtls>
+<Refs><EndToEndId>E2E-1</EndToEndId></Refs>
+<RltdPties><Dbtr><Nm>Me</Nm></Dbtr><Cdtr><Nm>Landlord</Nm></Cdtr><CdtrAcct><Id><IBAN>DE99</IBAN></Id></CdtrAcct></RltdPties>
+<RmtInf><Ustrd>Rent</Ustrd><Ustrd>January</Ustrd></RmtInf>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">20.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><RvslInd>true</RvslInd><Sts><Cd>BOOK</Cd></Sts>
+<BookgDt><DtTm>2026-01-06T10:00:00</DtTm></BookgDt>
+<AddtlNtryInf>Return of   card payment</AddtlNtryInf>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">5.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>PDNG</Sts>
+<BookgDt><Dt>2026-01-07</Dt></BookgDt>
+</Ntry>
+</Stmt>
+<Stmt>
+<Id>S2</Id>
+<Acct><Id><Othr><Id>12345</Id></Othr></Id><Ccy>USD</Ccy></Acct>
+<Bal><Tp><CdOrPrtry><Cd>ITBD</Cd></CdOrPrtry></Tp><Amt>10.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Dt><Dt>2026-01-31</Dt></Dt></Bal>
+<Ntry>
+<Amt>10.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><RvslInd>true</RvslInd>
+<ValDt><Dt>2026-01-08</Dt></ValDt>
+<NtryDtls><TxDtls><RltdPties><Dbtr><Nm>Employer</Nm></Dbtr><DbtrAcct><Id><Othr><Id>777</Id></Othr></Id></DbtrAcct></RltdPties><AddtlTxInf>Salary returned</AddtlTxInf></TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="USD">1.00</Amt><CdtDbtInd>BOTH</CdtDbtInd>
+<BookgDt><Dt>2026-01-09</Dt></BookgDt>
+</Ntry>
+</Stmt>
+</BkToCstmrStmt>
+</Document>
+`
+
+func TestParseCAMT(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		rows     []Row
+		errors   []int
+		skipped  int
+		balances []Balance
+		wantErr  bool
+	}{
+		{
+			name:  "reversals and several statements",
+			input: camtStatements,
+			rows: []Row{
+				{
+					Line:        10,
+					Date:        date(2026, 1, 5),
+					Amount:      money.MustParse("-50"),
+					Description: "Landlord Rent January",
+					Currency:    "EUR",
+					ExternalID:  "DE01:REF1",
+					Metadata: map[string]string{
+						MetadataStatement:           "S1",
+						MetadataBankReference:       "REF1",
+						MetadataEndToEndID:          "E2E-1",
+						MetadataCounterpartyName:    "Landlord",
+						MetadataCounterpartyAccount: "DE99",
+					},
+				},
+				{
+					Line:        20,
+					Date:        date(2026, 1, 6),
+					Amount:      money.MustParse("20"),
+					Description: "Return of card payment",
+					Currency:    "EUR",
+					ExternalID:  "DE01:S1/2",
+					Metadata: map[string]string{
+						MetadataStatement: "S1",
+						MetadataReversal:  "true",
+					},
+				},
+				{
+					Line:        34,
+					Date:        date(2026, 1, 8),
+					Amount:      money.MustParse("-10"),
+					Description: "Salary returned",
+					Currency:    "USD",
+					ExternalID:  "12345:S2/1",
+					Metadata: map[string]string{
+						MetadataStatement: "S2",
+						MetadataReversal:  "true",
+					},
+				},
+			},
+			errors:  []int{39},
+			skipped: 1,
+			balances: []Balance{
+				{Account: "DE01", Currency: "EUR", Date: date(2026, 1, 31), Amount: money.MustParse("70")},
+				{Account: "12345", Currency: "USD", Date: date(2026, 1, 31), Amount: money.MustParse("-10")},
+			},
+		},
+		{
+			name: "notification",
+			input: `<Document><BkToCstmrDbtCdtNtfctn><Ntfctn><Id>N1</Id>
+<Ntry><Amt Ccy="CHF">12.30</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts><BookgDt><Dt>2026-03-01</Dt></BookgDt><NtryDtls><TxDtls><Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs><RltdPties><Dbtr><Pty><Nm>Customer</Nm></Pty></Dbtr></RltdPties></TxDtls></NtryDtls></Ntry>
+</Ntfctn></BkToCstmrDbtCdtNtfctn></Document>`,
+			rows: []Row{{
+				Line:        2,
+				Date:        date(2026, 3, 1),
+				Amount:      money.MustParse("12.30"),
+				Description: "Customer",
+				Currency:    "CHF",
+				ExternalID:  "N1/1",
+				Metadata: map[string]string{
+					MetadataStatement:        "N1",
+					MetadataCounterpartyName: "Customer",
+				},
+			}},
+			errors:   []int{},
+			balances: []Balance{},
+		},
+		{
+			name:    "no statement",
+			input:   "<Document><BkToCstmrStmt></BkToCstmrStmt></Document>",
+			wantErr: true,
+		},
+		{
+			name:    "invalid XML",
+			input:   "<Document><Stmt><Ntry></Stmt>",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := ParseCAMT(strings.NewReader(test.input))
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			checkStatement(t, statement, test.rows, test.errors, test.skipped)
+
+			if !reflect.DeepEqual(statement.Balances, test.balances) {
+				t.Errorf("balances = %+v, want %+v", statement.Balances, test.balances)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package statements
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// dateTokens maps the date format placeholders of an import profile to Go
+// layout elements. Longer placeholders come first so that YYYY is not read
+// as two YY.
+var dateTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DD", "02"},
+	{"D", "2"},
+}
+
+// DateLayout turns a date format such as "DD.MM.YYYY" into a Go time layout.
+// Any character other than the Y, M and D placeholders is kept as is.
+func DateLayout(format string) (string, error) {
+	var layout strings.Builder
+	year, month, day := false, false, false
+
+	for format != "" {
+		matched := false
+
+		for _, token := range dateTokens {
+			if strings.HasPrefix(format, token.token) {
+				layout.WriteString(token.layout)
+				format = format[len(token.token):]
+				matched = true
+
+				switch token.token[0] {
+				case 'Y':
+					year = true
+				case 'M':
+					month = true
+				case 'D':
+					day = true
+				}
+
+				break
+			}
+		}
+
+		if matched {
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(format)
+
+		if r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return "", fmt.Errorf("date format may only use YYYY, YY, MM, M, DD and D placeholders, found %q", r)
+		}
+
+		layout.WriteRune(r)
+		format = format[size:]
+	}
+
+	if !year || !month || !day {
+		return "", errors.New("date format needs a year, a month and a day")
+	}
+
+	return layout.String(), nil
+}
+
+// ParseAmount reads an amount written with the given decimal separator. The
+// other separator, spaces and apostrophes are taken as thousands separators.
+// Negative amounts may be written with a leading or trailing minus sign or
+// in parentheses.
+func ParseAmount(value string, decimalSeparator string) (money.Amount, error) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, "\u00a0", " "))
+	negative := false
+
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+
+	if strings.HasSuffix(value, "-") {
+		negative = true
+		value = value[:len(value)-1]
+	}
+
+	thousands := ","
+
+	if decimalSeparator == "," {
+		thousands = "."
+	}
+
+	value = strings.NewReplacer(thousands, "", " ", "", "'", "").Replace(value)
+
+	if decimalSeparator == "," {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+
+	amount, err := money.Parse(value)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
+
+func delimiterRune(delimiter string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(delimiter)
+
+	if size == 0 || size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, fmt.Errorf("delimiter %q must be a single character other than a quote or a line break", delimiter)
+	}
+
+	return r, nil
+}
+
+// ValidateProfile checks that a CSV import profile can be used to read a
+// statement.
+func ValidateProfile(profile db.CreateImportProfileParams) error {
+	if strings.TrimSpace(profile.Name) == "" {
+		return errors.New("name was not provided")
+	}
+
+	if _, err := delimiterRune(profile.Delimiter); err != nil {
+		return err
+	}
+
+	if _, err := DateLayout(profile.DateFormat); err != nil {
+		return err
+	}
+
+	if profile.DecimalSeparator != "." && profile.DecimalSeparator != "," {
+		return errors.New(`decimal_separator must be "." or ","`)
+	}
+
+	if profile.SkipRows < 0 {
+		return errors.New("skip_rows cannot be negative")
+	}
+
+	if !profile.AmountColumn.Valid && !profile.DebitColumn.Valid && !profile.CreditColumn.Valid {
+		return errors.New("either amount_column or debit_column and credit_column must be provided")
+	}
+
+	if profile.AmountColumn.Valid && (profile.DebitColumn.Valid || profile.CreditColumn.Valid) {
+		return errors.New("amount_column cannot be combined with debit_column or credit_column")
+	}
+
+	columns := append([]int32{profile.DateColumn}, profile.DescriptionColumns...)
+
+	for _, column := range []pgtype.Int4{profile.AmountColumn, profile.DebitColumn, profile.CreditColumn} {
+		if column.Valid {
+			columns = append(columns, column.Int32)
+		}
+	}
+
+	for _, column := range columns {
+		if column < 0 {
+			return errors.New("column indexes start at 0 and cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// cell returns the trimmed value of a column, or false when the record is
+// too short to have it.
+func cell(record []string, column int32) (string, bool) {
+	if column < 0 || int(column) >= len(record) {
+		return "", false
+	}
+
+	return strings.TrimSpace(record[column]), true
+}
+
+func blank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseCSV reads a CSV statement using the column mapping of an import
+// profile. Lines that cannot be read are reported in the statement errors
+// rather than stopping the whole file.
+func ParseCSV(r io.Reader, profile db.ImportProfile) (Statement, error) {
+	statement := newStatement()
+
+	delimiter, err := delimiterRune(profile.Delimiter)
+
+	if err != nil {
+		return statement, err
+	}
+
+	layout, err := DateLayout(profile.DateFormat)
+
+	if err != nil {
+		return statement, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	for records := 0; ; records++ {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+
+		if errors.As(err, &parseErr) {
+			statement.Fail(parseErr.StartLine, "invalid CSV: %s", parseErr.Err)
+			continue
+		}
+
+		if err != nil {
+			return statement, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		if records == 0 {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+		}
+
+		if records < int(profile.SkipRows) || blank(record) {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		row, err := csvRow(record, profile, layout)
+
+		if err != nil {
+			statement.Fail(line, "%s", err)
+			continue
+		}
+
+		row.Line = line
+		statement.add(row)
+	}
+
+	return statement, nil
+}
+
+func csvRow(record []string, profile db.ImportProfile, layout string) (Row, error) {
+	var row Row
+
+	value, ok := cell(record, profile.DateColumn)
+
+	if !ok {
+		return row, fmt.Errorf("date column %d is missing", profile.DateColumn)
+	}
+
+	date, err := time.Parse(layout, value)
+
+	if err != nil {
+		return row, fmt.Errorf("invalid date %q, expected %s", value, profile.DateFormat)
+	}
+
+	row.Date = pgtype.Date{Time: date, Valid: true}
+
+	if profile.AmountColumn.Valid {
+		value, ok := cell(record, profile.AmountColumn.Int32)
+
+		if !ok {
+			return row, fmt.Errorf("amount column %d is missing", profile.AmountColumn.Int32)
+		}
+
+		row.Amount, err = ParseAmount(value, profile.DecimalSeparator)
+
+		if err != nil {
+			return row, fmt.Errorf("invalid amount %q", value)
+		}
+
+		if profile.NegateAmounts {
+			row.Amount = -row.Amount
+		}
+	} else {
+		found := false
+
+		// Banks differ on whether debits carry a minus sign, so only the
+		// column an amount is in decides its direction.
+		for _, column := range []struct {
+			index pgtype.Int4
+			sign  money.Amount
+		}{{profile.DebitColumn, -1}, {profile.CreditColumn, 1}} {
+			if !column.index.Valid {
+				continue
+			}
+
+			value, _ := cell(record, column.index.Int32)
+
+			if value == "" {
+				continue
+			}
+
+			amount, err := ParseAmount(value, profile.DecimalSeparator)
+
+			if err != nil {
+				return row, fmt.Errorf("invalid amount %q", value)
+			}
+
+			row.Amount += column.sign * amount.Abs()
+			found = true
+		}
+
+		if !found {
+			return row, errors.New("neither the debit nor the credit column holds an amount")
+		}
+	}
+
+	var description []string
+
+	for _, column := range profile.DescriptionColumns {
+		if value, _ := cell(record, column); value != "" {
+			description = append(description, value)
+		}
+	}
+
+	row.Description = strings.Join(description, " ")
+
+	return row, nil
+}
@@ -0,0 +1,215 @@
+package statements
+
+import (
+	db "cashpal/database/generated"
+	"cashpal/money"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func column(index int32) pgtype.Int4 {
+	return pgtype.Int4{Int32: index, Valid: true}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"DD.MM.YYYY", "02.01.2006", false},
+		{"YYYY-MM-DD", "2006-01-02", false},
+		{"M/D/YY", "1/2/06", false},
+		{"DD/MM/YY", "02/01/06", false},
+		{"DD.MM", "", true},
+		{"DD.MM.YYYY hh", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := DateLayout(test.format)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("DateLayout(%q) err = %v, want error %v", test.format, err, test.wantErr)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("DateLayout(%q) = %q, want %q", test.format, got, test.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value            string
+		decimalSeparator string
+		want             string
+		wantErr          bool
+	}{
+		{"12.50", ".", "12.50", false},
+		{"1,234.56", ".", "1234.56", false},
+		{"1.234,56", ",", "1234.56", false},
+		{"1 234,56", ",", "1234.56", false},
+		{"1'000.00", ".", "1000", false},
+		{"-3", ".", "-3", false},
+		{"12.50-", ".", "-12.50", false},
+		{"(12.50)", ".", "-12.50", false},
+		{" 12,00 ", ",", "12", false},
+		{"abc", ".", "", true},
+		{"", ".", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseAmount(test.value, test.decimalSeparator)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseAmount(%q, %q) err = %v, want error %v", test.value, test.decimalSeparator, err, test.wantErr)
+			continue
+		}
+
+		if !test.wantErr && got != money.MustParse(test.want) {
+			t.Errorf("ParseAmount(%q, %q) = %s, want %s", test.value, test.decimalSeparator, got, test.want)
+		}
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	valid := db.CreateImportProfileParams{
+		Name:               "Bank",
+		Delimiter:          ";",
+		DateColumn:         0,
+		DateFormat:         "DD.MM.YYYY",
+		DescriptionColumns: []int32{1},
+		AmountColumn:       column(2),
+		DecimalSeparator:   ",",
+	}
+
+	tests := []struct {
+		name    string
+		change  func(*db.CreateImportProfileParams)
+		wantErr bool
+	}{
+		{"valid", func(*db.CreateImportProfileParams) {}, false},
+		{"debit and credit columns", func(p *db.CreateImportProfileParams) {
+			p.AmountColumn = pgtype.Int4{}
+			p.DebitColumn = column(2)
+			p.CreditColumn = column(3)
+		}, false},
+		{"tab delimiter", func(p *db.CreateImportProfileParams) { p.Delimiter = "\t" }, false},
+		{"missing name", func(p *db.CreateImportProfileParams) { p.Name = " " }, true},
+		{"empty delimiter", func(p *db.CreateImportProfileParams) { p.Delimiter = "" }, true},
+		{"quote delimiter", func(p *db.CreateImportProfileParams) { p.Delimiter = `"` }, true},
+		{"long delimiter", func(p *db.CreateImportProfileParams) { p.Delimiter = ";;" }, true},
+		{"invalid date format", func(p *db.CreateImportProfileParams) { p.DateFormat = "MM.YYYY" }, true},
+		{"invalid decimal separator", func(p *db.CreateImportProfileParams) { p.DecimalSeparator = "'" }, true},
+		{"negative skip rows", func(p *db.CreateImportProfileParams) { p.SkipRows = -1 }, true},
+		{"no amount column", func(p *db.CreateImportProfileParams) { p.AmountColumn = pgtype.Int4{} }, true},
+		{"amount and debit columns", func(p *db.CreateImportProfileParams) { p.DebitColumn = column(3) }, true},
+		{"negative column", func(p *db.CreateImportProfileParams) { p.DescriptionColumns = []int32{-1} }, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile := valid
+			test.change(&profile)
+
+			if err := ValidateProfile(profile); (err != nil) != test.wantErr {
+				t.Errorf("err = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile db.ImportProfile
+		input   string
+		rows    []Row
+		errors  []int
+		skipped int
+	}{
+		{
+			name: "amount column with a header",
+			profile: db.ImportProfile{
+				Delimiter:          ";",
+				SkipRows:           1,
+				DateColumn:         0,
+				DateFormat:         "DD.MM.YYYY",
+				DescriptionColumns: []int32{1, 3},
+				AmountColumn:       column(2),
+				DecimalSeparator:   ",",
+			},
+			input: "\ufeffDatum;Text;Betrag;Referenz\n" +
+				"31.01.2026;Rent;-1.200,00;January\n" +
+				"\n" +
+				"01.02.2026;\"Salary; February\";2.500,50;\n",
+			rows: []Row{
+				{Line: 2, Date: date(2026, 1, 31), Amount: money.MustParse("-1200"), Description: "Rent January"},
+				{Line: 4, Date: date(2026, 2, 1), Amount: money.MustParse("2500.50"), Description: "Salary; February"},
+			},
+			errors: []int{},
+		},
+		{
+			name: "debit and credit columns",
+			profile: db.ImportProfile{
+				Delimiter:          ",",
+				SkipRows:           1,
+				DateColumn:         0,
+				DateFormat:         "MM/DD/YYYY",
+				DescriptionColumns: []int32{1},
+				DebitColumn:        column(2),
+				CreditColumn:       column(3),
+				DecimalSeparator:   ".",
+			},
+			input: "Date,Description,Debit,Credit\n" +
+				"01/05/2026,Coffee,3.50,\n" +
+				"01/06/2026,Refund,,-10.00\n" +
+				"01/07/2026,Nothing,,\n" +
+				"01/08/2026,Both,-1.00,4.00\n",
+			rows: []Row{
+				{Line: 2, Date: date(2026, 1, 5), Amount: money.MustParse("-3.50"), Description: "Coffee"},
+				{Line: 3, Date: date(2026, 1, 6), Amount: money.MustParse("10"), Description: "Refund"},
+				{Line: 5, Date: date(2026, 1, 8), Amount: money.MustParse("3"), Description: "Both"},
+			},
+			errors: []int{4},
+		},
+		{
+			name: "negated amounts and bad lines",
+			profile: db.ImportProfile{
+				Delimiter:          "\t",
+				DateColumn:         0,
+				DateFormat:         "YYYY-MM-DD",
+				DescriptionColumns: []int32{1},
+				AmountColumn:       column(2),
+				DecimalSeparator:   ".",
+				NegateAmounts:      true,
+			},
+			input: "2026-01-05\tCard\t12.00\n" +
+				"05.01.2026\tCard\t1.00\n" +
+				"2026-01-06\tCard\tone\n" +
+				"2026-01-07\tShort\n" +
+				"2026-01-08\tZero\t0.00\n",
+			rows: []Row{
+				{Line: 1, Date: date(2026, 1, 5), Amount: money.MustParse("-12"), Description: "Card"},
+			},
+			errors:  []int{2, 3, 4},
+			skipped: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := ParseCSV(strings.NewReader(test.input), test.profile)
+
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			checkStatement(t, statement, test.rows, test.errors, test.skipped)
+		})
+	}
+}
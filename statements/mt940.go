@@ -0,0 +1,506 @@
+package statements
+
+import (
+	"cashpal/money"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// mt940Field is a tagged field of an MT940 message, such as :61:, with the
+// lines that continue it.
+type mt940Field struct {
+	tag   string
+	value string
+	line  int
+}
+
+var mt940Tag = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):`)
+
+// mt940Entry matches a :61: statement line: value date, optional booking
+// date, debit or credit mark, optional funds code, amount, transaction type,
+// customer reference and, after a double slash, the bank reference.
+var mt940Entry = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(RC|RD|C|D)([A-Z])?([0-9]+,[0-9]*)([NFS][A-Z0-9]{3})(.*)$`)
+
+// mt940Balance matches an opening or closing balance: debit or credit mark,
+// date, currency and amount.
+var mt940Balance = regexp.MustCompile(`^(C|D)([0-9]{6})([A-Z]{3})([0-9]+,[0-9]*)`)
+
+// mt940SEPATag matches the keywords German banks put in front of the parts
+// of a SEPA purpose, such as EREF+ and SVWZ+.
+var mt940SEPATag = regexp.MustCompile(`(EREF|KREF|MREF|CRED|DEBT|SVWZ|ABWA|ABWE|IBAN|BIC)\+`)
+
+// mt940Subfield matches the purpose subfields of structured :86: details.
+var mt940Subfield = regexp.MustCompile(`\?2[0-9]`)
+
+// mt940Statement holds the fields of one statement, from its :20: to its
+// closing balance.
+type mt940Statement struct {
+	reference string
+	account   string
+	number    string
+	currency  string
+	balance   *mt940Field
+	entries   []mt940Field
+	details   map[int]mt940Field
+	openedOn  string
+}
+
+// mt940Fields splits an MT940 file into its fields. The block headers of a
+// SWIFT message, such as {1:...}{2:...}{4:, and the trailing -} are dropped.
+func mt940Fields(data string) []mt940Field {
+	var fields []mt940Field
+
+	for number, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || trimmed == "-" || trimmed == "-}" || strings.HasPrefix(trimmed, "{") {
+			continue
+		}
+
+		if tag := mt940Tag.FindStringSubmatch(line); tag != nil {
+			fields = append(fields, mt940Field{tag: tag[1], value: line[len(tag[0]):], line: number + 1})
+			continue
+		}
+
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+
+	return fields
+}
+
+func parseMT940Date(value string) (time.Time, error) {
+	date, err := time.Parse("060102", value)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return date, nil
+}
+
+func parseMT940Amount(value string) (money.Amount, error) {
+	amount, err := ParseAmount(value, ",")
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	return amount, nil
+}
+
+// ParseMT940 reads SWIFT MT940 customer statements. A file may hold several
+// statements, one after another, for one or more accounts. The final closing
+// balance of every statement is reported in the statement balances.
+//
+// MT940 has no unique id for an entry, so the external id of a row is made of
+// the account, the :20: reference, the statement number from :28C:, the
+// opening balance date and the position of the entry in the statement. Banks
+// often repeat the same reference or number for every statement, so none of
+// them is enough on its own. The structured
+// :86: details German banks write, with ?20 to ?29 holding the purpose and
+// ?32 the counterparty, are read into the description and the row metadata,
+// as are the /NAME/ and /REMI/ codes Dutch banks use.
+func ParseMT940(r io.Reader) (Statement, error) {
+	statement := newStatement()
+
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return statement, err
+	}
+
+	var current *mt940Statement
+	found := false
+
+	finish := func() {
+		if current != nil {
+			readMT940Statement(&statement, current)
+		}
+
+		current = nil
+	}
+
+	for _, field := range mt940Fields(latin1(data)) {
+		if field.tag != "20" && current == nil {
+			// A statement without a :20: still starts at its account.
+			if field.tag != "25" {
+				continue
+			}
+
+			current = &mt940Statement{details: map[int]mt940Field{}}
+		}
+
+		switch field.tag {
+		case "20":
+			finish()
+			current = &mt940Statement{reference: strings.TrimSpace(field.value), details: map[int]mt940Field{}}
+			found = true
+		case "25":
+			current.account = strings.TrimSpace(field.value)
+			found = true
+		case "28", "28C":
+			current.number = strings.TrimSpace(field.value)
+		case "60F", "60M":
+			if balance := mt940Balance.FindStringSubmatch(strings.TrimSpace(field.value)); balance != nil {
+				current.currency = balance[3]
+				current.openedOn = balance[2]
+			}
+		case "61":
+			current.entries = append(current.entries, field)
+		case "86":
+			// Details follow the entry they describe; details after the
+			// closing balance describe the statement and are ignored.
+			if len(current.entries) > 0 && current.balance == nil {
+				current.details[len(current.entries)-1] = field
+			}
+		case "62F", "62M":
+			balance := field
+			current.balance = &balance
+
+			if match := mt940Balance.FindStringSubmatch(strings.TrimSpace(field.value)); match != nil && current.currency == "" {
+				current.currency = match[3]
+			}
+
+			if field.tag == "62F" {
+				finish()
+			}
+		}
+	}
+
+	finish()
+
+	if !found {
+		return statement, errors.New("the MT940 file holds no statement")
+	}
+
+	return statement, nil
+}
+
+func readMT940Statement(statement *Statement, current *mt940Statement) {
+	for i, entry := range current.entries {
+		row, err := mt940Row(entry, current.details[i], current, i)
+
+		if err != nil {
+			statement.Fail(entry.line, "%s", err)
+			continue
+		}
+
+		statement.add(row)
+	}
+
+	if current.balance == nil {
+		return
+	}
+
+	value := strings.TrimSpace(current.balance.value)
+	balance := mt940Balance.FindStringSubmatch(value)
+
+	if balance == nil {
+		statement.Fail(current.balance.line, "invalid closing balance %q", value)
+		return
+	}
+
+	date, err := parseMT940Date(balance[2])
+
+	if err != nil {
+		statement.Fail(current.balance.line, "closing balance has an %s", err)
+		return
+	}
+
+	amount, err := parseMT940Amount(balance[4])
+
+	if err != nil {
+		statement.Fail(current.balance.line, "closing balance has an %s", err)
+		return
+	}
+
+	if balance[1] == "D" {
+		amount = -amount
+	}
+
+	statement.Balances = append(statement.Balances, Balance{
+		Account:  current.account,
+		Currency: balance[3],
+		Date:     pgtype.Date{Time: date, Valid: true},
+		Amount:   amount,
+	})
+}
+
+// mt940BookingDate returns the booking date of an entry, which is written
+// without a year. It is taken from the value date, across a new year when
+// the two fall in December and January.
+func mt940BookingDate(valueDate time.Time, monthDay string) (time.Time, error) {
+	month, _ := strconv.Atoi(monthDay[:2])
+	day, _ := strconv.Atoi(monthDay[2:])
+	year := valueDate.Year()
+
+	switch {
+	case month == 12 && valueDate.Month() == time.January:
+		year--
+	case month == 1 && valueDate.Month() == time.December:
+		year++
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+
+	if int(date.Month()) != month || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid booking date %q", monthDay)
+	}
+
+	return date, nil
+}
+
+func mt940Row(entry mt940Field, details mt940Field, current *mt940Statement, index int) (Row, error) {
+	row := Row{Line: entry.line, Currency: current.currency}
+
+	first, supplementary, _ := strings.Cut(entry.value, "\n")
+	match := mt940Entry.FindStringSubmatch(strings.TrimSpace(first))
+
+	if match == nil {
+		return row, fmt.Errorf("invalid statement line %q", strings.TrimSpace(first))
+	}
+
+	valueDate, err := parseMT940Date(match[1])
+
+	if err != nil {
+		return row, err
+	}
+
+	date := valueDate
+
+	if match[2] != "" {
+		date, err = mt940BookingDate(valueDate, match[2])
+
+		if err != nil {
+			return row, err
+		}
+	}
+
+	row.Date = pgtype.Date{Time: date, Valid: true}
+
+	row.Amount, err = parseMT940Amount(match[5])
+
+	if err != nil {
+		return row, err
+	}
+
+	// A reversed credit takes money out of the account and a reversed debit
+	// brings it back.
+	if match[3] == "D" || match[3] == "RC" {
+		row.Amount = -row.Amount
+	}
+
+	metadata := map[string]string{}
+
+	if current.number != "" {
+		metadata[MetadataStatement] = current.number
+	}
+
+	if strings.HasPrefix(match[3], "R") {
+		metadata[MetadataReversal] = "true"
+	}
+
+	customerReference, bankReference, _ := strings.Cut(match[7], "//")
+
+	if customerReference = strings.TrimSpace(customerReference); customerReference != "" && customerReference != "NONREF" {
+		metadata[MetadataCustomerReference] = customerReference
+	}
+
+	if bankReference = strings.TrimSpace(bankReference); bankReference != "" {
+		metadata[MetadataBankReference] = bankReference
+	}
+
+	var id []string
+
+	for _, part := range []string{current.reference, current.number, current.openedOn} {
+		if part != "" {
+			id = append(id, part)
+		}
+	}
+
+	row.ExternalID = strings.Join(append(id, strconv.Itoa(index+1)), "/")
+
+	if current.account != "" {
+		row.ExternalID = current.account + ":" + row.ExternalID
+	}
+
+	description := readMT940Details(strings.ReplaceAll(details.value, "\n", ""), metadata)
+
+	if description == "" {
+		description = strings.TrimSpace(supplementary)
+	}
+
+	if name := metadata[MetadataCounterpartyName]; name != "" && !strings.Contains(description, name) {
+		description = strings.TrimSpace(name + " " + description)
+	}
+
+	row.Description = strings.Join(strings.Fields(description), " ")
+
+	if len(metadata) > 0 {
+		row.Metadata = metadata
+	}
+
+	return row, nil
+}
+
+// readMT940Details reads the :86: details of an entry into metadata and
+// returns the purpose of the payment.
+func readMT940Details(value string, metadata map[string]string) string {
+	switch {
+	case mt940Subfield.MatchString(value):
+		return readMT940Subfields(value, metadata)
+	case strings.HasPrefix(value, "/"):
+		return readMT940Codes(value, metadata)
+	default:
+		return value
+	}
+}
+
+// readMT940Subfields reads details such as
+// 166?00SEPA-UEBERWEISUNG?20EREF+E2E-1?21SVWZ+Invoice 42?32Jane Doe.
+// Purpose lines are cut at a fixed width and are joined without spaces.
+func readMT940Subfields(value string, metadata map[string]string) string {
+	var purpose, name strings.Builder
+	postingText := ""
+
+	for _, part := range strings.Split(value, "?")[1:] {
+		if len(part) < 2 {
+			continue
+		}
+
+		code, err := strconv.Atoi(part[:2])
+
+		if err != nil {
+			continue
+		}
+
+		text := part[2:]
+
+		switch {
+		case code == 0:
+			postingText = strings.TrimSpace(text)
+		case code >= 20 && code <= 29, code >= 60 && code <= 63:
+			purpose.WriteString(text)
+		case code == 31:
+			if account := strings.TrimSpace(text); account != "" {
+				metadata[MetadataCounterpartyAccount] = account
+			}
+		case code == 32, code == 33:
+			name.WriteString(text)
+		}
+	}
+
+	if counterparty := strings.TrimSpace(name.String()); counterparty != "" {
+		metadata[MetadataCounterpartyName] = counterparty
+	}
+
+	text := purpose.String()
+	locations := mt940SEPATag.FindAllStringSubmatchIndex(text, -1)
+
+	if len(locations) == 0 {
+		return firstOf(text, postingText)
+	}
+
+	parts := map[string]string{}
+
+	for i, location := range locations {
+		end := len(text)
+
+		if i+1 < len(locations) {
+			end = locations[i+1][0]
+		}
+
+		parts[text[location[2]:location[3]]] = strings.TrimSpace(text[location[1]:end])
+	}
+
+	if endToEndID := parts["EREF"]; endToEndID != "" && endToEndID != "NOTPROVIDED" {
+		metadata[MetadataEndToEndID] = endToEndID
+	}
+
+	if account := parts["IBAN"]; account != "" && metadata[MetadataCounterpartyAccount] == "" {
+		metadata[MetadataCounterpartyAccount] = account
+	}
+
+	return firstOf(parts["SVWZ"], strings.TrimSpace(text[:locations[0][0]]), postingText)
+}
+
+// mt940Codes are the codes of /CODE/value details that Cashpal reads.
+var mt940Codes = []string{"EREF", "NAME", "REMI", "IBAN", "CNTP", "TRTP", "MARF", "CSID", "BENM", "ORDP", "ID", "ADDR", "BIC", "PREF", "RTRN", "ATOS", "ISDT"}
+
+// readMT940Codes reads details such as
+// /TRTP/SEPA OVERBOEKING/IBAN/NL12ABCD0123456789/NAME/J DOE/REMI/Invoice 42.
+func readMT940Codes(value string, metadata map[string]string) string {
+	parts := map[string]string{}
+	code := ""
+	rest := value
+
+	for rest != "" {
+		found := false
+
+		for _, candidate := range mt940Codes {
+			if next, ok := strings.CutPrefix(rest, "/"+candidate+"/"); ok {
+				code = candidate
+				rest = next
+				found = true
+				break
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if code != "" {
+			parts[code] += rest[:1]
+		}
+
+		rest = rest[1:]
+	}
+
+	for code, part := range parts {
+		parts[code] = strings.Trim(part, "/ ")
+	}
+
+	if endToEndID := parts["EREF"]; endToEndID != "" && endToEndID != "NOTPROVIDED" {
+		metadata[MetadataEndToEndID] = endToEndID
+	}
+
+	if name := parts["NAME"]; name != "" {
+		metadata[MetadataCounterpartyName] = name
+	}
+
+	if account := parts["IBAN"]; account != "" {
+		metadata[MetadataCounterpartyAccount] = account
+	}
+
+	// Some banks write the counterparty as /CNTP/account/bic/name/city/.
+	if counterparty := strings.Split(parts["CNTP"], "/"); len(counterparty) >= 3 {
+		if metadata[MetadataCounterpartyAccount] == "" && strings.TrimSpace(counterparty[0]) != "" {
+			metadata[MetadataCounterpartyAccount] = strings.TrimSpace(counterparty[0])
+		}
+
+		if metadata[MetadataCounterpartyName] == "" && strings.TrimSpace(counterparty[2]) != "" {
+			metadata[MetadataCounterpartyName] = strings.TrimSpace(counterparty[2])
+		}
+	}
+
+	// Unstructured remittance information may be written as USTD//text.
+	remittance := strings.TrimPrefix(parts["REMI"], "USTD//")
+
+	return firstOf(remittance, parts["TRTP"])
+}
@@ -0,0 +1,209 @@
+package statements
+
+import (
+	"cashpal/money"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mt940Statements holds a German statement with structured :86: details and
+// a year-end booking date, followed by a Dutch statement without a :20:.
+const mt940Statements = `{1:F01BANKDEFFAXXX0000000000}{2:O9400000000000BANKDEFFXXXX00000000000000000000N}{4:
+:20:STARTUMS
+:25:10020030/1234567
+:28C:00001/001
+:60F:C260101EUR1000,00
+:61:2601020102D50,00NTRFNONREF//BANK1
+:86:166?00SEPA-UEBERWEISUNG?20EREF+E2E-1?21SVWZ+Invoice 42?31DE99
+123?32Jane Doe
+:61:2601030103RC20,00NCHGREF2
+:86:/TRTP/SEPA OVERBOEKING/IBAN/NL12ABCD0123456789/NAME/J DOE/REMI/USTD//Refund 7/
+:61:2612310101C5,00NMSC
+Interest
+:61:2601XXD1,00NMSC
+:62F:C260103EUR935,00
+:86:Statement details are not read
+-}
+:25:NL12ABCD0123456789
+:28C:2
+:60F:D260101EUR10,00
+:61:260105C1,50NTRFNONREF
+:86:Plain text details
+:62F:D260105EUR8,50
+`
+
+func TestParseMT940(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		rows     []Row
+		errors   []int
+		balances []Balance
+		wantErr  bool
+	}{
+		{
+			name:  "several statements",
+			input: mt940Statements,
+			rows: []Row{
+				{
+					Line:        6,
+					Date:        date(2026, 1, 2),
+					Amount:      money.MustParse("-50"),
+					Description: "Jane Doe Invoice 42",
+					Currency:    "EUR",
+					ExternalID:  "10020030/1234567:STARTUMS/00001/001/260101/1",
+					Metadata: map[string]string{
+						MetadataStatement:           "00001/001",
+						MetadataBankReference:       "BANK1",
+						MetadataEndToEndID:          "E2E-1",
+						MetadataCounterpartyAccount: "DE99123",
+						MetadataCounterpartyName:    "Jane Doe",
+					},
+				},
+				{
+					Line:        9,
+					Date:        date(2026, 1, 3),
+					Amount:      money.MustParse("-20"),
+					Description: "J DOE Refund 7",
+					Currency:    "EUR",
+					ExternalID:  "10020030/1234567:STARTUMS/00001/001/260101/2",
+					Metadata: map[string]string{
+						MetadataStatement:           "00001/001",
+						MetadataReversal:            "true",
+						MetadataCustomerReference:   "REF2",
+						MetadataCounterpartyAccount: "NL12ABCD0123456789",
+						MetadataCounterpartyName:    "J DOE",
+					},
+				},
+				{
+					Line:        11,
+					Date:        date(2027, 1, 1),
+					Amount:      money.MustParse("5"),
+					Description: "Interest",
+					Currency:    "EUR",
+					ExternalID:  "10020030/1234567:STARTUMS/00001/001/260101/3",
+					Metadata:    map[string]string{MetadataStatement: "00001/001"},
+				},
+				{
+					Line:        20,
+					Date:        date(2026, 1, 5),
+					Amount:      money.MustParse("1.50"),
+					Description: "Plain text details",
+					Currency:    "EUR",
+					ExternalID:  "NL12ABCD0123456789:2/260101/1",
+					Metadata:    map[string]string{MetadataStatement: "2"},
+				},
+			},
+			errors: []int{13},
+			balances: []Balance{
+				{Account: "10020030/1234567", Currency: "EUR", Date: date(2026, 1, 3), Amount: money.MustParse("935")},
+				{Account: "NL12ABCD0123456789", Currency: "EUR", Date: date(2026, 1, 5), Amount: money.MustParse("-8.50")},
+			},
+		},
+		{
+			name:    "no statement",
+			input:   "nothing to read\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := ParseMT940(strings.NewReader(test.input))
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			checkStatement(t, statement, test.rows, test.errors, 0)
+
+			if !reflect.DeepEqual(statement.Balances, test.balances) {
+				t.Errorf("balances = %+v, want %+v", statement.Balances, test.balances)
+			}
+		})
+	}
+}
+
+func TestReadMT940Details(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		want     string
+		metadata map[string]string
+	}{
+		{
+			name:  "subfields with SEPA keywords",
+			value: "166?00SEPA-UEBERWEISUNG?20EREF+E2E-1?21SVWZ+Invoice 4?222?23IBAN+DE02 ?32Jane ?33Doe",
+			want:  "Invoice 42",
+			metadata: map[string]string{
+				MetadataEndToEndID:          "E2E-1",
+				MetadataCounterpartyAccount: "DE02",
+				MetadataCounterpartyName:    "Jane Doe",
+			},
+		},
+		{
+			name:  "subfields without SEPA keywords",
+			value: "835?00ENTGELT?20Account fee?31DE03",
+			want:  "Account fee",
+			metadata: map[string]string{
+				MetadataCounterpartyAccount: "DE03",
+			},
+		},
+		{
+			name:     "subfields without a purpose",
+			value:    "805?00ABSCHLUSS?20",
+			want:     "ABSCHLUSS",
+			metadata: map[string]string{},
+		},
+		{
+			name:     "end-to-end reference not provided",
+			value:    "166?20EREF+NOTPROVIDED?21SVWZ+Gift",
+			want:     "Gift",
+			metadata: map[string]string{},
+		},
+		{
+			name:  "codes",
+			value: "/EREF/E2E-2/TRTP/SEPA OVERBOEKING/IBAN/NL12ABCD0123456789/NAME/J DOE/REMI/Invoice 7",
+			want:  "Invoice 7",
+			metadata: map[string]string{
+				MetadataEndToEndID:          "E2E-2",
+				MetadataCounterpartyAccount: "NL12ABCD0123456789",
+				MetadataCounterpartyName:    "J DOE",
+			},
+		},
+		{
+			name:  "counterparty code",
+			value: "/TRTP/iDEAL/CNTP/NL34EFGH0123456789/EFGHNL2A/Webshop BV/Amsterdam/",
+			want:  "iDEAL",
+			metadata: map[string]string{
+				MetadataCounterpartyAccount: "NL34EFGH0123456789",
+				MetadataCounterpartyName:    "Webshop BV",
+			},
+		},
+		{
+			name:     "plain text",
+			value:    "Card payment 1234",
+			want:     "Card payment 1234",
+			metadata: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata := map[string]string{}
+
+			if got := readMT940Details(test.value, metadata); got != test.want {
+				t.Errorf("readMT940Details = %q, want %q", got, test.want)
+			}
+
+			if !reflect.DeepEqual(metadata, test.metadata) {
+				t.Errorf("metadata = %v, want %v", metadata, test.metadata)
+			}
+		})
+	}
+}
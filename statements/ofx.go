@@ -0,0 +1,306 @@
+package statements
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ofxNode is an OFX element. Elements that hold a value have no children.
+type ofxNode struct {
+	name     string
+	value    string
+	line     int
+	children []*ofxNode
+}
+
+// child returns the first direct child with the given name.
+func (n *ofxNode) child(name string) *ofxNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// text follows a path of child names and returns the value at its end, or
+// an empty string when the path does not exist.
+func (n *ofxNode) text(path ...string) string {
+	node := n
+
+	for _, name := range path {
+		if node = node.child(name); node == nil {
+			return ""
+		}
+	}
+
+	return node.value
+}
+
+// find returns every element with the given name below n, in document order.
+func (n *ofxNode) find(name string) []*ofxNode {
+	var found []*ofxNode
+
+	for _, child := range n.children {
+		if child.name == name {
+			found = append(found, child)
+			continue
+		}
+
+		found = append(found, child.find(name)...)
+	}
+
+	return found
+}
+
+// parseOFXTree reads both OFX 1.x, which is SGML and leaves the closing tags
+// of values out, and OFX 2.x, which is XML. The header in front of the OFX
+// element is skipped. An element followed by text holds a value; any other
+// element is an aggregate that ends at its closing tag.
+func parseOFXTree(data string) (*ofxNode, error) {
+	start := strings.Index(data, "<OFX>")
+
+	if start < 0 {
+		start = strings.Index(data, "<ofx>")
+	}
+
+	if start < 0 {
+		return nil, errors.New("invalid OFX: the file has no OFX element")
+	}
+
+	line := 1 + strings.Count(data[:start], "\n")
+	data = data[start:]
+
+	root := &ofxNode{}
+	stack := []*ofxNode{root}
+
+	for {
+		open := strings.IndexByte(data, '<')
+
+		if open < 0 {
+			break
+		}
+
+		line += strings.Count(data[:open], "\n")
+		data = data[open:]
+
+		if strings.HasPrefix(data, "<!--") {
+			end := strings.Index(data, "-->")
+
+			if end < 0 {
+				break
+			}
+
+			line += strings.Count(data[:end], "\n")
+			data = data[end+3:]
+			continue
+		}
+
+		end := strings.IndexByte(data, '>')
+
+		if end < 0 {
+			return nil, fmt.Errorf("invalid OFX: line %d: unterminated tag", line)
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(data[1:end]))
+		data = data[end+1:]
+
+		if tag == "" || tag[0] == '?' || tag[0] == '!' {
+			continue
+		}
+
+		if name, closing := strings.CutPrefix(tag, "/"); closing {
+			// Closing tags of values and stray closing tags match nothing
+			// on the stack and are ignored.
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+
+			continue
+		}
+
+		node := &ofxNode{name: tag, line: line}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+
+		next := strings.IndexByte(data, '<')
+
+		if next < 0 {
+			next = len(data)
+		}
+
+		if value := strings.TrimSpace(data[:next]); value != "" {
+			node.value = html.UnescapeString(value)
+			continue
+		}
+
+		stack = append(stack, node)
+	}
+
+	return root.child("OFX"), nil
+}
+
+// latin1 decodes files that are not UTF-8. OFX 1.x files usually declare
+// the Windows-1252 or ISO-8859-1 character set, and both agree with Latin-1
+// on the letters found in descriptions.
+func latin1(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+
+	runes := make([]rune, len(data))
+
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
+
+// parseOFXDate reads the date part of an OFX date time such as
+// 20260131120000.000[-5:EST].
+func parseOFXDate(value string) (pgtype.Date, error) {
+	if len(value) < 8 {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+
+	if err != nil {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return pgtype.Date{Time: date, Valid: true}, nil
+}
+
+// ParseOFX reads the bank and credit card statements of an OFX or QFX file.
+// The external id of a row is the bank's FITID, prefixed with the account id
+// when the statement has one since FITIDs are only unique per bank account.
+// The ledger balance of every statement is reported in the statement
+// balances.
+func ParseOFX(r io.Reader) (Statement, error) {
+	statement := newStatement()
+
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return statement, err
+	}
+
+	root, err := parseOFXTree(latin1(data))
+
+	if err != nil {
+		return statement, err
+	}
+
+	if root == nil {
+		return statement, errors.New("invalid OFX: the file has no OFX element")
+	}
+
+	responses := append(root.find("STMTRS"), root.find("CCSTMTRS")...)
+
+	if len(responses) == 0 {
+		return statement, errors.New("the OFX file holds no bank or credit card statement")
+	}
+
+	for _, response := range responses {
+		currency := strings.ToUpper(response.text("CURDEF"))
+		account := response.text("BANKACCTFROM", "ACCTID")
+
+		if account == "" {
+			account = response.text("CCACCTFROM", "ACCTID")
+		}
+
+		for _, transaction := range response.find("STMTTRN") {
+			row, err := ofxRow(transaction, currency, account)
+
+			if err != nil {
+				statement.Fail(transaction.line, "%s", err)
+				continue
+			}
+
+			statement.add(row)
+		}
+
+		if ledgerBalance := response.child("LEDGERBAL"); ledgerBalance != nil {
+			balance := Balance{Account: account, Currency: currency}
+
+			balance.Date, err = parseOFXDate(ledgerBalance.text("DTASOF"))
+
+			if err != nil {
+				statement.Fail(ledgerBalance.line, "ledger balance has an %s", err)
+				continue
+			}
+
+			value := ledgerBalance.text("BALAMT")
+			balance.Amount, err = ParseAmount(value, guessDecimalSeparator(value))
+
+			if err != nil {
+				statement.Fail(ledgerBalance.line, "ledger balance has an invalid amount %q", value)
+				continue
+			}
+
+			statement.Balances = append(statement.Balances, balance)
+		}
+	}
+
+	return statement, nil
+}
+
+func ofxRow(transaction *ofxNode, currency string, account string) (Row, error) {
+	row := Row{Line: transaction.line, Currency: currency}
+
+	var err error
+
+	row.Date, err = parseOFXDate(transaction.text("DTPOSTED"))
+
+	if err != nil {
+		return row, err
+	}
+
+	value := transaction.text("TRNAMT")
+	row.Amount, err = ParseAmount(value, guessDecimalSeparator(value))
+
+	if err != nil {
+		return row, fmt.Errorf("invalid amount %q", value)
+	}
+
+	// A CURRENCY aggregate means the amount is in another currency than
+	// the statement.
+	if symbol := transaction.text("CURRENCY", "CURSYM"); symbol != "" {
+		row.Currency = strings.ToUpper(symbol)
+	}
+
+	if fitID := transaction.text("FITID"); fitID != "" {
+		row.ExternalID = fitID
+
+		if account != "" {
+			row.ExternalID = account + ":" + fitID
+		}
+	}
+
+	name := transaction.text("NAME")
+
+	if name == "" {
+		name = transaction.text("PAYEE", "NAME")
+	}
+
+	row.Description = name
+
+	if memo := transaction.text("MEMO"); memo != "" && memo != name {
+		row.Description = strings.TrimSpace(name + " " + memo)
+	}
+
+	return row, nil
+}
@@ -0,0 +1,181 @@
+package statements
+
+import (
+	"cashpal/money"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS><CODE>0<SEVERITY>INFO</STATUS>
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM>
+<BANKID>12345678
+<ACCTID>DE001
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20260131120000.000[-5:EST]
+<TRNAMT>-42.50
+<FITID>A1
+<NAME>Grocer &amp; Co
+<MEMO>Weekly shop
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20260201
+<TRNAMT>1.000,00
+<FITID>A2
+<NAME>Salary
+<MEMO>Salary
+<CURRENCY><CURRATE>1.1<CURSYM>usd</CURRENCY>
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>2026
+<TRNAMT>1.00
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>20260202
+<TRNAMT>0.00
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>957.50
+<DTASOF>20260202
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>USD</CURDEF>
+        <CCACCTFROM>
+          <ACCTID>4111</ACCTID>
+        </CCACCTFROM>
+        <BANKTRANLIST>
+          <!-- <STMTTRN> in a comment is not read -->
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20260110</DTPOSTED>
+            <TRNAMT>-19.99</TRNAMT>
+            <FITID>X9</FITID>
+            <PAYEE><NAME>Bookshop</NAME></PAYEE>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>CREDIT</TRNTYPE>
+            <DTPOSTED>20260111</DTPOSTED>
+            <TRNAMT>five</TRNAMT>
+          </STMTTRN>
+        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>-19.99</BALAMT>
+          <DTASOF>20260111</DTASOF>
+        </LEDGERBAL>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		rows     []Row
+		errors   []int
+		skipped  int
+		balances []Balance
+		wantErr  bool
+	}{
+		{
+			name:  "sgml",
+			input: ofxSGML,
+			rows: []Row{
+				{Line: 22, Date: date(2026, 1, 31), Amount: money.MustParse("-42.50"), Description: "Grocer & Co Weekly shop", Currency: "EUR", ExternalID: "DE001:A1"},
+				{Line: 30, Date: date(2026, 2, 1), Amount: money.MustParse("1000"), Description: "Salary", Currency: "USD", ExternalID: "DE001:A2"},
+			},
+			errors:  []int{39},
+			skipped: 1,
+			balances: []Balance{
+				{Account: "DE001", Currency: "EUR", Date: date(2026, 2, 2), Amount: money.MustParse("957.50")},
+			},
+		},
+		{
+			name:  "xml",
+			input: ofxXML,
+			rows: []Row{
+				{Line: 13, Date: date(2026, 1, 10), Amount: money.MustParse("-19.99"), Description: "Bookshop", Currency: "USD", ExternalID: "4111:X9"},
+			},
+			errors: []int{20},
+			balances: []Balance{
+				{Account: "4111", Currency: "USD", Date: date(2026, 1, 11), Amount: money.MustParse("-19.99")},
+			},
+		},
+		{
+			name:    "no ofx element",
+			input:   "OFXHEADER:100\n",
+			wantErr: true,
+		},
+		{
+			name:    "no statement",
+			input:   "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := ParseOFX(strings.NewReader(test.input))
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			checkStatement(t, statement, test.rows, test.errors, test.skipped)
+
+			if !reflect.DeepEqual(statement.Balances, test.balances) {
+				t.Errorf("balances = %+v, want %+v", statement.Balances, test.balances)
+			}
+		})
+	}
+}
+
+func TestParseOFXLatin1(t *testing.T) {
+	input := "<OFX><STMTRS><STMTTRN><DTPOSTED>20260105<TRNAMT>-5<NAME>Caf\xe9</STMTTRN></STMTRS></OFX>"
+
+	statement, err := ParseOFX(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(statement.Rows) != 1 || statement.Rows[0].Description != "Café" {
+		t.Errorf("rows = %+v, want one row for Café", statement.Rows)
+	}
+}
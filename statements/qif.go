@@ -0,0 +1,314 @@
+package statements
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	QIFBank  = "Bank"
+	QIFCCard = "CCard"
+	QIFCash  = "Cash"
+)
+
+// qifRecord holds the fields of one QIF transaction until its closing ^.
+type qifRecord struct {
+	line     int
+	date     string
+	amount   string
+	payee    string
+	memo     string
+	category string
+	splits   []qifSplit
+}
+
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// qifSection returns the account type of a !Type header, or an empty string
+// for the sections Cashpal does not read, such as investments and lists.
+func qifSection(header string) string {
+	name, ok := strings.CutPrefix(strings.ToLower(header), "type:")
+
+	if !ok {
+		return ""
+	}
+
+	for _, section := range []string{QIFBank, QIFCCard, QIFCash} {
+		if strings.TrimSpace(name) == strings.ToLower(section) {
+			return section
+		}
+	}
+
+	return ""
+}
+
+// qifCategory reads the category field of a transaction or a split. A name
+// in brackets is a transfer to another account, which has no category, and
+// anything after a slash is a class.
+func qifCategory(value string) string {
+	if strings.HasPrefix(value, "[") {
+		return ""
+	}
+
+	category, _, _ := strings.Cut(value, "/")
+
+	return strings.TrimSpace(category)
+}
+
+// parseQIFDate reads the many date forms QIF files use, such as 1/31/26,
+// 1/31'26, 01-31-2026 and 2026-01-31. Two-digit years after an apostrophe,
+// or below 70, are in the 2000s.
+func parseQIFDate(value string, dayFirst bool) (pgtype.Date, error) {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return r < '0' || r > '9' })
+
+	if len(parts) != 3 {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	numbers := make([]int, 3)
+
+	for i, part := range parts {
+		numbers[i], _ = strconv.Atoi(part)
+	}
+
+	var year, month, day int
+
+	switch {
+	case len(parts[0]) == 4:
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	case dayFirst:
+		day, month, year = numbers[0], numbers[1], numbers[2]
+	default:
+		month, day, year = numbers[0], numbers[1], numbers[2]
+	}
+
+	if len(parts[0]) != 4 && len(parts[2]) <= 2 {
+		if strings.Contains(value, "'") || year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return pgtype.Date{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	return pgtype.Date{Time: date, Valid: true}, nil
+}
+
+func qifRow(record qifRecord, dayFirst bool) (Row, error) {
+	row := Row{Line: record.line, Category: qifCategory(record.category)}
+
+	var err error
+
+	row.Date, err = parseQIFDate(record.date, dayFirst)
+
+	if err != nil {
+		return row, err
+	}
+
+	row.Amount, err = ParseAmount(record.amount, guessDecimalSeparator(record.amount))
+
+	if err != nil {
+		return row, fmt.Errorf("invalid amount %q", record.amount)
+	}
+
+	row.Description = record.payee
+
+	if record.memo != "" && record.memo != record.payee {
+		row.Description = strings.TrimSpace(record.payee + " " + record.memo)
+	}
+
+	if len(record.splits) == 0 {
+		return row, nil
+	}
+
+	total := row.Amount
+
+	for _, split := range record.splits {
+		amount, err := ParseAmount(split.amount, guessDecimalSeparator(split.amount))
+
+		if err != nil {
+			return row, fmt.Errorf("invalid split amount %q", split.amount)
+		}
+
+		row.Splits = append(row.Splits, Split{
+			Category: qifCategory(split.category),
+			Memo:     split.memo,
+			Amount:   amount,
+		})
+
+		total -= amount
+	}
+
+	if total != 0 {
+		return row, fmt.Errorf("splits do not add up to the amount %s", row.Amount)
+	}
+
+	// The category of a split transaction only repeats the first split.
+	row.Category = ""
+
+	return row, nil
+}
+
+// ParseQIF reads the bank, credit card and cash sections of a QIF file and
+// skips every other section. QIF does not say in which order dates are
+// written: dayFirst reads 02/01/2026 as the 2nd of January rather than the
+// 1st of February.
+func ParseQIF(r io.Reader, dayFirst bool) (Statement, error) {
+	statement := newStatement()
+
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return statement, err
+	}
+
+	section := ""
+	var record qifRecord
+
+	finish := func() {
+		if record.line != 0 {
+			row, err := qifRow(record, dayFirst)
+
+			if err != nil {
+				statement.Fail(record.line, "%s", err)
+			} else {
+				statement.add(row)
+			}
+		}
+
+		record = qifRecord{}
+	}
+
+	for number, line := range strings.Split(latin1(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			continue
+		}
+
+		if line[0] == '!' {
+			finish()
+
+			// Options such as !Option:AutoSwitch do not start a section.
+			if header := line[1:]; !strings.HasPrefix(strings.ToLower(header), "option:") && !strings.HasPrefix(strings.ToLower(header), "clear:") {
+				section = qifSection(header)
+			}
+
+			continue
+		}
+
+		if section == "" {
+			continue
+		}
+
+		if line[0] == '^' {
+			finish()
+			continue
+		}
+
+		if record.line == 0 {
+			record.line = number + 1
+		}
+
+		value := strings.TrimSpace(line[1:])
+
+		switch line[0] {
+		case 'D':
+			record.date = value
+		case 'T', 'U':
+			if record.amount == "" {
+				record.amount = value
+			}
+		case 'P':
+			record.payee = value
+		case 'M':
+			record.memo = value
+		case 'L':
+			record.category = value
+		case 'S':
+			record.splits = append(record.splits, qifSplit{category: value})
+		case 'E', '$':
+			if len(record.splits) == 0 {
+				record.splits = append(record.splits, qifSplit{})
+			}
+
+			split := &record.splits[len(record.splits)-1]
+
+			if line[0] == 'E' {
+				split.memo = value
+			} else {
+				split.amount = value
+			}
+		}
+	}
+
+	finish()
+
+	return statement, nil
+}
+
+// qifValue keeps a value on a single line.
+func qifValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// WriteQIF writes rows as a QIF section of the given account type. Dates are
+// written month first unless dayFirst is set.
+func WriteQIF(w io.Writer, accountType string, rows []Row, dayFirst bool) error {
+	if accountType != QIFBank && accountType != QIFCCard && accountType != QIFCash {
+		return errors.New("QIF account type must be Bank, CCard or Cash")
+	}
+
+	layout := "01/02/2006"
+
+	if dayFirst {
+		layout = "02/01/2006"
+	}
+
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "!Type:%s\n", accountType)
+
+	for _, row := range rows {
+		fmt.Fprintf(out, "D%s\n", row.Date.Time.Format(layout))
+		fmt.Fprintf(out, "T%s\n", row.Amount)
+
+		if description := qifValue(row.Description); description != "" {
+			fmt.Fprintf(out, "P%s\n", description)
+		}
+
+		if category := qifValue(row.Category); category != "" {
+			fmt.Fprintf(out, "L%s\n", category)
+		}
+
+		for _, split := range row.Splits {
+			fmt.Fprintf(out, "S%s\n", qifValue(split.Category))
+
+			if memo := qifValue(split.Memo); memo != "" {
+				fmt.Fprintf(out, "E%s\n", memo)
+			}
+
+			fmt.Fprintf(out, "$%s\n", split.Amount)
+		}
+
+		fmt.Fprintln(out, "^")
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,166 @@
+package statements
+
+import (
+	"bytes"
+	"cashpal/money"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQIF(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dayFirst bool
+		rows     []Row
+		errors   []int
+	}{
+		{
+			name: "splits",
+			input: "!Type:Bank\n" +
+				"D02/01'26\n" +
+				"T-100.00\n" +
+				"PSupermarket\n" +
+				"LFood:Groceries\n" +
+				"SFood:Groceries\n" +
+				"EWeekly shop\n" +
+				"$-60.00\n" +
+				"SHousehold/Home\n" +
+				"$-40.00\n" +
+				"^\n",
+			rows: []Row{{
+				Line:        2,
+				Date:        date(2026, 2, 1),
+				Amount:      money.MustParse("-100"),
+				Description: "Supermarket",
+				Splits: []Split{
+					{Category: "Food:Groceries", Memo: "Weekly shop", Amount: money.MustParse("-60")},
+					{Category: "Household", Amount: money.MustParse("-40")},
+				},
+			}},
+			errors: []int{},
+		},
+		{
+			name: "splits that do not add up",
+			input: "!Type:CCard\n" +
+				"D1/5/2026\n" +
+				"T-10.00\n" +
+				"SFood\n" +
+				"$-4.00\n" +
+				"SFun\n" +
+				"$-5.00\n" +
+				"^\n",
+			rows:   []Row{},
+			errors: []int{2},
+		},
+		{
+			name: "split without a category",
+			input: "!Type:Cash\n" +
+				"D2026-01-05\n" +
+				"T-10.00\n" +
+				"$-10.00\n" +
+				"^\n",
+			rows: []Row{{
+				Line:   2,
+				Date:   date(2026, 1, 5),
+				Amount: money.MustParse("-10"),
+				Splits: []Split{{Amount: money.MustParse("-10")}},
+			}},
+			errors: []int{},
+		},
+		{
+			name: "transfers, memos and sections",
+			input: "!Option:AutoSwitch\n" +
+				"!Type:Bank\n" +
+				"D1/31/26\n" +
+				"T1,234.56\n" +
+				"PEmployer\n" +
+				"MJanuary\n" +
+				"L[Savings]\n" +
+				"^\n" +
+				"D13/13/2026\n" +
+				"T5\n" +
+				"^\n" +
+				"!Type:Invst\n" +
+				"D1/1/26\n" +
+				"T9\n" +
+				"^\n" +
+				"!Type:Bank\n" +
+				"D12/31/99\n" +
+				"U-1.00\n" +
+				"T-2.00\n" +
+				"^\n",
+			rows: []Row{
+				{Line: 3, Date: date(2026, 1, 31), Amount: money.MustParse("1234.56"), Description: "Employer January"},
+				{Line: 17, Date: date(1999, 12, 31), Amount: money.MustParse("-1")},
+			},
+			errors: []int{9},
+		},
+		{
+			name:     "day first",
+			input:    "!Type:Bank\r\nD02/01/2026\r\nT-1,50\r\n^\r\n",
+			dayFirst: true,
+			rows: []Row{
+				{Line: 2, Date: date(2026, 1, 2), Amount: money.MustParse("-1.50")},
+			},
+			errors: []int{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := ParseQIF(strings.NewReader(test.input), test.dayFirst)
+
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			checkStatement(t, statement, test.rows, test.errors, 0)
+		})
+	}
+}
+
+func TestWriteQIF(t *testing.T) {
+	rows := []Row{
+		{
+			Date:        date(2026, 2, 1),
+			Amount:      money.MustParse("-100"),
+			Description: "Super\nmarket",
+			Splits: []Split{
+				{Category: "Food:Groceries", Memo: "Weekly shop", Amount: money.MustParse("-60")},
+				{Category: "Household", Amount: money.MustParse("-40")},
+			},
+		},
+		{Date: date(2026, 2, 3), Amount: money.MustParse("5.25"), Category: "Income"},
+	}
+
+	var out bytes.Buffer
+
+	if err := WriteQIF(&out, QIFBank, rows, true); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	want := "!Type:Bank\n" +
+		"D01/02/2026\nT-100.00\nPSuper market\n" +
+		"SFood:Groceries\nEWeekly shop\n$-60.00\nSHousehold\n$-40.00\n^\n" +
+		"D03/02/2026\nT5.25\nLIncome\n^\n"
+
+	if out.String() != want {
+		t.Fatalf("WriteQIF = %q, want %q", out.String(), want)
+	}
+
+	statement, err := ParseQIF(&out, true)
+
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if len(statement.Rows) != 2 || !reflect.DeepEqual(statement.Rows[0].Splits, rows[0].Splits) {
+		t.Errorf("rows read back = %+v, want the splits written", statement.Rows)
+	}
+
+	if err := WriteQIF(&out, "Invst", rows, false); err == nil {
+		t.Error("WriteQIF accepted an investment account")
+	}
+}
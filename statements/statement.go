@@ -0,0 +1,165 @@
+// Package statements reads bank statement files into rows that can be turned
+// into Cashpal transactions.
+package statements
+
+import (
+	"cashpal/money"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatOFX   = "ofx"
+	FormatQIF   = "qif"
+	FormatCAMT  = "camt"
+	FormatMT940 = "mt940"
+)
+
+// Keys of the metadata the camt and MT940 parsers keep for each row.
+const (
+	MetadataEndToEndID          = "end_to_end_id"
+	MetadataBankReference       = "bank_reference"
+	MetadataCustomerReference   = "customer_reference"
+	MetadataCounterpartyName    = "counterparty_name"
+	MetadataCounterpartyAccount = "counterparty_account"
+	MetadataStatement           = "statement"
+	MetadataReversal            = "reversal"
+)
+
+var ErrUnknownFormat = errors.New("unknown statement format, expected csv, ofx, qif, camt or mt940")
+
+// Row is one transaction of a statement. Amount is signed: money coming into
+// the account is positive and money leaving it is negative. Currency is empty
+// when the statement does not say, and ExternalID is the bank's own id for
+// the transaction when it has one. Categories are paths of category names
+// separated by colons, such as "Food:Groceries". Metadata holds the
+// references and counterparty details a statement gives beyond the
+// description.
+type Row struct {
+	Line        int               `json:"line"`
+	Date        pgtype.Date       `json:"date"`
+	Amount      money.Amount      `json:"amount"`
+	Description string            `json:"description"`
+	Currency    string            `json:"currency,omitempty"`
+	ExternalID  string            `json:"external_id,omitempty"`
+	Category    string            `json:"category,omitempty"`
+	Splits      []Split           `json:"splits,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Split is the part of a row booked to one category. Its amount is signed
+// like the amount of the row, and the splits of a row add up to it.
+type Split struct {
+	Category string       `json:"category"`
+	Memo     string       `json:"memo"`
+	Amount   money.Amount `json:"amount"`
+}
+
+// Balance is the closing balance a bank reports for one of the accounts in a
+// statement.
+type Balance struct {
+	Account  string       `json:"account"`
+	Currency string       `json:"currency"`
+	Date     pgtype.Date  `json:"date"`
+	Amount   money.Amount `json:"amount"`
+}
+
+// RowError explains why a line of a statement could not be read.
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Statement holds the rows read from a file, the lines that could not be read
+// and the balances the file reports. Lines with a zero amount carry no money
+// and, like entries the bank has not booked yet, are only counted in
+// Skipped.
+type Statement struct {
+	Rows     []Row      `json:"rows"`
+	Errors   []RowError `json:"errors"`
+	Skipped  int        `json:"skipped"`
+	Balances []Balance  `json:"balances"`
+}
+
+func newStatement() Statement {
+	return Statement{Rows: []Row{}, Errors: []RowError{}, Balances: []Balance{}}
+}
+
+// Fail records that a line could not be read.
+func (s *Statement) Fail(line int, format string, args ...any) {
+	s.Errors = append(s.Errors, RowError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *Statement) add(row Row) {
+	if row.Amount == 0 {
+		s.Skipped++
+		return
+	}
+
+	s.Rows = append(s.Rows, row)
+}
+
+// guessDecimalSeparator guesses the decimal separator of an amount in a
+// format that allows both a period and a comma. A lone comma followed by
+// three digits is taken as a thousands separator.
+func guessDecimalSeparator(value string) string {
+	comma := strings.LastIndex(value, ",")
+	period := strings.LastIndex(value, ".")
+
+	if comma > period && (period >= 0 || len(value)-comma-1 != 3) {
+		return ","
+	}
+
+	return "."
+}
+
+// Parse reads a statement in a format that carries its own layout. CSV files
+// differ from bank to bank and are read with ParseCSV and an import profile;
+// QIF files do not say in which order they write dates and are read with
+// ParseQIF.
+func Parse(format string, r io.Reader) (Statement, error) {
+	switch format {
+	case FormatOFX:
+		return ParseOFX(r)
+	case FormatCSV:
+		return newStatement(), errors.New("CSV statements need an import profile")
+	case FormatQIF:
+		return ParseQIF(r, false)
+	case FormatCAMT:
+		return ParseCAMT(r)
+	case FormatMT940:
+		return ParseMT940(r)
+	default:
+		return newStatement(), ErrUnknownFormat
+	}
+}
+
+// DetectFormat guesses the statement format from a file name or a content
+// type.
+func DetectFormat(nameOrContentType string) string {
+	value := strings.ToLower(nameOrContentType)
+
+	switch {
+	case strings.Contains(value, "ofx"), strings.Contains(value, "qfx"):
+		return FormatOFX
+	case strings.Contains(value, "qif"):
+		return FormatQIF
+	case strings.Contains(value, "camt"), strings.Contains(value, "xml"):
+		return FormatCAMT
+	case strings.Contains(value, "mt940"), strings.Contains(value, "940"), strings.HasSuffix(value, ".sta"):
+		return FormatMT940
+	case strings.Contains(value, "csv"):
+		return FormatCSV
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,134 @@
+package statements
+
+import (
+	"cashpal/money"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func date(year int, month time.Month, day int) pgtype.Date {
+	return pgtype.Date{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+func errorLines(statement Statement) []int {
+	lines := []int{}
+
+	for _, rowError := range statement.Errors {
+		lines = append(lines, rowError.Line)
+	}
+
+	return lines
+}
+
+// checkStatement compares the rows, the lines of the errors and the skipped
+// count of a statement with what a test expects.
+func checkStatement(t *testing.T, statement Statement, rows []Row, errors []int, skipped int) {
+	t.Helper()
+
+	if !reflect.DeepEqual(statement.Rows, rows) {
+		t.Errorf("rows = %+v, want %+v", statement.Rows, rows)
+	}
+
+	if !reflect.DeepEqual(errorLines(statement), errors) {
+		t.Errorf("errors = %+v, want errors on lines %v", statement.Errors, errors)
+	}
+
+	if statement.Skipped != skipped {
+		t.Errorf("skipped = %d, want %d", statement.Skipped, skipped)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		rows    int
+		wantErr bool
+	}{
+		{"ofx", FormatOFX, "<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><STMTTRN><DTPOSTED>20260105<TRNAMT>-1.00</STMTTRN></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>", 1, false},
+		{"qif", FormatQIF, "!Type:Bank\nD01/05/2026\nT-1.00\n^\n", 1, false},
+		{"camt", FormatCAMT, "<Document><BkToCstmrStmt><Stmt><Ntry><Amt>1.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2026-01-05</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>", 1, false},
+		{"mt940", FormatMT940, ":20:REF\n:25:123\n:61:260105D1,00NTRFNONREF\n:62F:C260105EUR0,00\n", 1, false},
+		{"csv needs a profile", FormatCSV, "2026-01-05,1.00", 0, true},
+		{"unknown format", "xls", "", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statement, err := Parse(test.format, strings.NewReader(test.input))
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error %v", err, test.wantErr)
+			}
+
+			if len(statement.Rows) != test.rows {
+				t.Errorf("rows = %+v, want %d rows", statement.Rows, test.rows)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"statement.ofx", FormatOFX},
+		{"Statement.QFX", FormatOFX},
+		{"application/x-ofx", FormatOFX},
+		{"export.qif", FormatQIF},
+		{"camt053.xml", FormatCAMT},
+		{"application/xml", FormatCAMT},
+		{"umsaetze.sta", FormatMT940},
+		{"MT940_2026.txt", FormatMT940},
+		{"export.csv", FormatCSV},
+		{"text/csv", FormatCSV},
+		{"report.pdf", ""},
+	}
+
+	for _, test := range tests {
+		if got := DetectFormat(test.value); got != test.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestGuessDecimalSeparator(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"12.50", "."},
+		{"12,50", ","},
+		{"1,234.56", "."},
+		{"1.234,56", ","},
+		{"1,234", "."},
+		{"-42", "."},
+	}
+
+	for _, test := range tests {
+		if got := guessDecimalSeparator(test.value); got != test.want {
+			t.Errorf("guessDecimalSeparator(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestStatementAdd(t *testing.T) {
+	statement := newStatement()
+
+	statement.add(Row{Line: 1, Amount: money.MustParse("1")})
+	statement.add(Row{Line: 2})
+
+	if len(statement.Rows) != 1 || statement.Rows[0].Line != 1 {
+		t.Errorf("rows = %+v, want only line 1", statement.Rows)
+	}
+
+	if statement.Skipped != 1 {
+		t.Errorf("skipped = %d, want 1", statement.Skipped)
+	}
+}